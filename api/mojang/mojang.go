@@ -2,6 +2,7 @@ package mojang
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,8 +10,16 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/elyby/chrly/tracing"
 )
 
+var tracer = otel.Tracer("github.com/elyby/chrly/api/mojang")
+
 var HttpClient = &http.Client{
 	Timeout: 3 * time.Second,
 	Transport: &http.Transport{
@@ -18,6 +27,10 @@ var HttpClient = &http.Client{
 	},
 }
 
+// SessionServerHttpClient is used for the requests to the session server instead of the HttpClient when it's set.
+// It allows to spread the requests across multiple outbound addresses, since the session server limits are per IP
+var SessionServerHttpClient *http.Client
+
 type SignedTexturesResponse struct {
 	Id    string      `json:"id"`
 	Name  string      `json:"name"`
@@ -71,9 +84,17 @@ var SessionServerMojangComAddr = "https://sessionserver.mojang.com"
 
 // Exchanges usernames array to array of uuids
 // See https://wiki.vg/Mojang_API#Playernames_-.3E_UUIDs
-func UsernamesToUuids(usernames []string) ([]*ProfileInfo, error) {
+func UsernamesToUuids(ctx context.Context, usernames []string) (result []*ProfileInfo, err error) {
+	ctx, span := tracer.Start(ctx, "mojang.UsernamesToUuids",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("mojang.usernames_count", len(usernames))),
+	)
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
 	requestBody, _ := json.Marshal(usernames)
-	request, err := http.NewRequest("POST", ApiMojangDotComAddr+"/profiles/minecraft", bytes.NewBuffer(requestBody))
+	request, err := http.NewRequestWithContext(ctx, "POST", ApiMojangDotComAddr+"/profiles/minecraft", bytes.NewBuffer(requestBody))
 	if err != nil {
 		return nil, err
 	}
@@ -90,8 +111,6 @@ func UsernamesToUuids(usernames []string) ([]*ProfileInfo, error) {
 		return nil, responseErr
 	}
 
-	var result []*ProfileInfo
-
 	body, _ := ioutil.ReadAll(response.Body)
 	_ = json.Unmarshal(body, &result)
 
@@ -100,19 +119,32 @@ func UsernamesToUuids(usernames []string) ([]*ProfileInfo, error) {
 
 // Obtains textures information for provided uuid
 // See https://wiki.vg/Mojang_API#UUID_-.3E_Profile_.2B_Skin.2FCape
-func UuidToTextures(uuid string, signed bool) (*SignedTexturesResponse, error) {
+func UuidToTextures(ctx context.Context, uuid string, signed bool) (result *SignedTexturesResponse, err error) {
+	ctx, span := tracer.Start(ctx, "mojang.UuidToTextures",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("mojang.uuid", uuid)),
+	)
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
 	normalizedUuid := strings.ReplaceAll(uuid, "-", "")
 	url := SessionServerMojangComAddr + "/session/minecraft/profile/" + normalizedUuid
 	if signed {
 		url += "?unsigned=false"
 	}
 
-	request, err := http.NewRequest("GET", url, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	response, err := HttpClient.Do(request)
+	client := HttpClient
+	if SessionServerHttpClient != nil {
+		client = SessionServerHttpClient
+	}
+
+	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -122,8 +154,6 @@ func UuidToTextures(uuid string, signed bool) (*SignedTexturesResponse, error) {
 		return nil, responseErr
 	}
 
-	var result *SignedTexturesResponse
-
 	body, _ := ioutil.ReadAll(response.Body)
 	_ = json.Unmarshal(body, &result)
 
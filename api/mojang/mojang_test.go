@@ -1,6 +1,7 @@
 package mojang
 
 import (
+	"context"
 	"net/http"
 	"testing"
 
@@ -65,7 +66,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		if assert.NoError(err) {
 			assert.Len(result, 2)
 			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", result[0].Id)
@@ -97,7 +98,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{""})
+		result, err := UsernamesToUuids(context.Background(), []string{""})
 		assert.Nil(result)
 		assert.IsType(&BadRequestError{}, err)
 		assert.EqualError(err, "400 IllegalArgumentException: profileName can not be null or empty.")
@@ -118,7 +119,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		assert.Nil(result)
 		assert.IsType(&ForbiddenError{}, err)
 		assert.EqualError(err, "403: Forbidden")
@@ -142,7 +143,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		assert.Nil(result)
 		assert.IsType(&TooManyRequestsError{}, err)
 		assert.EqualError(err, "429: Too Many Requests")
@@ -163,7 +164,7 @@ func TestUsernamesToUuids(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UsernamesToUuids([]string{"Thinkofdeath", "maksimkurb"})
+		result, err := UsernamesToUuids(context.Background(), []string{"Thinkofdeath", "maksimkurb"})
 		assert.Nil(result)
 		assert.IsType(&ServerError{}, err)
 		assert.EqualError(err, "500: Server error")
@@ -196,7 +197,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		if assert.NoError(err) {
 			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", result.Id)
 			assert.Equal("Thinkofdeath", result.Name)
@@ -232,7 +233,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69f-c907-48ee-8d71-d7ba5aa00d20", true)
+		result, err := UuidToTextures(context.Background(), "4566e69f-c907-48ee-8d71-d7ba5aa00d20", true)
 		if assert.NoError(err) {
 			assert.Equal("4566e69fc90748ee8d71d7ba5aa00d20", result.Id)
 			assert.Equal("Thinkofdeath", result.Name)
@@ -243,6 +244,29 @@ func TestUuidToTextures(t *testing.T) {
 		}
 	})
 
+	t.Run("use the session server client when it's set", func(t *testing.T) {
+		assert := testify.New(t)
+
+		defer gock.Off()
+		gock.New("https://sessionserver.mojang.com").
+			Get("/session/minecraft/profile/4566e69fc90748ee8d71d7ba5aa00d20").
+			Reply(204).
+			BodyString("")
+
+		client := &http.Client{}
+		gock.InterceptClient(client)
+
+		HttpClient = &http.Client{}
+		SessionServerHttpClient = client
+		defer func() {
+			SessionServerHttpClient = nil
+		}()
+
+		_, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
+		assert.IsType(&EmptyResponse{}, err)
+		assert.True(gock.IsDone())
+	})
+
 	t.Run("handle empty response", func(t *testing.T) {
 		assert := testify.New(t)
 
@@ -257,7 +281,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		assert.Nil(result)
 		assert.IsType(&EmptyResponse{}, err)
 		assert.EqualError(err, "200: Empty Response")
@@ -281,7 +305,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		assert.Nil(result)
 		assert.IsType(&TooManyRequestsError{}, err)
 		assert.EqualError(err, "429: Too Many Requests")
@@ -302,7 +326,7 @@ func TestUuidToTextures(t *testing.T) {
 
 		HttpClient = client
 
-		result, err := UuidToTextures("4566e69fc90748ee8d71d7ba5aa00d20", false)
+		result, err := UuidToTextures(context.Background(), "4566e69fc90748ee8d71d7ba5aa00d20", false)
 		assert.Nil(result)
 		assert.IsType(&ServerError{}, err)
 		assert.EqualError(err, "500: Server error")
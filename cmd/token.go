@@ -3,24 +3,34 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/elyby/chrly/http"
 
 	"github.com/spf13/cobra"
 )
 
+var tokenScopes []string
+var tokenTTL time.Duration
+
 var tokenCmd = &cobra.Command{
 	Use:   "token",
 	Short: "Creates a new token, which allows to interact with Chrly API",
 	Run: func(cmd *cobra.Command, args []string) {
+		scopes, err := parseScopes(tokenScopes)
+		if err != nil {
+			log.Fatal(err)
+		}
+
 		container := shouldGetContainer()
 		var auth *http.JwtAuth
-		err := container.Resolve(&auth)
+		err = container.Resolve(&auth)
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		token, err := auth.NewToken(http.SkinScope)
+		token, err := auth.NewToken(tokenTTL, scopes...)
 		if err != nil {
 			log.Fatalf("Unable to create new token. The error is %v\n", err)
 		}
@@ -29,6 +39,66 @@ var tokenCmd = &cobra.Command{
 	},
 }
 
+var revokeTokenCmd = &cobra.Command{
+	Use:   "revoke [token]",
+	Short: "Revokes the token, so it can't be used to interact with Chrly API anymore",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		container := shouldGetContainer()
+		var auth *http.JwtAuth
+		err := container.Resolve(&auth)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var revokedTokensRepository http.RevokedTokensRepository
+		err = container.Resolve(&revokedTokensRepository)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		auth.RevokedTokensRepo = revokedTokensRepository
+		err = auth.RevokeToken(args[0])
+		if err != nil {
+			log.Fatalf("Unable to revoke the token. The error is %v\n", err)
+		}
+
+		fmt.Println("The token has been revoked")
+	},
+}
+
+func parseScopes(names []string) ([]http.Scope, error) {
+	scopes := make([]http.Scope, len(names))
+	for i, name := range names {
+		scope := http.Scope(name)
+		if !hasScope(http.Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope \"%s\"", name)
+		}
+
+		scopes[i] = scope
+	}
+
+	return scopes, nil
+}
+
+func hasScope(scopes []http.Scope, needle http.Scope) bool {
+	for _, scope := range scopes {
+		if scope == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
 func init() {
+	scopesNames := make([]string, len(http.Scopes))
+	for i, scope := range http.Scopes {
+		scopesNames[i] = string(scope)
+	}
+
+	tokenCmd.Flags().StringSliceVar(&tokenScopes, "scope", []string{string(http.SkinScope)}, "scopes of the token: "+strings.Join(scopesNames, ", "))
+	tokenCmd.Flags().DurationVar(&tokenTTL, "ttl", 0, "lifetime of the token, e.g. 720h. The token never expires when it's not set")
+	tokenCmd.AddCommand(revokeTokenCmd)
 	RootCmd.AddCommand(tokenCmd)
 }
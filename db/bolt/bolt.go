@@ -0,0 +1,350 @@
+package bolt
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/model"
+)
+
+var now = time.Now
+
+var skinsBucket = []byte("skins")
+var userIdsBucket = []byte("user-ids")
+var mojangUuidsBucket = []byte("mojang-uuids")
+var mojangTexturesBucket = []byte("mojang-textures")
+
+// New opens (or creates) the database file. The file is locked while the database is opened,
+// so it can't be shared between several Chrly instances
+func New(path string) (*Bolt, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{skinsBucket, userIdsBucket, mojangUuidsBucket, mojangTexturesBucket} {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Bolt{
+		UuidTTL:        time.Hour * 24 * 30,
+		NilUuidTTL:     time.Hour * 24,
+		TexturesTTL:    time.Minute + 10*time.Second,
+		NilTexturesTTL: time.Minute + 10*time.Second,
+		GCPeriod:       time.Minute,
+		db:             db,
+		done:           make(chan struct{}),
+	}, nil
+}
+
+// Bolt is an embedded key-value storage, that keeps all the data in a single file
+type Bolt struct {
+	// UuidTTL specifies how long the Mojang's UUID of the username is considered valid
+	UuidTTL time.Duration
+	// NilUuidTTL specifies how long the information about the absence of the Mojang's account is considered valid
+	NilUuidTTL time.Duration
+	// TexturesTTL specifies how long the Mojang's textures are considered valid
+	TexturesTTL time.Duration
+	// NilTexturesTTL specifies how long the information about the absence of textures is considered valid
+	NilTexturesTTL time.Duration
+	// GCPeriod specifies how often the expired Mojang's textures are removed from the file
+	GCPeriod time.Duration
+
+	db        *bbolt.DB
+	once      sync.Once
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type texturesItem struct {
+	Textures *mojang.SignedTexturesResponse `json:"textures"`
+	StoredAt int64                          `json:"storedAt"`
+}
+
+func (b *Bolt) FindSkinByUsername(username string) (*model.Skin, error) {
+	var skin *model.Skin
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		skin, err = findByUsername(tx, username)
+
+		return err
+	})
+
+	return skin, err
+}
+
+func findByUsername(tx *bbolt.Tx, username string) (*model.Skin, error) {
+	data := tx.Bucket(skinsBucket).Get(buildUsernameKey(username))
+	if data == nil {
+		return nil, nil
+	}
+
+	var skin *model.Skin
+	err := json.Unmarshal(data, &skin)
+	if err != nil {
+		return nil, err
+	}
+
+	skin.OldUsername = skin.Username
+
+	return skin, nil
+}
+
+func (b *Bolt) FindSkinByUserId(id int) (*model.Skin, error) {
+	var skin *model.Skin
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		skin, err = findByUserId(tx, id)
+
+		return err
+	})
+
+	return skin, err
+}
+
+func findByUserId(tx *bbolt.Tx, id int) (*model.Skin, error) {
+	username := tx.Bucket(userIdsBucket).Get(buildUserIdKey(id))
+	if username == nil {
+		return nil, nil
+	}
+
+	return findByUsername(tx, string(username))
+}
+
+func (b *Bolt) SaveSkin(skin *model.Skin) error {
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		skins := tx.Bucket(skinsBucket)
+
+		// If user has changed username, then we must delete his old username record
+		if skin.OldUsername != "" && skin.OldUsername != skin.Username {
+			err := skins.Delete(buildUsernameKey(skin.OldUsername))
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.Bucket(userIdsBucket).Put(buildUserIdKey(skin.UserId), []byte(skin.Username))
+		if err != nil {
+			return err
+		}
+
+		data, _ := json.Marshal(skin)
+
+		return skins.Put(buildUsernameKey(skin.Username), data)
+	})
+	if err != nil {
+		return err
+	}
+
+	skin.OldUsername = skin.Username
+
+	return nil
+}
+
+func (b *Bolt) RemoveSkinByUserId(id int) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		record, err := findByUserId(tx, id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Bucket(userIdsBucket).Delete(buildUserIdKey(id))
+		if err != nil {
+			return err
+		}
+
+		if record != nil {
+			return tx.Bucket(skinsBucket).Delete(buildUsernameKey(record.Username))
+		}
+
+		return nil
+	})
+}
+
+func (b *Bolt) RemoveSkinByUsername(username string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		record, err := findByUsername(tx, username)
+		if err != nil {
+			return err
+		}
+
+		if record == nil {
+			return nil
+		}
+
+		err = tx.Bucket(skinsBucket).Delete(buildUsernameKey(record.Username))
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(userIdsBucket).Delete(buildUserIdKey(record.UserId))
+	})
+}
+
+func (b *Bolt) GetUuid(username string) (string, bool, error) {
+	var value string
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		value = string(tx.Bucket(mojangUuidsBucket).Get(buildUsernameKey(username)))
+
+		return nil
+	})
+	if err != nil || value == "" {
+		return "", false, err
+	}
+
+	parts := strings.Split(value, ":")
+	// The malformed value is treated as a miss, so it'll be overwritten with the next StoreUuid call
+	if len(parts) != 2 {
+		return "", false, nil
+	}
+
+	timestamp, _ := strconv.ParseInt(parts[1], 10, 64)
+	ttl := b.UuidTTL
+	if parts[0] == "" {
+		ttl = b.NilUuidTTL
+	}
+
+	storedAt := time.Unix(timestamp, 0)
+	if storedAt.Add(ttl).Before(now()) {
+		err := b.db.Update(func(tx *bbolt.Tx) error {
+			return tx.Bucket(mojangUuidsBucket).Delete(buildUsernameKey(username))
+		})
+
+		return "", false, err
+	}
+
+	return parts[0], true, nil
+}
+
+func (b *Bolt) StoreUuid(username string, uuid string) error {
+	value := uuid + ":" + strconv.FormatInt(now().Unix(), 10)
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(mojangUuidsBucket).Put(buildUsernameKey(username), []byte(value))
+	})
+}
+
+func (b *Bolt) GetTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	var item *texturesItem
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(mojangTexturesBucket).Get([]byte(uuid))
+		if data == nil {
+			return nil
+		}
+
+		return json.Unmarshal(data, &item)
+	})
+	if err != nil || item == nil || b.isExpired(item) {
+		return nil, false, err
+	}
+
+	return item.Textures, true, nil
+}
+
+func (b *Bolt) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
+	b.once.Do(b.start)
+
+	data, _ := json.Marshal(&texturesItem{
+		Textures: textures,
+		StoredAt: now().Unix(),
+	})
+
+	// The interface doesn't allow to return an error, but since this is only a cache, the error can be ignored
+	_ = b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(mojangTexturesBucket).Put([]byte(uuid), data)
+	})
+}
+
+func (b *Bolt) Ping() error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		return nil
+	})
+}
+
+func (b *Bolt) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+		err = b.db.Close()
+	})
+
+	return err
+}
+
+func (b *Bolt) start() {
+	ticker := time.NewTicker(b.GCPeriod)
+	go func() {
+		for {
+			select {
+			case <-b.done:
+				ticker.Stop()
+				return
+			case <-ticker.C:
+				_ = b.gc()
+			}
+		}
+	}()
+}
+
+func (b *Bolt) gc() error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(mojangTexturesBucket)
+
+		// Keys are collected first, since the deletion during the iteration makes the cursor skip items
+		var expiredKeys [][]byte
+		err := bucket.ForEach(func(key []byte, data []byte) error {
+			var item *texturesItem
+			if json.Unmarshal(data, &item) != nil || b.isExpired(item) {
+				expiredKeys = append(expiredKeys, key)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, key := range expiredKeys {
+			err := bucket.Delete(key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *Bolt) isExpired(item *texturesItem) bool {
+	ttl := b.TexturesTTL
+	if item.Textures == nil {
+		ttl = b.NilTexturesTTL
+	}
+
+	return time.Unix(item.StoredAt, 0).Add(ttl).Before(now())
+}
+
+func buildUsernameKey(username string) []byte {
+	return []byte(strings.ToLower(username))
+}
+
+func buildUserIdKey(id int) []byte {
+	return []byte(strconv.Itoa(id))
+}
@@ -0,0 +1,441 @@
+package bolt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"go.etcd.io/bbolt"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/model"
+)
+
+func TestNew(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		panic(fmt.Errorf("cannot crete temp directory for tests: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("should create database file", func(t *testing.T) {
+		db, err := New(path.Join(dir, "chrly.bolt"))
+		assert.Nil(t, err)
+		assert.NotNil(t, db)
+		assert.FileExists(t, path.Join(dir, "chrly.bolt"))
+		_ = db.Close()
+	})
+
+	t.Run("should allow to close database twice", func(t *testing.T) {
+		db, err := New(path.Join(dir, "chrly.bolt"))
+		assert.Nil(t, err)
+		assert.Nil(t, db.Close())
+		assert.Nil(t, db.Close())
+	})
+
+	t.Run("should return error", func(t *testing.T) {
+		db, err := New(path.Join(dir, "not", "exists", "chrly.bolt"))
+		assert.Error(t, err)
+		assert.Nil(t, db)
+	})
+}
+
+type boltTestSuite struct {
+	suite.Suite
+
+	Bolt *Bolt
+
+	dir string
+}
+
+func (suite *boltTestSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		panic(fmt.Errorf("cannot crete temp directory for tests: %w", err))
+	}
+
+	db, err := New(path.Join(dir, "chrly.bolt"))
+	if err != nil {
+		panic(fmt.Errorf("cannot open bolt database: %w", err))
+	}
+
+	suite.Bolt = db
+	suite.dir = dir
+}
+
+func (suite *boltTestSuite) TearDownSuite() {
+	_ = suite.Bolt.Close()
+	_ = os.RemoveAll(suite.dir)
+}
+
+func (suite *boltTestSuite) SetupTest() {
+	// Cleanup database before the each test
+	_ = suite.Bolt.db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range [][]byte{skinsBucket, userIdsBucket, mojangUuidsBucket, mojangTexturesBucket} {
+			_ = tx.DeleteBucket(bucket)
+			_, _ = tx.CreateBucket(bucket)
+		}
+
+		return nil
+	})
+}
+
+func (suite *boltTestSuite) TearDownTest() {
+	// Restore time.Now func
+	now = time.Now
+}
+
+func (suite *boltTestSuite) RunSubTest(name string, subTest func()) {
+	suite.SetupTest()
+	suite.Run(name, subTest)
+}
+
+func (suite *boltTestSuite) put(bucket []byte, key string, value string) {
+	err := suite.Bolt.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), []byte(value))
+	})
+	if err != nil {
+		panic(fmt.Errorf("cannot put value: %w", err))
+	}
+}
+
+func (suite *boltTestSuite) get(bucket []byte, key string) []byte {
+	var value []byte
+	_ = suite.Bolt.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data != nil {
+			value = append([]byte{}, data...)
+		}
+
+		return nil
+	})
+
+	return value
+}
+
+func TestBolt(t *testing.T) {
+	suite.Run(t, new(boltTestSuite))
+}
+
+const skinRecord = `{
+	"userId": 1,
+	"uuid": "fd5da1e4d66d4d17aadee2446093896d",
+	"username": "Mock",
+	"skinId": 1,
+	"url": "http://localhost/skin.png",
+	"is1_8": true,
+	"isSlim": false,
+	"mojangTextures": "mock-mojang-textures",
+	"mojangSignature": "mock-mojang-signature"
+}`
+
+func (suite *boltTestSuite) TestFindSkinByUsername() {
+	suite.RunSubTest("exists record", func() {
+		suite.put(skinsBucket, "mock", skinRecord)
+
+		skin, err := suite.Bolt.FindSkinByUsername("Mock")
+		suite.Require().Nil(err)
+		suite.Require().NotNil(skin)
+		suite.Require().Equal(1, skin.UserId)
+		suite.Require().Equal("fd5da1e4d66d4d17aadee2446093896d", skin.Uuid)
+		suite.Require().Equal("Mock", skin.Username)
+		suite.Require().Equal(1, skin.SkinId)
+		suite.Require().Equal("http://localhost/skin.png", skin.Url)
+		suite.Require().True(skin.Is1_8)
+		suite.Require().False(skin.IsSlim)
+		suite.Require().Equal("mock-mojang-textures", skin.MojangTextures)
+		suite.Require().Equal("mock-mojang-signature", skin.MojangSignature)
+		suite.Require().Equal(skin.Username, skin.OldUsername)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		skin, err := suite.Bolt.FindSkinByUsername("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Nil(skin)
+	})
+
+	suite.RunSubTest("invalid json encoding", func() {
+		suite.put(skinsBucket, "mock", "hello world")
+
+		skin, err := suite.Bolt.FindSkinByUsername("Mock")
+		suite.Require().Nil(skin)
+		suite.Require().EqualError(err, "invalid character 'h' looking for beginning of value")
+	})
+}
+
+func (suite *boltTestSuite) TestFindSkinByUserId() {
+	suite.RunSubTest("exists record", func() {
+		suite.put(skinsBucket, "mock", skinRecord)
+		suite.put(userIdsBucket, "1", "Mock")
+
+		skin, err := suite.Bolt.FindSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().NotNil(skin)
+		suite.Require().Equal(1, skin.UserId)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		skin, err := suite.Bolt.FindSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().Nil(skin)
+	})
+
+	suite.RunSubTest("exists user id record, but no skin record", func() {
+		suite.put(userIdsBucket, "1", "Mock")
+
+		skin, err := suite.Bolt.FindSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().Nil(skin)
+	})
+}
+
+func (suite *boltTestSuite) TestSaveSkin() {
+	suite.RunSubTest("save new entity", func() {
+		skin := &model.Skin{
+			UserId:          1,
+			Uuid:            "fd5da1e4d66d4d17aadee2446093896d",
+			Username:        "Mock",
+			SkinId:          1,
+			Url:             "http://localhost/skin.png",
+			Is1_8:           true,
+			IsSlim:          false,
+			MojangTextures:  "mock-mojang-textures",
+			MojangSignature: "mock-mojang-signature",
+		}
+		err := suite.Bolt.SaveSkin(skin)
+		suite.Require().Nil(err)
+		suite.Require().Equal("Mock", skin.OldUsername)
+
+		suite.Require().NotNil(suite.get(skinsBucket, "mock"))
+		suite.Require().Equal("Mock", string(suite.get(userIdsBucket, "1")))
+	})
+
+	suite.RunSubTest("save exists record with changed username", func() {
+		suite.put(skinsBucket, "mock", skinRecord)
+		suite.put(userIdsBucket, "1", "Mock")
+
+		skin, _ := suite.Bolt.FindSkinByUserId(1)
+		skin.Username = "NewMock"
+		err := suite.Bolt.SaveSkin(skin)
+		suite.Require().Nil(err)
+
+		suite.Require().Nil(suite.get(skinsBucket, "mock"))
+		suite.Require().NotNil(suite.get(skinsBucket, "newmock"))
+		suite.Require().Equal("NewMock", string(suite.get(userIdsBucket, "1")))
+	})
+}
+
+func (suite *boltTestSuite) TestRemoveSkinByUserId() {
+	suite.RunSubTest("exists record", func() {
+		suite.put(skinsBucket, "mock", skinRecord)
+		suite.put(userIdsBucket, "1", "Mock")
+
+		err := suite.Bolt.RemoveSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().Nil(suite.get(skinsBucket, "mock"))
+		suite.Require().Nil(suite.get(userIdsBucket, "1"))
+	})
+
+	suite.RunSubTest("exists only id", func() {
+		suite.put(userIdsBucket, "1", "Mock")
+
+		err := suite.Bolt.RemoveSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().Nil(suite.get(userIdsBucket, "1"))
+	})
+}
+
+func (suite *boltTestSuite) TestRemoveSkinByUsername() {
+	suite.RunSubTest("exists record", func() {
+		suite.put(skinsBucket, "mock", skinRecord)
+		suite.put(userIdsBucket, "1", "Mock")
+
+		err := suite.Bolt.RemoveSkinByUsername("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Nil(suite.get(skinsBucket, "mock"))
+		suite.Require().Nil(suite.get(userIdsBucket, "1"))
+	})
+
+	suite.RunSubTest("no records", func() {
+		err := suite.Bolt.RemoveSkinByUsername("Mock")
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *boltTestSuite) TestGetUuid() {
+	suite.RunSubTest("exists record", func() {
+		suite.put(mojangUuidsBucket, "mock", fmt.Sprintf("%s:%d", "d3ca513eb3e14946b58047f2bd3530fd", time.Now().Unix()))
+
+		uuid, found, err := suite.Bolt.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", uuid)
+	})
+
+	suite.RunSubTest("exists record with empty uuid value", func() {
+		suite.put(mojangUuidsBucket, "mock", fmt.Sprintf(":%d", time.Now().Unix()))
+
+		uuid, found, err := suite.Bolt.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Empty(uuid)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		uuid, found, err := suite.Bolt.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().False(found)
+		suite.Require().Empty(uuid)
+	})
+
+	suite.RunSubTest("exists record with malformed value", func() {
+		suite.put(mojangUuidsBucket, "mock", "d3ca513eb3e14946b58047f2bd3530fd")
+
+		uuid, found, err := suite.Bolt.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().False(found)
+		suite.Require().Empty(uuid)
+	})
+
+	suite.RunSubTest("exists, but expired record", func() {
+		suite.put(mojangUuidsBucket, "mock", fmt.Sprintf(
+			"%s:%d",
+			"d3ca513eb3e14946b58047f2bd3530fd",
+			time.Now().Add(-1*time.Hour*24*31).Unix(),
+		))
+
+		uuid, found, err := suite.Bolt.GetUuid("Mock")
+		suite.Require().Empty(uuid)
+		suite.Require().False(found)
+		suite.Require().Nil(err)
+		suite.Require().Nil(suite.get(mojangUuidsBucket, "mock"), "should cleanup expired records")
+	})
+
+	suite.RunSubTest("exists record with empty uuid value, expired by the nil ttl", func() {
+		suite.put(mojangUuidsBucket, "mock", fmt.Sprintf(":%d", time.Now().Add(-2*time.Hour).Unix()))
+
+		defer func(ttl time.Duration) {
+			suite.Bolt.NilUuidTTL = ttl
+		}(suite.Bolt.NilUuidTTL)
+		suite.Bolt.NilUuidTTL = time.Hour
+
+		uuid, found, err := suite.Bolt.GetUuid("Mock")
+		suite.Require().Empty(uuid)
+		suite.Require().False(found)
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *boltTestSuite) TestStoreUuid() {
+	now = func() time.Time {
+		return time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC)
+	}
+
+	err := suite.Bolt.StoreUuid("Mock", "d3ca513eb3e14946b58047f2bd3530fd")
+	suite.Require().Nil(err)
+	suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd:1587435016", string(suite.get(mojangUuidsBucket, "mock")))
+}
+
+var texturesWithSkin = &mojang.SignedTexturesResponse{
+	Id:   "dead24f9a4fa4877b7b04c8c6c72bb46",
+	Name: "mock",
+	Props: []*mojang.Property{
+		{
+			Name: "textures",
+			Value: mojang.EncodeTextures(&mojang.TexturesProp{
+				Timestamp:   time.Now().UnixNano() / 10e5,
+				ProfileID:   "dead24f9a4fa4877b7b04c8c6c72bb46",
+				ProfileName: "mock",
+				Textures: &mojang.TexturesResponse{
+					Skin: &mojang.SkinTexturesResponse{
+						Url: "http://textures.minecraft.net/texture/74d1e08b0bb7e9f590af27758125bbed1778ac6cef729aedfcb9613e9911ae75",
+					},
+				},
+			}),
+		},
+	},
+}
+
+func (suite *boltTestSuite) TestGetTextures() {
+	suite.RunSubTest("exists record", func() {
+		suite.Bolt.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+
+		textures, found, err := suite.Bolt.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Equal(texturesWithSkin, textures)
+	})
+
+	suite.RunSubTest("exists nil record", func() {
+		suite.Bolt.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
+
+		textures, found, err := suite.Bolt.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Nil(textures)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		textures, found, err := suite.Bolt.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		suite.Require().Nil(err)
+		suite.Require().False(found)
+		suite.Require().Nil(textures)
+	})
+
+	suite.RunSubTest("exists, but expired record", func() {
+		now = func() time.Time {
+			return time.Now().Add(-1 * time.Hour)
+		}
+		suite.Bolt.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+		now = time.Now
+
+		textures, found, err := suite.Bolt.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		suite.Require().Nil(err)
+		suite.Require().False(found)
+		suite.Require().Nil(textures)
+	})
+
+	suite.RunSubTest("exists nil record, expired by the separate ttl", func() {
+		defer func(ttl time.Duration) {
+			suite.Bolt.NilTexturesTTL = ttl
+		}(suite.Bolt.NilTexturesTTL)
+		suite.Bolt.NilTexturesTTL = time.Second
+
+		now = func() time.Time {
+			return time.Now().Add(-10 * time.Second)
+		}
+		suite.Bolt.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
+		suite.Bolt.StoreTextures("b5d58475007d4f9e9ddd1403e2497579", texturesWithSkin)
+		now = time.Now
+
+		_, found, _ := suite.Bolt.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		suite.Require().False(found)
+
+		_, found, _ = suite.Bolt.GetTextures("b5d58475007d4f9e9ddd1403e2497579")
+		suite.Require().True(found)
+	})
+}
+
+func (suite *boltTestSuite) TestGC() {
+	now = func() time.Time {
+		return time.Now().Add(-1 * time.Hour)
+	}
+	suite.Bolt.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+	now = time.Now
+	suite.Bolt.StoreTextures("b5d58475007d4f9e9ddd1403e2497579", nil)
+
+	err := suite.Bolt.gc()
+	suite.Require().Nil(err)
+	suite.Require().Nil(suite.get(mojangTexturesBucket, "dead24f9a4fa4877b7b04c8c6c72bb46"))
+	suite.Require().NotNil(suite.get(mojangTexturesBucket, "b5d58475007d4f9e9ddd1403e2497579"))
+}
+
+func (suite *boltTestSuite) TestPing() {
+	err := suite.Bolt.Ping()
+	suite.Require().Nil(err)
+}
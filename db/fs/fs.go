@@ -1,24 +1,177 @@
 package fs
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/elyby/chrly/model"
 )
 
+const capesDirName = "capes"
+const skinsDirName = "skins"
+const accountsDirName = "accounts"
+const accountIdsDirName = "ids"
+const revokedTokensDirName = "revoked-tokens"
+
 func New(basePath string) (*Filesystem, error) {
 	return &Filesystem{path: basePath}, nil
 }
 
+// Filesystem stores all textures data under the base path:
+//   capes/{username}.png      - capes files
+//   skins/{hash}.png          - uploaded skins files, stored by the hash of their contents,
+//                               so the same file uploaded by different users is stored only once
+//   accounts/{username}.json  - skins metadata
+//   accounts/ids/{id}         - index of the user id to the username of the skin record
+//   revoked-tokens/{jti}      - denylist of the revoked tokens, containing the token expiration timestamp
 type Filesystem struct {
 	path string
+	// CapesDirName overrides the name of the capes directory. It's kept for the installations,
+	// that have been configured with a custom directory before the layout was unified
+	CapesDirName string
+}
+
+func (f *Filesystem) FindSkinByUsername(username string) (*model.Skin, error) {
+	data, err := ioutil.ReadFile(f.buildAccountPath(username))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var skin *model.Skin
+	err = json.Unmarshal(data, &skin)
+	if err != nil {
+		return nil, err
+	}
+
+	skin.OldUsername = skin.Username
+
+	return skin, nil
+}
+
+func (f *Filesystem) FindSkinByUserId(id int) (*model.Skin, error) {
+	username, err := f.findUsernameByUserId(id)
+	if err != nil || username == "" {
+		return nil, err
+	}
+
+	return f.FindSkinByUsername(username)
+}
+
+func (f *Filesystem) SaveSkin(skin *model.Skin) error {
+	if skin.Username == "" {
+		return errors.New("unable to save a skin with an empty username")
+	}
+
+	// If user has changed username, then we must delete his old username record
+	if skin.OldUsername != "" && skin.OldUsername != skin.Username {
+		err := removeFile(f.buildAccountPath(skin.OldUsername))
+		if err != nil {
+			return err
+		}
+	}
+
+	data, _ := json.Marshal(skin)
+	err := writeFileAtomically(f.accountsPath(), strings.ToLower(skin.Username)+".json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	err = writeFileAtomically(f.accountIdsPath(), strconv.Itoa(skin.UserId), strings.NewReader(skin.Username))
+	if err != nil {
+		return err
+	}
+
+	skin.OldUsername = skin.Username
+
+	return nil
+}
+
+func (f *Filesystem) RemoveSkinByUserId(id int) error {
+	record, err := f.FindSkinByUserId(id)
+	if err != nil {
+		return err
+	}
+
+	err = removeFile(f.buildAccountIdPath(id))
+	if err != nil {
+		return err
+	}
+
+	if record != nil {
+		return removeFile(f.buildAccountPath(record.Username))
+	}
+
+	return nil
+}
+
+func (f *Filesystem) RemoveSkinByUsername(username string) error {
+	record, err := f.FindSkinByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	if record == nil {
+		return nil
+	}
+
+	err = removeFile(f.buildAccountPath(record.Username))
+	if err != nil {
+		return err
+	}
+
+	return removeFile(f.buildAccountIdPath(record.UserId))
+}
+
+func (f *Filesystem) FindSkinFileByHash(hash string) (*model.SkinFile, error) {
+	file, err := os.Open(f.buildSkinFilePath(hash))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &model.SkinFile{
+		Hash:       hash,
+		File:       file,
+		ModifiedAt: stat.ModTime(),
+	}, nil
+}
+
+func (f *Filesystem) SaveSkinFile(hash string, file io.Reader) error {
+	if hash == "" {
+		return errors.New("unable to save a skin file with an empty hash")
+	}
+
+	return writeFileAtomically(f.skinsPath(), strings.ToLower(hash)+".png", file)
 }
 
 func (f *Filesystem) FindCapeByUsername(username string) (*model.Cape, error) {
-	capePath := path.Join(f.path, strings.ToLower(username)+".png")
-	file, err := os.Open(capePath)
+	if username == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(f.buildCapePath(username))
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil
@@ -28,6 +181,152 @@ func (f *Filesystem) FindCapeByUsername(username string) (*model.Cape, error) {
 	}
 
 	return &model.Cape{
-		File: file,
+		Username: username,
+		File:     file,
 	}, nil
 }
+
+func (f *Filesystem) SaveCape(cape *model.Cape) error {
+	if cape.Username == "" {
+		return errors.New("unable to save a cape with an empty username")
+	}
+
+	return writeFileAtomically(f.capesPath(), strings.ToLower(cape.Username)+".png", cape.File)
+}
+
+func (f *Filesystem) RemoveCapeByUsername(username string) error {
+	return removeFile(f.buildCapePath(username))
+}
+
+func (f *Filesystem) IsTokenRevoked(jti string) (bool, error) {
+	if !isValidFileName(jti) {
+		return false, nil
+	}
+
+	data, err := ioutil.ReadFile(f.buildRevokedTokenPath(jti))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// The expired token will be rejected anyway, so its record is no longer needed
+	expiresAt, _ := strconv.ParseInt(string(data), 10, 64)
+	if expiresAt != 0 && time.Unix(expiresAt, 0).Before(time.Now()) {
+		return false, removeFile(f.buildRevokedTokenPath(jti))
+	}
+
+	return true, nil
+}
+
+func (f *Filesystem) RevokeToken(jti string, expiresAt time.Time) error {
+	if !isValidFileName(jti) {
+		return fmt.Errorf("unable to revoke a token with the jti \"%s\"", jti)
+	}
+
+	var timestamp int64
+	if !expiresAt.IsZero() {
+		timestamp = expiresAt.Unix()
+	}
+
+	return writeFileAtomically(f.revokedTokensPath(), jti, strings.NewReader(strconv.FormatInt(timestamp, 10)))
+}
+
+func (f *Filesystem) findUsernameByUserId(id int) (string, error) {
+	data, err := ioutil.ReadFile(f.buildAccountIdPath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+func (f *Filesystem) capesPath() string {
+	if f.CapesDirName != "" {
+		return path.Join(f.path, f.CapesDirName)
+	}
+
+	return path.Join(f.path, capesDirName)
+}
+
+func (f *Filesystem) skinsPath() string {
+	return path.Join(f.path, skinsDirName)
+}
+
+func (f *Filesystem) accountsPath() string {
+	return path.Join(f.path, accountsDirName)
+}
+
+func (f *Filesystem) accountIdsPath() string {
+	return path.Join(f.accountsPath(), accountIdsDirName)
+}
+
+func (f *Filesystem) revokedTokensPath() string {
+	return path.Join(f.path, revokedTokensDirName)
+}
+
+func (f *Filesystem) buildCapePath(username string) string {
+	return path.Join(f.capesPath(), strings.ToLower(username)+".png")
+}
+
+func (f *Filesystem) buildSkinFilePath(hash string) string {
+	return path.Join(f.skinsPath(), strings.ToLower(hash)+".png")
+}
+
+func (f *Filesystem) buildAccountPath(username string) string {
+	return path.Join(f.accountsPath(), strings.ToLower(username)+".json")
+}
+
+func (f *Filesystem) buildAccountIdPath(id int) string {
+	return path.Join(f.accountIdsPath(), strconv.Itoa(id))
+}
+
+func (f *Filesystem) buildRevokedTokenPath(jti string) string {
+	return path.Join(f.revokedTokensPath(), jti)
+}
+
+// The name must not allow to escape the target directory
+func isValidFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
+
+func removeFile(filePath string) error {
+	err := os.Remove(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Writes into a temporary file first and then moves it to the target path,
+// so readers will never see a partially written file
+func writeFileAtomically(dir string, name string, file io.Reader) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := ioutil.TempFile(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = io.Copy(tmpFile, file)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path.Join(dir, name))
+}
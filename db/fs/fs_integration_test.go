@@ -1,13 +1,17 @@
 package fs
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/model"
 )
 
 func TestNew(t *testing.T) {
@@ -16,22 +20,295 @@ func TestNew(t *testing.T) {
 	require.Equal(t, "base/path", fs.path)
 }
 
-func TestFilesystem(t *testing.T) {
+func createTempDir(prefix string) string {
+	dir, err := ioutil.TempDir("", prefix)
+	if err != nil {
+		panic(fmt.Errorf("cannot crete temp directory for tests: %w", err))
+	}
+
+	return dir
+}
+
+func createSkinModel(userId int, username string) *model.Skin {
+	return &model.Skin{
+		UserId:          userId,
+		Uuid:            "fd5da1e4d66d4d17aadee2446093896d",
+		Username:        username,
+		SkinId:          1,
+		Url:             "http://localhost/skin.png",
+		Is1_8:           true,
+		IsSlim:          false,
+		MojangTextures:  "mock-mojang-textures",
+		MojangSignature: "mock-mojang-signature",
+	}
+}
+
+func TestFilesystem_Skins(t *testing.T) {
+	t.Run("SaveSkin", func(t *testing.T) {
+		t.Run("save new entity", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			skin := createSkinModel(1, "Mock")
+			err := fs.SaveSkin(skin)
+			require.Nil(t, err)
+			require.Equal(t, "Mock", skin.OldUsername)
+
+			data, err := ioutil.ReadFile(path.Join(dir, "accounts", "mock.json"))
+			require.Nil(t, err)
+			require.JSONEq(t, `{
+				"userId": 1,
+				"uuid": "fd5da1e4d66d4d17aadee2446093896d",
+				"username": "Mock",
+				"skinId": 1,
+				"url": "http://localhost/skin.png",
+				"is1_8": true,
+				"isSlim": false,
+				"mojangTextures": "mock-mojang-textures",
+				"mojangSignature": "mock-mojang-signature",
+				"OldUsername": ""
+			}`, string(data))
+
+			data, err = ioutil.ReadFile(path.Join(dir, "accounts", "ids", "1"))
+			require.Nil(t, err)
+			require.Equal(t, "Mock", string(data))
+		})
+
+		t.Run("save exists record with changed username", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			skin := createSkinModel(1, "Mock")
+			_ = fs.SaveSkin(skin)
+
+			skin.Username = "NewMock"
+			err := fs.SaveSkin(skin)
+			require.Nil(t, err)
+			require.Equal(t, "NewMock", skin.OldUsername)
+
+			require.NoFileExists(t, path.Join(dir, "accounts", "mock.json"))
+			require.FileExists(t, path.Join(dir, "accounts", "newmock.json"))
+
+			data, err := ioutil.ReadFile(path.Join(dir, "accounts", "ids", "1"))
+			require.Nil(t, err)
+			require.Equal(t, "NewMock", string(data))
+		})
+
+		t.Run("empty username", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			err := fs.SaveSkin(createSkinModel(1, ""))
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("FindSkinByUsername", func(t *testing.T) {
+		dir := createTempDir("accounts")
+		defer os.RemoveAll(dir)
+
+		t.Run("exists record", func(t *testing.T) {
+			fs, _ := New(dir)
+			_ = fs.SaveSkin(createSkinModel(1, "Mock"))
+
+			skin, err := fs.FindSkinByUsername("mock")
+			require.Nil(t, err)
+			require.NotNil(t, skin)
+			require.Equal(t, 1, skin.UserId)
+			require.Equal(t, "Mock", skin.Username)
+			require.Equal(t, "Mock", skin.OldUsername)
+			require.Equal(t, "http://localhost/skin.png", skin.Url)
+			require.True(t, skin.Is1_8)
+			require.Equal(t, "mock-mojang-textures", skin.MojangTextures)
+		})
+
+		t.Run("not exists record", func(t *testing.T) {
+			fs, _ := New(dir)
+			skin, err := fs.FindSkinByUsername("not_exists")
+			require.Nil(t, err)
+			require.Nil(t, skin)
+		})
+
+		t.Run("invalid json encoding", func(t *testing.T) {
+			err := ioutil.WriteFile(path.Join(dir, "accounts", "invalid.json"), []byte("hello world"), 0644)
+			if err != nil {
+				panic(fmt.Errorf("cannot create temp account for tests: %w", err))
+			}
+
+			fs, _ := New(dir)
+			skin, err := fs.FindSkinByUsername("invalid")
+			require.Nil(t, skin)
+			require.EqualError(t, err, "invalid character 'h' looking for beginning of value")
+		})
+	})
+
+	t.Run("FindSkinByUserId", func(t *testing.T) {
+		dir := createTempDir("accounts")
+		defer os.RemoveAll(dir)
+
+		t.Run("exists record", func(t *testing.T) {
+			fs, _ := New(dir)
+			_ = fs.SaveSkin(createSkinModel(1, "Mock"))
+
+			skin, err := fs.FindSkinByUserId(1)
+			require.Nil(t, err)
+			require.NotNil(t, skin)
+			require.Equal(t, 1, skin.UserId)
+			require.Equal(t, "Mock", skin.Username)
+		})
+
+		t.Run("not exists record", func(t *testing.T) {
+			fs, _ := New(dir)
+			skin, err := fs.FindSkinByUserId(2)
+			require.Nil(t, err)
+			require.Nil(t, skin)
+		})
+
+		t.Run("exists id record, but no skin record", func(t *testing.T) {
+			err := ioutil.WriteFile(path.Join(dir, "accounts", "ids", "3"), []byte("Unknown"), 0644)
+			if err != nil {
+				panic(fmt.Errorf("cannot create temp account id for tests: %w", err))
+			}
+
+			fs, _ := New(dir)
+			skin, err := fs.FindSkinByUserId(3)
+			require.Nil(t, err)
+			require.Nil(t, skin)
+		})
+	})
+
+	t.Run("RemoveSkinByUserId", func(t *testing.T) {
+		t.Run("exists record", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			_ = fs.SaveSkin(createSkinModel(1, "Mock"))
+
+			err := fs.RemoveSkinByUserId(1)
+			require.Nil(t, err)
+			require.NoFileExists(t, path.Join(dir, "accounts", "mock.json"))
+			require.NoFileExists(t, path.Join(dir, "accounts", "ids", "1"))
+		})
+
+		t.Run("not exists record", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			err := fs.RemoveSkinByUserId(1)
+			require.Nil(t, err)
+		})
+	})
+
+	t.Run("RemoveSkinByUsername", func(t *testing.T) {
+		t.Run("exists record", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			_ = fs.SaveSkin(createSkinModel(1, "Mock"))
+
+			err := fs.RemoveSkinByUsername("mock")
+			require.Nil(t, err)
+			require.NoFileExists(t, path.Join(dir, "accounts", "mock.json"))
+			require.NoFileExists(t, path.Join(dir, "accounts", "ids", "1"))
+		})
+
+		t.Run("not exists record", func(t *testing.T) {
+			dir := createTempDir("accounts")
+			defer os.RemoveAll(dir)
+
+			fs, _ := New(dir)
+			err := fs.RemoveSkinByUsername("mock")
+			require.Nil(t, err)
+		})
+	})
+}
+
+func TestFilesystem_SkinsFiles(t *testing.T) {
+	dir := createTempDir("skins")
+	defer os.RemoveAll(dir)
+
+	t.Run("SaveSkinFile", func(t *testing.T) {
+		t.Run("save file into not existing directory", func(t *testing.T) {
+			fs, _ := New(path.Join(dir, "nested"))
+			err := fs.SaveSkinFile("mock_hash", bytes.NewBufferString("mock skin"))
+			require.Nil(t, err)
+
+			data, err := ioutil.ReadFile(path.Join(dir, "nested", "skins", "mock_hash.png"))
+			require.Nil(t, err)
+			require.Equal(t, "mock skin", string(data))
+
+			files, _ := ioutil.ReadDir(path.Join(dir, "nested", "skins"))
+			require.Len(t, files, 1)
+		})
+
+		t.Run("empty hash", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.SaveSkinFile("", bytes.NewBufferString("mock skin"))
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("FindSkinFileByHash", func(t *testing.T) {
+		t.Run("exists file", func(t *testing.T) {
+			fs, _ := New(dir)
+			_ = fs.SaveSkinFile("exists_hash", bytes.NewBufferString("mock skin"))
+
+			file, err := fs.FindSkinFileByHash("exists_hash")
+			require.Nil(t, err)
+			require.NotNil(t, file)
+			require.Equal(t, "exists_hash", file.Hash)
+			require.False(t, file.ModifiedAt.IsZero())
+			data, _ := ioutil.ReadAll(file.File)
+			require.Equal(t, "mock skin", string(data))
+		})
+
+		t.Run("not exists file", func(t *testing.T) {
+			fs, _ := New(dir)
+			file, err := fs.FindSkinFileByHash("not_exists_hash")
+			require.Nil(t, err)
+			require.Nil(t, file)
+		})
+	})
+}
+
+func TestFilesystem_Capes(t *testing.T) {
 	t.Run("FindCapeByUsername", func(t *testing.T) {
-		dir, err := ioutil.TempDir("", "capes")
-		if err != nil {
-			panic(fmt.Errorf("cannot crete temp directory for tests: %w", err))
-		}
+		dir := createTempDir("capes")
 		defer os.RemoveAll(dir)
 
 		t.Run("exists cape", func(t *testing.T) {
-			file, err := os.Create(path.Join(dir, "username.png"))
+			_ = os.MkdirAll(path.Join(dir, "capes"), 0755)
+			file, err := os.Create(path.Join(dir, "capes", "username.png"))
+			if err != nil {
+				panic(fmt.Errorf("cannot create temp skin for tests: %w", err))
+			}
+			defer os.Remove(file.Name())
+
+			fs, _ := New(dir)
+			cape, err := fs.FindCapeByUsername("username")
+			require.Nil(t, err)
+			require.NotNil(t, cape)
+			require.Equal(t, "username", cape.Username)
+			capeFile, _ := cape.File.(*os.File)
+			require.Equal(t, file.Name(), capeFile.Name())
+		})
+
+		t.Run("exists cape in the custom directory", func(t *testing.T) {
+			_ = os.MkdirAll(path.Join(dir, "custom-capes"), 0755)
+			file, err := os.Create(path.Join(dir, "custom-capes", "username.png"))
 			if err != nil {
 				panic(fmt.Errorf("cannot create temp skin for tests: %w", err))
 			}
 			defer os.Remove(file.Name())
 
 			fs, _ := New(dir)
+			fs.CapesDirName = "custom-capes"
 			cape, err := fs.FindCapeByUsername("username")
 			require.Nil(t, err)
 			require.NotNil(t, cape)
@@ -53,4 +330,137 @@ func TestFilesystem(t *testing.T) {
 			require.Nil(t, cape)
 		})
 	})
+
+	t.Run("SaveCape", func(t *testing.T) {
+		dir := createTempDir("capes")
+		defer os.RemoveAll(dir)
+
+		t.Run("save new cape", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.SaveCape(&model.Cape{
+				Username: "UserName",
+				File:     bytes.NewBufferString("mock cape"),
+			})
+			require.Nil(t, err)
+
+			data, err := ioutil.ReadFile(path.Join(dir, "capes", "username.png"))
+			require.Nil(t, err)
+			require.Equal(t, "mock cape", string(data))
+		})
+
+		t.Run("replace exists cape", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.SaveCape(&model.Cape{
+				Username: "username",
+				File:     bytes.NewBufferString("new mock cape"),
+			})
+			require.Nil(t, err)
+
+			data, err := ioutil.ReadFile(path.Join(dir, "capes", "username.png"))
+			require.Nil(t, err)
+			require.Equal(t, "new mock cape", string(data))
+		})
+
+		t.Run("empty username", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.SaveCape(&model.Cape{
+				File: bytes.NewBufferString("mock cape"),
+			})
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("RemoveCapeByUsername", func(t *testing.T) {
+		dir := createTempDir("capes")
+		defer os.RemoveAll(dir)
+
+		t.Run("exists cape", func(t *testing.T) {
+			_ = os.MkdirAll(path.Join(dir, "capes"), 0755)
+			err := ioutil.WriteFile(path.Join(dir, "capes", "username.png"), []byte("mock cape"), 0644)
+			if err != nil {
+				panic(fmt.Errorf("cannot create temp cape for tests: %w", err))
+			}
+
+			fs, _ := New(dir)
+			err = fs.RemoveCapeByUsername("UserName")
+			require.Nil(t, err)
+			require.NoFileExists(t, path.Join(dir, "capes", "username.png"))
+		})
+
+		t.Run("not exists cape", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.RemoveCapeByUsername("username")
+			require.Nil(t, err)
+		})
+	})
+}
+
+func TestFilesystem_RevokedTokens(t *testing.T) {
+	t.Run("RevokeToken", func(t *testing.T) {
+		dir := createTempDir("revoked-tokens")
+		defer os.RemoveAll(dir)
+
+		t.Run("save token into not existing directory", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.RevokeToken("a2b4c6", time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC))
+			require.Nil(t, err)
+
+			data, _ := ioutil.ReadFile(path.Join(dir, "revoked-tokens", "a2b4c6"))
+			require.Equal(t, "1587435016", string(data))
+		})
+
+		t.Run("token without expiration", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.RevokeToken("a2b4c6", time.Time{})
+			require.Nil(t, err)
+
+			data, _ := ioutil.ReadFile(path.Join(dir, "revoked-tokens", "a2b4c6"))
+			require.Equal(t, "0", string(data))
+		})
+
+		t.Run("invalid jti", func(t *testing.T) {
+			fs, _ := New(dir)
+			err := fs.RevokeToken("../a2b4c6", time.Time{})
+			require.EqualError(t, err, "unable to revoke a token with the jti \"../a2b4c6\"")
+		})
+	})
+
+	t.Run("IsTokenRevoked", func(t *testing.T) {
+		dir := createTempDir("revoked-tokens")
+		defer os.RemoveAll(dir)
+
+		writeToken := func(jti string, expiresAt int64) {
+			_ = os.MkdirAll(path.Join(dir, "revoked-tokens"), 0755)
+			err := ioutil.WriteFile(path.Join(dir, "revoked-tokens", jti), []byte(fmt.Sprintf("%d", expiresAt)), 0644)
+			if err != nil {
+				panic(fmt.Errorf("cannot create temp token for tests: %w", err))
+			}
+		}
+
+		t.Run("revoked token", func(t *testing.T) {
+			writeToken("a2b4c6", time.Now().Add(time.Hour).Unix())
+
+			fs, _ := New(dir)
+			isRevoked, err := fs.IsTokenRevoked("a2b4c6")
+			require.Nil(t, err)
+			require.True(t, isRevoked)
+		})
+
+		t.Run("expired record", func(t *testing.T) {
+			writeToken("a2b4c6", time.Now().Add(-time.Hour).Unix())
+
+			fs, _ := New(dir)
+			isRevoked, err := fs.IsTokenRevoked("a2b4c6")
+			require.Nil(t, err)
+			require.False(t, isRevoked)
+			require.NoFileExists(t, path.Join(dir, "revoked-tokens", "a2b4c6"))
+		})
+
+		t.Run("not revoked token", func(t *testing.T) {
+			fs, _ := New(dir)
+			isRevoked, err := fs.IsTokenRevoked("d8e0f2")
+			require.Nil(t, err)
+			require.False(t, isRevoked)
+		})
+	})
 }
@@ -3,16 +3,20 @@ package redis
 import (
 	"bytes"
 	"compress/zlib"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
-	"strconv"
+	"io/ioutil"
 	"strings"
 	"time"
 
 	"github.com/mediocregopher/radix.v2/pool"
+	"github.com/mediocregopher/radix.v2/pubsub"
 	"github.com/mediocregopher/radix.v2/redis"
 	"github.com/mediocregopher/radix.v2/util"
 
+	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/model"
 )
 
@@ -25,14 +29,28 @@ func New(addr string, poolSize int) (*Redis, error) {
 	}
 
 	return &Redis{
-		pool: conn,
+		UuidTTL:        time.Hour * 24 * 30,
+		NilUuidTTL:     time.Hour * 24,
+		TexturesTTL:    time.Minute + 10*time.Second,
+		NilTexturesTTL: time.Minute + 10*time.Second,
+		addr:           addr,
+		pool:           conn,
 	}, nil
 }
 
 const accountIdToUsernameKey = "hash:username-to-account-id" // TODO: this should be actually "hash:user-id-to-username"
-const mojangUsernameToUuidKey = "hash:mojang-username-to-uuid"
 
 type Redis struct {
+	// UuidTTL specifies how long the Mojang's UUID of the username is stored
+	UuidTTL time.Duration
+	// NilUuidTTL specifies how long the information about the absence of the Mojang's account is stored
+	NilUuidTTL time.Duration
+	// TexturesTTL specifies how long the Mojang's textures are stored
+	TexturesTTL time.Duration
+	// NilTexturesTTL specifies how long the information about the absence of Mojang's textures is stored
+	NilTexturesTTL time.Duration
+
+	addr string
 	pool *pool.Pool
 }
 
@@ -185,6 +203,146 @@ func removeByUsername(username string, conn util.Cmder) error {
 	return nil
 }
 
+func (db *Redis) FindSkinFileByHash(hash string) (*model.SkinFile, error) {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer db.pool.Put(conn)
+
+	return findSkinFileByHash(hash, conn)
+}
+
+func findSkinFileByHash(hash string, conn util.Cmder) (*model.SkinFile, error) {
+	response := conn.Cmd("HMGET", buildSkinFileKey(hash), "data", "modifiedAt")
+	if response.Err != nil {
+		return nil, response.Err
+	}
+
+	values, _ := response.Array()
+	if len(values) != 2 || values[0].IsType(redis.Nil) {
+		return nil, nil
+	}
+
+	data, err := values[0].Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	modifiedAt, err := values[1].Int64()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.SkinFile{
+		Hash:       hash,
+		File:       bytes.NewReader(data),
+		ModifiedAt: time.Unix(modifiedAt, 0),
+	}, nil
+}
+
+func (db *Redis) SaveSkinFile(hash string, file io.Reader) error {
+	if hash == "" {
+		return errors.New("unable to save a skin file with an empty hash")
+	}
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer db.pool.Put(conn)
+
+	return saveSkinFile(hash, data, conn)
+}
+
+func saveSkinFile(hash string, data []byte, conn util.Cmder) error {
+	res := conn.Cmd("HMSET", buildSkinFileKey(hash), "data", data, "modifiedAt", now().Unix())
+	if res.IsType(redis.Err) {
+		return res.Err
+	}
+
+	return nil
+}
+
+func (db *Redis) FindCapeByUsername(username string) (*model.Cape, error) {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return nil, err
+	}
+	defer db.pool.Put(conn)
+
+	return findCapeByUsername(username, conn)
+}
+
+func findCapeByUsername(username string, conn util.Cmder) (*model.Cape, error) {
+	response := conn.Cmd("GET", buildCapeKey(username))
+	if response.IsType(redis.Nil) {
+		return nil, nil
+	}
+
+	data, err := response.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Cape{
+		Username: username,
+		File:     bytes.NewReader(data),
+	}, nil
+}
+
+func (db *Redis) SaveCape(cape *model.Cape) error {
+	if cape.Username == "" {
+		return errors.New("unable to save a cape with an empty username")
+	}
+
+	data, err := ioutil.ReadAll(cape.File)
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer db.pool.Put(conn)
+
+	return saveCape(cape.Username, data, conn)
+}
+
+func saveCape(username string, data []byte, conn util.Cmder) error {
+	res := conn.Cmd("SET", buildCapeKey(username), data)
+	if res.IsType(redis.Err) {
+		return res.Err
+	}
+
+	return nil
+}
+
+func (db *Redis) RemoveCapeByUsername(username string) error {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer db.pool.Put(conn)
+
+	return removeCapeByUsername(username, conn)
+}
+
+func removeCapeByUsername(username string, conn util.Cmder) error {
+	res := conn.Cmd("DEL", buildCapeKey(username))
+	if res.IsType(redis.Err) {
+		return res.Err
+	}
+
+	return nil
+}
+
 func (db *Redis) GetUuid(username string) (string, bool, error) {
 	conn, err := db.pool.Get()
 	if err != nil {
@@ -196,22 +354,17 @@ func (db *Redis) GetUuid(username string) (string, bool, error) {
 }
 
 func findMojangUuidByUsername(username string, conn util.Cmder) (string, bool, error) {
-	key := strings.ToLower(username)
-	response := conn.Cmd("HGET", mojangUsernameToUuidKey, key)
+	response := conn.Cmd("GET", buildMojangUuidKey(username))
 	if response.IsType(redis.Nil) {
 		return "", false, nil
 	}
 
-	data, _ := response.Str()
-	parts := strings.Split(data, ":")
-	timestamp, _ := strconv.ParseInt(parts[1], 10, 64)
-	storedAt := time.Unix(timestamp, 0)
-	if storedAt.Add(time.Hour * 24 * 30).Before(now()) {
-		conn.Cmd("HDEL", mojangUsernameToUuidKey, key)
-		return "", false, nil
+	uuid, err := response.Str()
+	if err != nil {
+		return "", false, err
 	}
 
-	return parts[0], true, nil
+	return uuid, true, nil
 }
 
 func (db *Redis) StoreUuid(username string, uuid string) error {
@@ -221,12 +374,16 @@ func (db *Redis) StoreUuid(username string, uuid string) error {
 	}
 	defer db.pool.Put(conn)
 
-	return storeMojangUuid(username, uuid, conn)
+	ttl := db.UuidTTL
+	if uuid == "" {
+		ttl = db.NilUuidTTL
+	}
+
+	return storeMojangUuid(username, uuid, ttl, conn)
 }
 
-func storeMojangUuid(username string, uuid string, conn util.Cmder) error {
-	value := uuid + ":" + strconv.FormatInt(now().Unix(), 10)
-	res := conn.Cmd("HSET", mojangUsernameToUuidKey, strings.ToLower(username), value)
+func storeMojangUuid(username string, uuid string, ttl time.Duration, conn util.Cmder) error {
+	res := conn.Cmd("SET", buildMojangUuidKey(username), uuid, "PX", ttl.Milliseconds())
 	if res.IsType(redis.Err) {
 		return res.Err
 	}
@@ -234,6 +391,159 @@ func storeMojangUuid(username string, uuid string, conn util.Cmder) error {
 	return nil
 }
 
+func (db *Redis) GetTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return nil, false, err
+	}
+	defer db.pool.Put(conn)
+
+	return findMojangTextures(uuid, conn)
+}
+
+func findMojangTextures(uuid string, conn util.Cmder) (*mojang.SignedTexturesResponse, bool, error) {
+	response := conn.Cmd("GET", buildMojangTexturesKey(uuid))
+	if response.IsType(redis.Nil) {
+		return nil, false, nil
+	}
+
+	encodedResult, err := response.Bytes()
+	if err != nil {
+		return nil, false, err
+	}
+
+	result, err := zlibDecode(encodedResult)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var textures *mojang.SignedTexturesResponse
+	err = json.Unmarshal(result, &textures)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return textures, true, nil
+}
+
+func (db *Redis) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return
+	}
+	defer db.pool.Put(conn)
+
+	ttl := db.TexturesTTL
+	if textures == nil {
+		ttl = db.NilTexturesTTL
+	}
+
+	storeMojangTextures(uuid, textures, ttl, conn)
+}
+
+func storeMojangTextures(uuid string, textures *mojang.SignedTexturesResponse, ttl time.Duration, conn util.Cmder) {
+	str, _ := json.Marshal(textures)
+	conn.Cmd("SET", buildMojangTexturesKey(uuid), zlibEncode(str), "PX", ttl.Milliseconds())
+}
+
+func (db *Redis) IsTokenRevoked(jti string) (bool, error) {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return false, err
+	}
+	defer db.pool.Put(conn)
+
+	return isTokenRevoked(jti, conn)
+}
+
+func isTokenRevoked(jti string, conn util.Cmder) (bool, error) {
+	response := conn.Cmd("EXISTS", buildRevokedTokenKey(jti))
+	if response.IsType(redis.Err) {
+		return false, response.Err
+	}
+
+	exists, err := response.Int()
+	if err != nil {
+		return false, err
+	}
+
+	return exists == 1, nil
+}
+
+func (db *Redis) RevokeToken(jti string, expiresAt time.Time) error {
+	conn, err := db.pool.Get()
+	if err != nil {
+		return err
+	}
+	defer db.pool.Put(conn)
+
+	return revokeToken(jti, expiresAt, conn)
+}
+
+func revokeToken(jti string, expiresAt time.Time, conn util.Cmder) error {
+	args := []interface{}{buildRevokedTokenKey(jti), expiresAt.Unix()}
+	if !expiresAt.IsZero() {
+		ttl := expiresAt.Sub(now())
+		if ttl <= 0 {
+			// The token is already expired, so there is no need to store it
+			return nil
+		}
+
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+
+	res := conn.Cmd("SET", args...)
+	if res.IsType(redis.Err) {
+		return res.Err
+	}
+
+	return nil
+}
+
+func (db *Redis) Publish(channel string, message []byte) error {
+	return db.pool.Cmd("PUBLISH", channel, message).Err
+}
+
+func (db *Redis) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	// The subscribed connection can't be used for other commands, so it isn't taken from the pool
+	conn, err := redis.Dial("tcp", db.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	subClient := pubsub.NewSubClient(conn)
+	if resp := subClient.Subscribe(channel); resp.Err != nil {
+		return resp.Err
+	}
+
+	// Closing the connection is the only way to interrupt the blocked Receive call
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	for {
+		resp := subClient.Receive()
+		if resp.Err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return resp.Err
+		}
+
+		if resp.Type == pubsub.Message {
+			handler([]byte(resp.Message))
+		}
+	}
+}
+
 func (db *Redis) Ping() error {
 	r := db.pool.Cmd("PING")
 	if r.Err != nil {
@@ -251,6 +561,26 @@ func buildUsernameKey(username string) string {
 	return "username:" + strings.ToLower(username)
 }
 
+func buildSkinFileKey(hash string) string {
+	return "skin-file:" + strings.ToLower(hash)
+}
+
+func buildCapeKey(username string) string {
+	return "cape:" + strings.ToLower(username)
+}
+
+func buildMojangUuidKey(username string) string {
+	return "mojang-uuid:" + strings.ToLower(username)
+}
+
+func buildMojangTexturesKey(uuid string) string {
+	return "mojang-textures:" + strings.ToLower(uuid)
+}
+
+func buildRevokedTokenKey(jti string) string {
+	return "revoked-token:" + jti
+}
+
 func zlibEncode(str []byte) []byte {
 	var buff bytes.Buffer
 	writer := zlib.NewWriter(&buff)
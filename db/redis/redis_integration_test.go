@@ -3,7 +3,10 @@
 package redis
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"testing"
 	"time"
@@ -12,6 +15,7 @@ import (
 	assert "github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/model"
 )
 
@@ -308,13 +312,110 @@ func (suite *redisTestSuite) TestRemoveSkinByUsername() {
 	})
 }
 
+func (suite *redisTestSuite) TestFindSkinFileByHash() {
+	suite.RunSubTest("exists record", func() {
+		suite.cmd("HMSET", "skin-file:mock-hash", "data", "mock skin file", "modifiedAt", 1587435016)
+
+		skinFile, err := suite.Redis.FindSkinFileByHash("mock-hash")
+		suite.Require().Nil(err)
+		suite.Require().NotNil(skinFile)
+		suite.Require().Equal("mock-hash", skinFile.Hash)
+		suite.Require().Equal(time.Unix(1587435016, 0), skinFile.ModifiedAt)
+		data, _ := ioutil.ReadAll(skinFile.File)
+		suite.Require().Equal("mock skin file", string(data))
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		skinFile, err := suite.Redis.FindSkinFileByHash("mock-hash")
+		suite.Require().Nil(err)
+		suite.Require().Nil(skinFile)
+	})
+}
+
+func (suite *redisTestSuite) TestSaveSkinFile() {
+	suite.RunSubTest("save new file", func() {
+		now = func() time.Time {
+			return time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC)
+		}
+
+		err := suite.Redis.SaveSkinFile("mock-hash", bytes.NewBufferString("mock skin file"))
+		suite.Require().Nil(err)
+
+		resp := suite.cmd("HGETALL", "skin-file:mock-hash")
+		values, _ := resp.Map()
+		suite.Require().Equal(map[string]string{
+			"data":       "mock skin file",
+			"modifiedAt": "1587435016",
+		}, values)
+	})
+
+	suite.RunSubTest("empty hash", func() {
+		err := suite.Redis.SaveSkinFile("", bytes.NewBufferString("mock skin file"))
+		suite.Require().EqualError(err, "unable to save a skin file with an empty hash")
+	})
+}
+
+func (suite *redisTestSuite) TestFindCapeByUsername() {
+	suite.RunSubTest("exists record", func() {
+		suite.cmd("SET", "cape:mock", "mock cape file")
+
+		cape, err := suite.Redis.FindCapeByUsername("Mock")
+		suite.Require().Nil(err)
+		suite.Require().NotNil(cape)
+		suite.Require().Equal("Mock", cape.Username)
+		data, _ := ioutil.ReadAll(cape.File)
+		suite.Require().Equal("mock cape file", string(data))
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		cape, err := suite.Redis.FindCapeByUsername("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Nil(cape)
+	})
+}
+
+func (suite *redisTestSuite) TestSaveCape() {
+	suite.RunSubTest("save new cape", func() {
+		err := suite.Redis.SaveCape(&model.Cape{
+			Username: "Mock",
+			File:     bytes.NewBufferString("mock cape file"),
+		})
+		suite.Require().Nil(err)
+
+		resp := suite.cmd("GET", "cape:mock")
+		suite.Require().False(resp.IsType(redis.Nil))
+		str, _ := resp.Str()
+		suite.Require().Equal("mock cape file", str)
+	})
+
+	suite.RunSubTest("empty username", func() {
+		err := suite.Redis.SaveCape(&model.Cape{
+			File: bytes.NewBufferString("mock cape file"),
+		})
+		suite.Require().EqualError(err, "unable to save a cape with an empty username")
+	})
+}
+
+func (suite *redisTestSuite) TestRemoveCapeByUsername() {
+	suite.RunSubTest("exists record", func() {
+		suite.cmd("SET", "cape:mock", "mock cape file")
+
+		err := suite.Redis.RemoveCapeByUsername("Mock")
+		suite.Require().Nil(err)
+
+		resp := suite.cmd("GET", "cape:mock")
+		suite.Require().True(resp.IsType(redis.Nil))
+	})
+
+	suite.RunSubTest("no records", func() {
+		err := suite.Redis.RemoveCapeByUsername("Mock")
+		suite.Require().Nil(err)
+	})
+}
+
 func (suite *redisTestSuite) TestGetUuid() {
 	suite.RunSubTest("exists record", func() {
-		suite.cmd("HSET",
-			"hash:mojang-username-to-uuid",
-			"mock",
-			fmt.Sprintf("%s:%d", "d3ca513eb3e14946b58047f2bd3530fd", time.Now().Unix()),
-		)
+		suite.cmd("SET", "mojang-uuid:mock", "d3ca513eb3e14946b58047f2bd3530fd")
 
 		uuid, found, err := suite.Redis.GetUuid("Mock")
 		suite.Require().Nil(err)
@@ -323,16 +424,12 @@ func (suite *redisTestSuite) TestGetUuid() {
 	})
 
 	suite.RunSubTest("exists record with empty uuid value", func() {
-		suite.cmd("HSET",
-			"hash:mojang-username-to-uuid",
-			"mock",
-			fmt.Sprintf(":%d", time.Now().Unix()),
-		)
+		suite.cmd("SET", "mojang-uuid:mock", "")
 
 		uuid, found, err := suite.Redis.GetUuid("Mock")
 		suite.Require().Nil(err)
 		suite.Require().True(found)
-		suite.Require().Empty("", uuid)
+		suite.Require().Empty(uuid)
 	})
 
 	suite.RunSubTest("not exists record", func() {
@@ -341,36 +438,172 @@ func (suite *redisTestSuite) TestGetUuid() {
 		suite.Require().False(found)
 		suite.Require().Empty(uuid)
 	})
+}
+
+func (suite *redisTestSuite) TestStoreUuid() {
+	suite.RunSubTest("store uuid with positive ttl", func() {
+		suite.Redis.UuidTTL = time.Hour
+		suite.Redis.NilUuidTTL = time.Minute
 
-	suite.RunSubTest("exists, but expired record", func() {
-		suite.cmd("HSET",
-			"hash:mojang-username-to-uuid",
-			"mock",
-			fmt.Sprintf("%s:%d", "d3ca513eb3e14946b58047f2bd3530fd", time.Now().Add(-1*time.Hour*24*31).Unix()),
-		)
+		err := suite.Redis.StoreUuid("Mock", "d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(err)
 
-		uuid, found, err := suite.Redis.GetUuid("Mock")
-		suite.Require().Empty(uuid)
+		resp := suite.cmd("GET", "mojang-uuid:mock")
+		suite.Require().False(resp.IsType(redis.Nil))
+		str, _ := resp.Str()
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", str)
+
+		ttl, _ := suite.cmd("PTTL", "mojang-uuid:mock").Int64()
+		suite.Require().True(ttl > int64(time.Minute/time.Millisecond))
+		suite.Require().True(ttl <= int64(time.Hour/time.Millisecond))
+	})
+
+	suite.RunSubTest("store empty uuid with negative ttl", func() {
+		suite.Redis.UuidTTL = time.Hour
+		suite.Redis.NilUuidTTL = time.Minute
+
+		err := suite.Redis.StoreUuid("Mock", "")
+		suite.Require().Nil(err)
+
+		ttl, _ := suite.cmd("PTTL", "mojang-uuid:mock").Int64()
+		suite.Require().True(ttl > 0)
+		suite.Require().True(ttl <= int64(time.Minute/time.Millisecond))
+	})
+}
+
+func (suite *redisTestSuite) TestGetTextures() {
+	suite.RunSubTest("exists record", func() {
+		textures := &mojang.SignedTexturesResponse{Id: "d3ca513eb3e14946b58047f2bd3530fd", Name: "mock"}
+		suite.Redis.StoreTextures("d3ca513eb3e14946b58047f2bd3530fd", textures)
+
+		result, found, err := suite.Redis.GetTextures("d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Equal(textures.Id, result.Id)
+		suite.Require().Equal(textures.Name, result.Name)
+	})
+
+	suite.RunSubTest("exists nil record", func() {
+		suite.Redis.StoreTextures("d3ca513eb3e14946b58047f2bd3530fd", nil)
+
+		result, found, err := suite.Redis.GetTextures("d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Nil(result)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		result, found, err := suite.Redis.GetTextures("d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(err)
+		suite.Require().False(found)
+		suite.Require().Nil(result)
+	})
+
+	suite.RunSubTest("invalid zlib encoding", func() {
+		suite.cmd("SET", "mojang-textures:d3ca513eb3e14946b58047f2bd3530fd", "this is really not zlib")
+
+		result, found, err := suite.Redis.GetTextures("d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(result)
 		suite.Require().False(found)
+		suite.Require().EqualError(err, "zlib: invalid header")
+	})
+}
+
+func (suite *redisTestSuite) TestStoreTextures() {
+	suite.RunSubTest("store textures with positive ttl", func() {
+		suite.Redis.TexturesTTL = time.Hour
+		suite.Redis.NilTexturesTTL = time.Minute
+		suite.Redis.StoreTextures("d3ca513eb3e14946b58047f2bd3530fd", &mojang.SignedTexturesResponse{})
+
+		ttl, _ := suite.cmd("PTTL", "mojang-textures:d3ca513eb3e14946b58047f2bd3530fd").Int64()
+		suite.Require().True(ttl > int64(time.Minute/time.Millisecond))
+		suite.Require().True(ttl <= int64(time.Hour/time.Millisecond))
+	})
+
+	suite.RunSubTest("store nil textures with negative ttl", func() {
+		suite.Redis.TexturesTTL = time.Hour
+		suite.Redis.NilTexturesTTL = time.Minute
+		suite.Redis.StoreTextures("d3ca513eb3e14946b58047f2bd3530fd", nil)
+
+		ttl, _ := suite.cmd("PTTL", "mojang-textures:d3ca513eb3e14946b58047f2bd3530fd").Int64()
+		suite.Require().True(ttl > 0)
+		suite.Require().True(ttl <= int64(time.Minute/time.Millisecond))
+	})
+}
+
+func (suite *redisTestSuite) TestIsTokenRevoked() {
+	suite.RunSubTest("revoked token", func() {
+		suite.cmd("SET", "revoked-token:a2b4c6", 0)
+
+		isRevoked, err := suite.Redis.IsTokenRevoked("a2b4c6")
 		suite.Require().Nil(err)
+		suite.Require().True(isRevoked)
+	})
 
-		resp := suite.cmd("HGET", "hash:mojang-username-to-uuid", "mock")
-		suite.Require().True(resp.IsType(redis.Nil), "should cleanup expired records")
+	suite.RunSubTest("not revoked token", func() {
+		isRevoked, err := suite.Redis.IsTokenRevoked("a2b4c6")
+		suite.Require().Nil(err)
+		suite.Require().False(isRevoked)
 	})
 }
 
-func (suite *redisTestSuite) TestStoreUuid() {
-	now = func() time.Time {
-		return time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC)
-	}
+func (suite *redisTestSuite) TestRevokeToken() {
+	suite.RunSubTest("revoke token with expiration", func() {
+		err := suite.Redis.RevokeToken("a2b4c6", time.Now().Add(time.Hour))
+		suite.Require().Nil(err)
 
-	err := suite.Redis.StoreUuid("Mock", "d3ca513eb3e14946b58047f2bd3530fd")
+		ttl, _ := suite.cmd("PTTL", "revoked-token:a2b4c6").Int64()
+		suite.Require().True(ttl > int64(time.Minute/time.Millisecond))
+		suite.Require().True(ttl <= int64(time.Hour/time.Millisecond))
+	})
+
+	suite.RunSubTest("revoke token without expiration", func() {
+		err := suite.Redis.RevokeToken("a2b4c6", time.Time{})
+		suite.Require().Nil(err)
+
+		ttl, _ := suite.cmd("PTTL", "revoked-token:a2b4c6").Int64()
+		suite.Require().Equal(int64(-1), ttl)
+	})
+
+	suite.RunSubTest("skip already expired token", func() {
+		err := suite.Redis.RevokeToken("a2b4c6", time.Now().Add(-time.Hour))
+		suite.Require().Nil(err)
+
+		exists, _ := suite.cmd("EXISTS", "revoked-token:a2b4c6").Int()
+		suite.Require().Equal(0, exists)
+	})
+}
+
+func (suite *redisTestSuite) TestPublishSubscribe() {
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan []byte, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- suite.Redis.Subscribe(ctx, "mock-channel", func(message []byte) {
+			messages <- message
+		})
+	}()
+
+	// Wait until the subscription is established
+	time.Sleep(100 * time.Millisecond)
+
+	err := suite.Redis.Publish("mock-channel", []byte("mock message"))
 	suite.Require().Nil(err)
 
-	resp := suite.cmd("HGET", "hash:mojang-username-to-uuid", "mock")
-	suite.Require().False(resp.IsType(redis.Nil))
-	str, _ := resp.Str()
-	suite.Require().Equal(str, "d3ca513eb3e14946b58047f2bd3530fd:1587435016")
+	select {
+	case message := <-messages:
+		suite.Require().Equal([]byte("mock message"), message)
+	case <-time.After(time.Second):
+		suite.Fail("the message hasn't been received")
+	}
+
+	cancel()
+	select {
+	case err := <-result:
+		suite.Require().Equal(context.Canceled, err)
+	case <-time.After(time.Second):
+		suite.Fail("the subscription hasn't been stopped")
+	}
 }
 
 func (suite *redisTestSuite) TestPing() {
@@ -0,0 +1,36 @@
+package sql
+
+// Each migration is a list of statements, that will be executed in a single transaction.
+// The version of the migration is its position in the list, starting from 1.
+//
+// Never modify already released migrations, always append a new one.
+var migrations = [][]string{
+	// 1: initial schema
+	{
+		`CREATE TABLE skins (
+			user_id          INTEGER      NOT NULL PRIMARY KEY,
+			uuid             VARCHAR(36)  NOT NULL,
+			username         VARCHAR(255) NOT NULL,
+			skin_id          INTEGER      NOT NULL,
+			url              TEXT         NOT NULL,
+			is_1_8           BOOLEAN      NOT NULL,
+			is_slim          BOOLEAN      NOT NULL,
+			mojang_textures  TEXT         NOT NULL,
+			mojang_signature TEXT         NOT NULL,
+			skin_hash        VARCHAR(64)  NOT NULL
+		)`,
+		`CREATE UNIQUE INDEX skins_username_idx ON skins (LOWER(username))`,
+		`CREATE TABLE mojang_uuids (
+			username  VARCHAR(255) NOT NULL PRIMARY KEY,
+			uuid      VARCHAR(36)  NOT NULL,
+			stored_at BIGINT       NOT NULL
+		)`,
+	},
+	// 2: revoked tokens denylist
+	{
+		`CREATE TABLE revoked_tokens (
+			jti        VARCHAR(255) NOT NULL PRIMARY KEY,
+			expires_at BIGINT       NOT NULL
+		)`,
+	},
+}
@@ -0,0 +1,316 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/elyby/chrly/model"
+)
+
+var now = time.Now
+
+const SQLiteDriver = "sqlite3"
+const PostgresDriver = "postgres"
+
+// New opens a connection to the database and applies all pending migrations.
+// Supported drivers are "sqlite3" and "postgres".
+func New(driverName string, dsn string) (*SQL, error) {
+	if driverName != SQLiteDriver && driverName != PostgresDriver {
+		return nil, fmt.Errorf("unsupported sql driver \"%s\"", driverName)
+	}
+
+	conn, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows only one writer at a time, so concurrent connections will only lead to "database is locked" errors
+	if driverName == SQLiteDriver {
+		conn.SetMaxOpenConns(1)
+	}
+
+	db := &SQL{
+		UuidTTL:    time.Hour * 24 * 30,
+		NilUuidTTL: time.Hour * 24,
+		conn:       conn,
+		driverName: driverName,
+	}
+
+	err = db.migrate()
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("unable to apply migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+type SQL struct {
+	// UuidTTL specifies how long the Mojang's UUID of the username is considered valid
+	UuidTTL time.Duration
+	// NilUuidTTL specifies how long the information about the absence of the Mojang's account is considered valid
+	NilUuidTTL time.Duration
+
+	conn       *sql.DB
+	driverName string
+}
+
+const skinColumns = "user_id, uuid, username, skin_id, url, is_1_8, is_slim, mojang_textures, mojang_signature, skin_hash"
+
+func (db *SQL) FindSkinByUsername(username string) (*model.Skin, error) {
+	return db.findSkin("LOWER(username) = LOWER(?)", username)
+}
+
+func (db *SQL) FindSkinByUserId(id int) (*model.Skin, error) {
+	return db.findSkin("user_id = ?", id)
+}
+
+func (db *SQL) findSkin(condition string, args ...interface{}) (*model.Skin, error) {
+	row := db.conn.QueryRow(db.rebind("SELECT "+skinColumns+" FROM skins WHERE "+condition), args...)
+
+	skin := &model.Skin{}
+	err := row.Scan(
+		&skin.UserId,
+		&skin.Uuid,
+		&skin.Username,
+		&skin.SkinId,
+		&skin.Url,
+		&skin.Is1_8,
+		&skin.IsSlim,
+		&skin.MojangTextures,
+		&skin.MojangSignature,
+		&skin.SkinHash,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	skin.OldUsername = skin.Username
+
+	return skin, nil
+}
+
+func (db *SQL) SaveSkin(skin *model.Skin) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Username may be already occupied by the other user's record, so it must be released first
+	_, err = tx.Exec(
+		db.rebind("DELETE FROM skins WHERE LOWER(username) = LOWER(?) AND user_id <> ?"),
+		skin.Username,
+		skin.UserId,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(db.rebind(`INSERT INTO skins (`+skinColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			uuid = excluded.uuid,
+			username = excluded.username,
+			skin_id = excluded.skin_id,
+			url = excluded.url,
+			is_1_8 = excluded.is_1_8,
+			is_slim = excluded.is_slim,
+			mojang_textures = excluded.mojang_textures,
+			mojang_signature = excluded.mojang_signature,
+			skin_hash = excluded.skin_hash`),
+		skin.UserId,
+		skin.Uuid,
+		skin.Username,
+		skin.SkinId,
+		skin.Url,
+		skin.Is1_8,
+		skin.IsSlim,
+		skin.MojangTextures,
+		skin.MojangSignature,
+		skin.SkinHash,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	skin.OldUsername = skin.Username
+
+	return nil
+}
+
+func (db *SQL) RemoveSkinByUserId(id int) error {
+	_, err := db.conn.Exec(db.rebind("DELETE FROM skins WHERE user_id = ?"), id)
+
+	return err
+}
+
+func (db *SQL) RemoveSkinByUsername(username string) error {
+	_, err := db.conn.Exec(db.rebind("DELETE FROM skins WHERE LOWER(username) = LOWER(?)"), username)
+
+	return err
+}
+
+func (db *SQL) GetUuid(username string) (string, bool, error) {
+	key := strings.ToLower(username)
+	row := db.conn.QueryRow(db.rebind("SELECT uuid, stored_at FROM mojang_uuids WHERE username = ?"), key)
+
+	var uuid string
+	var timestamp int64
+	err := row.Scan(&uuid, &timestamp)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+
+	if err != nil {
+		return "", false, err
+	}
+
+	ttl := db.UuidTTL
+	if uuid == "" {
+		ttl = db.NilUuidTTL
+	}
+
+	storedAt := time.Unix(timestamp, 0)
+	if storedAt.Add(ttl).Before(now()) {
+		_, err := db.conn.Exec(db.rebind("DELETE FROM mojang_uuids WHERE username = ?"), key)
+
+		return "", false, err
+	}
+
+	return uuid, true, nil
+}
+
+func (db *SQL) StoreUuid(username string, uuid string) error {
+	_, err := db.conn.Exec(db.rebind(`INSERT INTO mojang_uuids (username, uuid, stored_at) VALUES (?, ?, ?)
+		ON CONFLICT (username) DO UPDATE SET uuid = excluded.uuid, stored_at = excluded.stored_at`),
+		strings.ToLower(username),
+		uuid,
+		now().Unix(),
+	)
+
+	return err
+}
+
+func (db *SQL) IsTokenRevoked(jti string) (bool, error) {
+	row := db.conn.QueryRow(db.rebind("SELECT expires_at FROM revoked_tokens WHERE jti = ?"), jti)
+
+	var expiresAt int64
+	err := row.Scan(&expiresAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	// The expired token will be rejected anyway, so its record is no longer needed
+	if expiresAt != 0 && time.Unix(expiresAt, 0).Before(now()) {
+		_, err := db.conn.Exec(db.rebind("DELETE FROM revoked_tokens WHERE jti = ?"), jti)
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (db *SQL) RevokeToken(jti string, expiresAt time.Time) error {
+	var timestamp int64
+	if !expiresAt.IsZero() {
+		timestamp = expiresAt.Unix()
+	}
+
+	_, err := db.conn.Exec(db.rebind(`INSERT INTO revoked_tokens (jti, expires_at) VALUES (?, ?)
+		ON CONFLICT (jti) DO UPDATE SET expires_at = excluded.expires_at`),
+		jti,
+		timestamp,
+	)
+
+	return err
+}
+
+func (db *SQL) Ping() error {
+	return db.conn.Ping()
+}
+
+func (db *SQL) Close() error {
+	return db.conn.Close()
+}
+
+func (db *SQL) migrate() error {
+	_, err := db.conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY)")
+	if err != nil {
+		return err
+	}
+
+	var version int
+	err = db.conn.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for ; version < len(migrations); version++ {
+		tx, err := db.conn.Begin()
+		if err != nil {
+			return err
+		}
+
+		for _, statement := range migrations[version] {
+			_, err = tx.Exec(statement)
+			if err != nil {
+				_ = tx.Rollback()
+				return fmt.Errorf("migration %d: %w", version+1, err)
+			}
+		}
+
+		_, err = tx.Exec(db.rebind("INSERT INTO schema_migrations (version) VALUES (?)"), version+1)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Queries are written with the "?" placeholders, but PostgreSQL expects numbered "$N" ones
+func (db *SQL) rebind(query string) string {
+	if db.driverName != PostgresDriver {
+		return query
+	}
+
+	var result strings.Builder
+	n := 0
+	for _, char := range query {
+		if char == '?' {
+			n++
+			result.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+
+		result.WriteRune(char)
+	}
+
+	return result.String()
+}
@@ -0,0 +1,438 @@
+package sql
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/elyby/chrly/model"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should apply migrations", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "sql")
+		if err != nil {
+			panic(fmt.Errorf("cannot crete temp directory for tests: %w", err))
+		}
+		defer os.RemoveAll(dir)
+
+		db, err := New(SQLiteDriver, path.Join(dir, "chrly.db"))
+		assert.Nil(t, err)
+		assert.NotNil(t, db)
+
+		var version int
+		_ = db.conn.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+		assert.Equal(t, len(migrations), version)
+		_ = db.Close()
+
+		// Reopening of the already migrated database must not fail
+		db, err = New(SQLiteDriver, path.Join(dir, "chrly.db"))
+		assert.Nil(t, err)
+		assert.NotNil(t, db)
+		_ = db.Close()
+	})
+
+	t.Run("should return error for unsupported driver", func(t *testing.T) {
+		db, err := New("mysql", "")
+		assert.EqualError(t, err, "unsupported sql driver \"mysql\"")
+		assert.Nil(t, db)
+	})
+}
+
+func TestSQL_rebind(t *testing.T) {
+	db := &SQL{driverName: PostgresDriver}
+	assert.Equal(t, "SELECT * FROM skins WHERE a = $1 AND b = $2", db.rebind("SELECT * FROM skins WHERE a = ? AND b = ?"))
+
+	db = &SQL{driverName: SQLiteDriver}
+	assert.Equal(t, "SELECT * FROM skins WHERE a = ? AND b = ?", db.rebind("SELECT * FROM skins WHERE a = ? AND b = ?"))
+}
+
+type sqlTestSuite struct {
+	suite.Suite
+
+	SQL *SQL
+
+	dir string
+}
+
+func (suite *sqlTestSuite) SetupSuite() {
+	dir, err := ioutil.TempDir("", "sql")
+	if err != nil {
+		panic(fmt.Errorf("cannot crete temp directory for tests: %w", err))
+	}
+
+	db, err := New(SQLiteDriver, path.Join(dir, "chrly.db"))
+	if err != nil {
+		panic(fmt.Errorf("cannot open sqlite database: %w", err))
+	}
+
+	suite.SQL = db
+	suite.dir = dir
+}
+
+func (suite *sqlTestSuite) TearDownSuite() {
+	_ = suite.SQL.Close()
+	_ = os.RemoveAll(suite.dir)
+}
+
+func (suite *sqlTestSuite) SetupTest() {
+	// Cleanup database before the each test
+	suite.exec("DELETE FROM skins")
+	suite.exec("DELETE FROM mojang_uuids")
+	suite.exec("DELETE FROM revoked_tokens")
+}
+
+func (suite *sqlTestSuite) TearDownTest() {
+	// Restore time.Now func
+	now = time.Now
+}
+
+func (suite *sqlTestSuite) RunSubTest(name string, subTest func()) {
+	suite.SetupTest()
+	suite.Run(name, subTest)
+}
+
+func (suite *sqlTestSuite) exec(query string, args ...interface{}) {
+	_, err := suite.SQL.conn.Exec(query, args...)
+	if err != nil {
+		panic(fmt.Errorf("cannot execute query: %w", err))
+	}
+}
+
+func (suite *sqlTestSuite) insertSkin(userId int, username string) {
+	suite.exec(
+		"INSERT INTO skins ("+skinColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		userId,
+		"fd5da1e4d66d4d17aadee2446093896d",
+		username,
+		1,
+		"http://localhost/skin.png",
+		true,
+		false,
+		"mock-mojang-textures",
+		"mock-mojang-signature",
+		"",
+	)
+}
+
+func (suite *sqlTestSuite) countSkins() int {
+	var count int
+	_ = suite.SQL.conn.QueryRow("SELECT COUNT(*) FROM skins").Scan(&count)
+
+	return count
+}
+
+func TestSQL(t *testing.T) {
+	suite.Run(t, new(sqlTestSuite))
+}
+
+func (suite *sqlTestSuite) TestFindSkinByUsername() {
+	suite.RunSubTest("exists record", func() {
+		suite.insertSkin(1, "Mock")
+
+		skin, err := suite.SQL.FindSkinByUsername("mock")
+		suite.Require().Nil(err)
+		suite.Require().NotNil(skin)
+		suite.Require().Equal(1, skin.UserId)
+		suite.Require().Equal("fd5da1e4d66d4d17aadee2446093896d", skin.Uuid)
+		suite.Require().Equal("Mock", skin.Username)
+		suite.Require().Equal(1, skin.SkinId)
+		suite.Require().Equal("http://localhost/skin.png", skin.Url)
+		suite.Require().True(skin.Is1_8)
+		suite.Require().False(skin.IsSlim)
+		suite.Require().Equal("mock-mojang-textures", skin.MojangTextures)
+		suite.Require().Equal("mock-mojang-signature", skin.MojangSignature)
+		suite.Require().Empty(skin.SkinHash)
+		suite.Require().Equal(skin.Username, skin.OldUsername)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		skin, err := suite.SQL.FindSkinByUsername("Mock")
+		suite.Require().Nil(err)
+		suite.Require().Nil(skin)
+	})
+}
+
+func (suite *sqlTestSuite) TestFindSkinByUserId() {
+	suite.RunSubTest("exists record", func() {
+		suite.insertSkin(1, "Mock")
+
+		skin, err := suite.SQL.FindSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().NotNil(skin)
+		suite.Require().Equal(1, skin.UserId)
+		suite.Require().Equal("Mock", skin.Username)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		skin, err := suite.SQL.FindSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().Nil(skin)
+	})
+}
+
+func (suite *sqlTestSuite) TestSaveSkin() {
+	suite.RunSubTest("save new entity", func() {
+		skin := &model.Skin{
+			UserId:          1,
+			Uuid:            "fd5da1e4d66d4d17aadee2446093896d",
+			Username:        "Mock",
+			SkinId:          1,
+			Url:             "http://localhost/skin.png",
+			Is1_8:           true,
+			IsSlim:          false,
+			MojangTextures:  "mock-mojang-textures",
+			MojangSignature: "mock-mojang-signature",
+			SkinHash:        "mock-hash",
+		}
+		err := suite.SQL.SaveSkin(skin)
+		suite.Require().Nil(err)
+		suite.Require().Equal("Mock", skin.OldUsername)
+
+		stored, _ := suite.SQL.FindSkinByUserId(1)
+		suite.Require().NotNil(stored)
+		suite.Require().Equal(skin, stored)
+	})
+
+	suite.RunSubTest("save exists record with changed username", func() {
+		suite.insertSkin(1, "Mock")
+
+		skin, _ := suite.SQL.FindSkinByUserId(1)
+		skin.Username = "NewMock"
+		err := suite.SQL.SaveSkin(skin)
+		suite.Require().Nil(err)
+
+		oldSkin, _ := suite.SQL.FindSkinByUsername("Mock")
+		suite.Require().Nil(oldSkin)
+
+		newSkin, _ := suite.SQL.FindSkinByUsername("NewMock")
+		suite.Require().NotNil(newSkin)
+		suite.Require().Equal(1, newSkin.UserId)
+		suite.Require().Equal(1, suite.countSkins())
+	})
+
+	suite.RunSubTest("save record with username occupied by other user", func() {
+		suite.insertSkin(1, "Mock")
+
+		err := suite.SQL.SaveSkin(&model.Skin{
+			UserId:   2,
+			Username: "mock",
+		})
+		suite.Require().Nil(err)
+
+		skin, _ := suite.SQL.FindSkinByUsername("Mock")
+		suite.Require().NotNil(skin)
+		suite.Require().Equal(2, skin.UserId)
+		suite.Require().Equal(1, suite.countSkins())
+	})
+}
+
+func (suite *sqlTestSuite) TestRemoveSkinByUserId() {
+	suite.RunSubTest("exists record", func() {
+		suite.insertSkin(1, "Mock")
+
+		err := suite.SQL.RemoveSkinByUserId(1)
+		suite.Require().Nil(err)
+		suite.Require().Equal(0, suite.countSkins())
+	})
+
+	suite.RunSubTest("no records", func() {
+		err := suite.SQL.RemoveSkinByUserId(1)
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *sqlTestSuite) TestRemoveSkinByUsername() {
+	suite.RunSubTest("exists record", func() {
+		suite.insertSkin(1, "Mock")
+
+		err := suite.SQL.RemoveSkinByUsername("mock")
+		suite.Require().Nil(err)
+		suite.Require().Equal(0, suite.countSkins())
+	})
+
+	suite.RunSubTest("no records", func() {
+		err := suite.SQL.RemoveSkinByUsername("Mock")
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *sqlTestSuite) TestGetUuid() {
+	suite.RunSubTest("exists record", func() {
+		suite.exec(
+			"INSERT INTO mojang_uuids (username, uuid, stored_at) VALUES (?, ?, ?)",
+			"mock",
+			"d3ca513eb3e14946b58047f2bd3530fd",
+			time.Now().Unix(),
+		)
+
+		uuid, found, err := suite.SQL.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", uuid)
+	})
+
+	suite.RunSubTest("exists record with empty uuid value", func() {
+		suite.exec(
+			"INSERT INTO mojang_uuids (username, uuid, stored_at) VALUES (?, ?, ?)",
+			"mock",
+			"",
+			time.Now().Unix(),
+		)
+
+		uuid, found, err := suite.SQL.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().True(found)
+		suite.Require().Empty(uuid)
+	})
+
+	suite.RunSubTest("not exists record", func() {
+		uuid, found, err := suite.SQL.GetUuid("Mock")
+		suite.Require().Nil(err)
+		suite.Require().False(found)
+		suite.Require().Empty(uuid)
+	})
+
+	suite.RunSubTest("exists, but expired record", func() {
+		suite.exec(
+			"INSERT INTO mojang_uuids (username, uuid, stored_at) VALUES (?, ?, ?)",
+			"mock",
+			"d3ca513eb3e14946b58047f2bd3530fd",
+			time.Now().Add(-1*time.Hour*24*31).Unix(),
+		)
+
+		uuid, found, err := suite.SQL.GetUuid("Mock")
+		suite.Require().Empty(uuid)
+		suite.Require().False(found)
+		suite.Require().Nil(err)
+
+		var count int
+		_ = suite.SQL.conn.QueryRow("SELECT COUNT(*) FROM mojang_uuids").Scan(&count)
+		suite.Require().Equal(0, count, "should cleanup expired records")
+	})
+
+	suite.RunSubTest("exists record with empty uuid value, expired by the nil ttl", func() {
+		suite.exec(
+			"INSERT INTO mojang_uuids (username, uuid, stored_at) VALUES (?, ?, ?)",
+			"mock",
+			"",
+			time.Now().Add(-2*time.Hour).Unix(),
+		)
+
+		defer func(ttl time.Duration) {
+			suite.SQL.NilUuidTTL = ttl
+		}(suite.SQL.NilUuidTTL)
+		suite.SQL.NilUuidTTL = time.Hour
+
+		uuid, found, err := suite.SQL.GetUuid("Mock")
+		suite.Require().Empty(uuid)
+		suite.Require().False(found)
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *sqlTestSuite) TestStoreUuid() {
+	now = func() time.Time {
+		return time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC)
+	}
+
+	suite.RunSubTest("new record", func() {
+		err := suite.SQL.StoreUuid("Mock", "d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(err)
+
+		var uuid string
+		var storedAt int64
+		_ = suite.SQL.conn.QueryRow("SELECT uuid, stored_at FROM mojang_uuids WHERE username = 'mock'").Scan(&uuid, &storedAt)
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", uuid)
+		suite.Require().Equal(int64(1587435016), storedAt)
+	})
+
+	suite.RunSubTest("replace exists record", func() {
+		suite.exec("INSERT INTO mojang_uuids (username, uuid, stored_at) VALUES ('mock', '', 0)")
+
+		err := suite.SQL.StoreUuid("Mock", "d3ca513eb3e14946b58047f2bd3530fd")
+		suite.Require().Nil(err)
+
+		var uuid string
+		_ = suite.SQL.conn.QueryRow("SELECT uuid FROM mojang_uuids WHERE username = 'mock'").Scan(&uuid)
+		suite.Require().Equal("d3ca513eb3e14946b58047f2bd3530fd", uuid)
+	})
+}
+
+func (suite *sqlTestSuite) TestIsTokenRevoked() {
+	now = func() time.Time {
+		return time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC)
+	}
+
+	suite.RunSubTest("revoked token", func() {
+		suite.exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ('a2b4c6', ?)", now().Add(time.Hour).Unix())
+
+		isRevoked, err := suite.SQL.IsTokenRevoked("a2b4c6")
+		suite.Require().Nil(err)
+		suite.Require().True(isRevoked)
+	})
+
+	suite.RunSubTest("revoked token without expiration", func() {
+		suite.exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ('a2b4c6', 0)")
+
+		isRevoked, err := suite.SQL.IsTokenRevoked("a2b4c6")
+		suite.Require().Nil(err)
+		suite.Require().True(isRevoked)
+	})
+
+	suite.RunSubTest("expired record", func() {
+		suite.exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ('a2b4c6', ?)", now().Add(-time.Hour).Unix())
+
+		isRevoked, err := suite.SQL.IsTokenRevoked("a2b4c6")
+		suite.Require().Nil(err)
+		suite.Require().False(isRevoked)
+
+		var count int
+		_ = suite.SQL.conn.QueryRow("SELECT COUNT(*) FROM revoked_tokens").Scan(&count)
+		suite.Require().Equal(0, count)
+	})
+
+	suite.RunSubTest("not revoked token", func() {
+		isRevoked, err := suite.SQL.IsTokenRevoked("a2b4c6")
+		suite.Require().Nil(err)
+		suite.Require().False(isRevoked)
+	})
+}
+
+func (suite *sqlTestSuite) TestRevokeToken() {
+	suite.RunSubTest("token with expiration", func() {
+		err := suite.SQL.RevokeToken("a2b4c6", time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC))
+		suite.Require().Nil(err)
+
+		var expiresAt int64
+		_ = suite.SQL.conn.QueryRow("SELECT expires_at FROM revoked_tokens WHERE jti = 'a2b4c6'").Scan(&expiresAt)
+		suite.Require().Equal(int64(1587435016), expiresAt)
+	})
+
+	suite.RunSubTest("token without expiration", func() {
+		err := suite.SQL.RevokeToken("a2b4c6", time.Time{})
+		suite.Require().Nil(err)
+
+		var expiresAt int64
+		_ = suite.SQL.conn.QueryRow("SELECT expires_at FROM revoked_tokens WHERE jti = 'a2b4c6'").Scan(&expiresAt)
+		suite.Require().Equal(int64(0), expiresAt)
+	})
+
+	suite.RunSubTest("revoke already revoked token", func() {
+		suite.exec("INSERT INTO revoked_tokens (jti, expires_at) VALUES ('a2b4c6', 0)")
+
+		err := suite.SQL.RevokeToken("a2b4c6", time.Date(2020, 04, 21, 02, 10, 16, 0, time.UTC))
+		suite.Require().Nil(err)
+	})
+}
+
+func (suite *sqlTestSuite) TestPing() {
+	err := suite.SQL.Ping()
+	suite.Require().Nil(err)
+}
@@ -3,35 +3,201 @@ package di
 import (
 	"context"
 	"fmt"
+	"os"
 	"path"
 	"time"
 
 	"github.com/goava/di"
 	"github.com/spf13/viper"
 
+	"github.com/elyby/chrly/db/bolt"
 	"github.com/elyby/chrly/db/fs"
 	"github.com/elyby/chrly/db/redis"
+	"github.com/elyby/chrly/db/sql"
 	es "github.com/elyby/chrly/eventsubscribers"
 	"github.com/elyby/chrly/http"
 	"github.com/elyby/chrly/mojangtextures"
 )
 
-// v4 had the idea that it would be possible to separate backends for storing skins and capes.
-// But in v5 the storage will be unified, so this is just temporary constructors before large reworking.
+// Skins, capes and their metadata are stored in the unified textures repository,
+// which backend is selected by the "storage.driver" param.
 //
-// Since there are no options for selecting target backends,
-// all constants in this case point to static specific implementations.
+// Mojang's UUIDs and textures caches have their own "mojang_textures.uuids_storage.driver"
+// and "mojang_textures.textures_storage.driver" params.
 var db = di.Options(
-	di.Provide(newRedis,
-		di.As(new(http.SkinsRepository)),
-		di.As(new(mojangtextures.UUIDsStorage)),
-	),
-	di.Provide(newFSFactory,
-		di.As(new(http.CapesRepository)),
-	),
+	di.Provide(newTexturesRepositoryFactory),
+	di.Provide(newRevokedTokensRepositoryFactory),
+	di.Provide(newMojangUUIDsStorageFactory),
+	di.Provide(newRedis),
+	di.Provide(newSQL),
+	di.Provide(newBolt),
+	di.Provide(newFSFactory),
 	di.Provide(newMojangSignedTexturesStorage),
 )
 
+func newTexturesRepositoryFactory(
+	container *di.Container,
+	config *viper.Viper,
+) (http.TexturesRepository, error) {
+	config.SetDefault("storage.driver", "split")
+
+	driver := config.GetString("storage.driver")
+	switch driver {
+	case "redis":
+		var redisStorage *redis.Redis
+		err := container.Resolve(&redisStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return redisStorage, nil
+	case "filesystem":
+		var fsStorage *fs.Filesystem
+		err := container.Resolve(&fsStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return fsStorage, nil
+	case "split":
+		// The v4 layout: skins metadata are stored in Redis, while skins and capes files are stored in the filesystem
+		var redisStorage *redis.Redis
+		err := container.Resolve(&redisStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		var fsStorage *fs.Filesystem
+		err = container.Resolve(&fsStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return &http.SeparatedTexturesRepository{
+			SkinsRepository:      redisStorage,
+			SkinsFilesRepository: fsStorage,
+			CapesRepository:      fsStorage,
+		}, nil
+	case "sql":
+		// SQL database stores only skins metadata, so the files are still stored in the filesystem
+		var sqlStorage *sql.SQL
+		err := container.Resolve(&sqlStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		var fsStorage *fs.Filesystem
+		err = container.Resolve(&fsStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return &http.SeparatedTexturesRepository{
+			SkinsRepository:      sqlStorage,
+			SkinsFilesRepository: fsStorage,
+			CapesRepository:      fsStorage,
+		}, nil
+	case "bolt":
+		// Embedded database stores only skins metadata, so the files are still stored in the filesystem
+		var boltStorage *bolt.Bolt
+		err := container.Resolve(&boltStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		var fsStorage *fs.Filesystem
+		err = container.Resolve(&fsStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return &http.SeparatedTexturesRepository{
+			SkinsRepository:      boltStorage,
+			SkinsFilesRepository: fsStorage,
+			CapesRepository:      fsStorage,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver \"%s\"", driver)
+	}
+}
+
+// Revoked tokens are stored in the same backend as the skins metadata. The only exception is
+// the bolt driver: its file is locked by the running server, so the token command couldn't write
+// into it. The filesystem is used instead
+func newRevokedTokensRepositoryFactory(
+	container *di.Container,
+	config *viper.Viper,
+) (http.RevokedTokensRepository, error) {
+	config.SetDefault("storage.driver", "split")
+
+	driver := config.GetString("storage.driver")
+	switch driver {
+	case "redis", "split":
+		var redisStorage *redis.Redis
+		err := container.Resolve(&redisStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return redisStorage, nil
+	case "filesystem", "bolt":
+		var fsStorage *fs.Filesystem
+		err := container.Resolve(&fsStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return fsStorage, nil
+	case "sql":
+		var sqlStorage *sql.SQL
+		err := container.Resolve(&sqlStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return sqlStorage, nil
+	default:
+		return nil, fmt.Errorf("unknown storage driver \"%s\"", driver)
+	}
+}
+
+func newMojangUUIDsStorageFactory(
+	container *di.Container,
+	config *viper.Viper,
+) (mojangtextures.UUIDsStorage, error) {
+	config.SetDefault("mojang_textures.uuids_storage.driver", "redis")
+
+	driver := config.GetString("mojang_textures.uuids_storage.driver")
+	switch driver {
+	case "redis":
+		var redisStorage *redis.Redis
+		err := container.Resolve(&redisStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return redisStorage, nil
+	case "sql":
+		var sqlStorage *sql.SQL
+		err := container.Resolve(&sqlStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return sqlStorage, nil
+	case "bolt":
+		var boltStorage *bolt.Bolt
+		err := container.Resolve(&boltStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return boltStorage, nil
+	default:
+		return nil, fmt.Errorf("unknown uuids storage driver \"%s\"", driver)
+	}
+}
+
 func newRedis(container *di.Container, config *viper.Viper) (*redis.Redis, error) {
 	config.SetDefault("storage.redis.host", "localhost")
 	config.SetDefault("storage.redis.port", 6379)
@@ -45,6 +211,16 @@ func newRedis(container *di.Container, config *viper.Viper) (*redis.Redis, error
 		return nil, err
 	}
 
+	conn.UuidTTL, conn.NilUuidTTL, err = getMojangUuidsTTLs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.TexturesTTL, conn.NilTexturesTTL, err = getMojangTexturesTTLs(config)
+	if err != nil {
+		return nil, err
+	}
+
 	if err := container.Provide(func() es.ReporterFunc {
 		return es.AvailableRedisPoolSizeReporter(conn, time.Second, context.Background())
 	}, di.As(new(es.Reporter))); err != nil {
@@ -63,16 +239,160 @@ func newRedis(container *di.Container, config *viper.Viper) (*redis.Redis, error
 	return conn, nil
 }
 
+func newSQL(container *di.Container, config *viper.Viper) (*sql.SQL, error) {
+	config.SetDefault("storage.filesystem.basePath", "data")
+	config.SetDefault("storage.sql.driver", sql.SQLiteDriver)
+	config.SetDefault("storage.sql.dsn", path.Join(config.GetString("storage.filesystem.basePath"), "chrly.db"))
+
+	conn, err := sql.New(config.GetString("storage.sql.driver"), config.GetString("storage.sql.dsn"))
+	if err != nil {
+		return nil, err
+	}
+
+	conn.UuidTTL, conn.NilUuidTTL, err = getMojangUuidsTTLs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := container.Provide(func() *namedHealthChecker {
+		return &namedHealthChecker{
+			Name:    "sql",
+			Checker: es.DatabaseChecker(conn),
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func newBolt(config *viper.Viper) (*bolt.Bolt, error) {
+	config.SetDefault("storage.filesystem.basePath", "data")
+	config.SetDefault("storage.bolt.fileName", "chrly.bolt")
+
+	basePath := config.GetString("storage.filesystem.basePath")
+	err := os.MkdirAll(basePath, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := bolt.New(path.Join(basePath, config.GetString("storage.bolt.fileName")))
+	if err != nil {
+		return nil, err
+	}
+
+	conn.UuidTTL, conn.NilUuidTTL, err = getMojangUuidsTTLs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.TexturesTTL, conn.NilTexturesTTL, err = getMojangTexturesTTLs(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
 func newFSFactory(config *viper.Viper) (*fs.Filesystem, error) {
 	config.SetDefault("storage.filesystem.basePath", "data")
-	config.SetDefault("storage.filesystem.capesDirName", "capes")
 
-	return fs.New(path.Join(
-		config.GetString("storage.filesystem.basePath"),
-		config.GetString("storage.filesystem.capesDirName"),
-	))
+	fsStorage, err := fs.New(config.GetString("storage.filesystem.basePath"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Deprecated: the param is still read to keep serving the capes from the custom directory after the upgrade
+	fsStorage.CapesDirName = config.GetString("storage.filesystem.capesDirName")
+
+	return fsStorage, nil
 }
 
-func newMojangSignedTexturesStorage() mojangtextures.TexturesStorage {
-	return mojangtextures.NewInMemoryTexturesStorage()
+func newMojangSignedTexturesStorage(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+) (mojangtextures.TexturesStorage, error) {
+	config.SetDefault("mojang_textures.textures_storage.driver", "in-memory")
+	config.SetDefault("mojang_textures.textures_storage.max_entries", 0)
+	config.SetDefault("mojang_textures.textures_storage.stale_ttl", 0)
+
+	driver := config.GetString("mojang_textures.textures_storage.driver")
+	switch driver {
+	case "in-memory":
+		ttl, nilTTL, err := getMojangTexturesTTLs(config)
+		if err != nil {
+			return nil, err
+		}
+
+		storage := mojangtextures.NewInMemoryTexturesStorage(emitter)
+		storage.Duration = ttl
+		storage.NilDuration = nilTTL
+		storage.MaxEntries = config.GetInt("mojang_textures.textures_storage.max_entries")
+		storage.StaleDuration = config.GetDuration("mojang_textures.textures_storage.stale_ttl")
+
+		return storage, nil
+	case "redis":
+		var redisStorage *redis.Redis
+		err := container.Resolve(&redisStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return redisStorage, nil
+	case "bolt":
+		var boltStorage *bolt.Bolt
+		err := container.Resolve(&boltStorage)
+		if err != nil {
+			return nil, err
+		}
+
+		return boltStorage, nil
+	default:
+		return nil, fmt.Errorf("unknown textures storage driver \"%s\"", driver)
+	}
+}
+
+func getMojangUuidsTTLs(config *viper.Viper) (time.Duration, time.Duration, error) {
+	config.SetDefault("mojang_textures.uuids_storage.ttl", time.Hour*24*30)
+	config.SetDefault("mojang_textures.uuids_storage.nil_ttl", time.Hour*24)
+
+	ttl, err := getTTL(config, "mojang_textures.uuids_storage.ttl")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	nilTTL, err := getTTL(config, "mojang_textures.uuids_storage.nil_ttl")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return ttl, nilTTL, nil
+}
+
+func getMojangTexturesTTLs(config *viper.Viper) (time.Duration, time.Duration, error) {
+	config.SetDefault("mojang_textures.textures_storage.ttl", time.Minute+10*time.Second)
+	config.SetDefault("mojang_textures.textures_storage.nil_ttl", time.Minute+10*time.Second)
+
+	ttl, err := getTTL(config, "mojang_textures.textures_storage.ttl")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	nilTTL, err := getTTL(config, "mojang_textures.textures_storage.nil_ttl")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return ttl, nilTTL, nil
+}
+
+// Redis doesn't accept the expiration shorter than a millisecond, so such values are rejected for all drivers
+func getTTL(config *viper.Viper, key string) (time.Duration, error) {
+	ttl := config.GetDuration(key)
+	if ttl < time.Millisecond {
+		return 0, fmt.Errorf("%s must be at least 1ms", key)
+	}
+
+	return ttl, nil
 }
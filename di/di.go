@@ -7,9 +7,11 @@ func New() (*di.Container, error) {
 		config,
 		dispatcher,
 		logger,
+		tracing,
 		db,
 		mojangTextures,
 		handlers,
+		webhooks,
 		server,
 	)
 	if err != nil {
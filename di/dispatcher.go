@@ -1,13 +1,20 @@
 package di
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/goava/di"
 	"github.com/mono83/slf"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
 
+	"github.com/elyby/chrly/db/redis"
 	d "github.com/elyby/chrly/dispatcher"
 	"github.com/elyby/chrly/eventsubscribers"
 	"github.com/elyby/chrly/http"
 	"github.com/elyby/chrly/mojangtextures"
+	w "github.com/elyby/chrly/webhooks"
 )
 
 var dispatcher = di.Options(
@@ -16,21 +23,70 @@ var dispatcher = di.Options(
 		di.As(new(d.Subscriber)),
 		di.As(new(http.Emitter)),
 		di.As(new(mojangtextures.Emitter)),
+		di.As(new(w.Emitter)),
 		di.As(new(eventsubscribers.Subscriber)),
 	),
 	di.Invoke(enableEventsHandlers),
 )
 
-func newDispatcher() d.Dispatcher {
-	return d.New()
+func newDispatcher(container *di.Container, config *viper.Viper) (d.Dispatcher, error) {
+	config.SetDefault("dispatcher.driver", "local")
+	config.SetDefault("dispatcher.redis.channel", "chrly:events")
+	config.SetDefault("dispatcher.redis.topics", []string{
+		"skinsystem:skin:saved",
+		"skinsystem:skin:removed",
+		"skinsystem:cape:saved",
+		"skinsystem:cape:removed",
+	})
+
+	switch driver := config.GetString("dispatcher.driver"); driver {
+	case "local":
+		return d.New(), nil
+	case "redis":
+		var redisClient *redis.Redis
+		err := container.Resolve(&redisClient)
+		if err != nil {
+			return nil, err
+		}
+
+		dispatcher := d.NewDistributed(
+			redisClient,
+			config.GetString("dispatcher.redis.channel"),
+			config.GetStringSlice("dispatcher.redis.topics"),
+		)
+		go dispatcher.Listen(context.Background())
+
+		return dispatcher, nil
+	default:
+		return nil, fmt.Errorf("unknown dispatcher driver \"%s\"", driver)
+	}
 }
 
-func enableEventsHandlers(
-	dispatcher d.Subscriber,
-	logger slf.Logger,
-	statsReporter slf.StatsReporter,
-) {
+type eventsHandlersParams struct {
+	di.Inject
+
+	Dispatcher         d.Subscriber         `di:""`
+	Logger             slf.Logger           `di:""`
+	StatsReporter      slf.StatsReporter    `di:""`
+	PrometheusRegistry *prometheus.Registry `di:"" optional:"true"`
+	Webhooks           *w.Webhooks          `di:"" optional:"true"`
+}
+
+func enableEventsHandlers(params eventsHandlersParams) {
 	// TODO: use idea from https://github.com/goava/di/issues/10#issuecomment-615869852
-	(&eventsubscribers.Logger{Logger: logger}).ConfigureWithDispatcher(dispatcher)
-	(&eventsubscribers.StatsReporter{StatsReporter: statsReporter}).ConfigureWithDispatcher(dispatcher)
+	(&eventsubscribers.Logger{Logger: params.Logger}).ConfigureWithDispatcher(params.Dispatcher)
+	(&eventsubscribers.StatsReporter{StatsReporter: params.StatsReporter}).ConfigureWithDispatcher(params.Dispatcher)
+	if params.PrometheusRegistry != nil {
+		(&eventsubscribers.PrometheusReporter{Registerer: params.PrometheusRegistry}).ConfigureWithDispatcher(params.Dispatcher)
+	}
+
+	if params.Webhooks != nil {
+		// Each instance sends webhooks only for its own events, so the targets won't receive duplicates
+		var subscriber d.Subscriber = params.Dispatcher
+		if distributed, ok := subscriber.(*d.Distributed); ok {
+			subscriber = distributed.Local()
+		}
+
+		params.Webhooks.ConfigureWithDispatcher(subscriber)
+	}
 }
@@ -7,7 +7,10 @@ import (
 	"github.com/etherlabsio/healthcheck"
 	"github.com/goava/di"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/viper"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 
 	. "github.com/elyby/chrly/http"
 	"github.com/elyby/chrly/mojangtextures"
@@ -41,11 +44,24 @@ func newHandlerFactory(
 	}
 
 	router.StrictSlash(true)
+	requestInfoMiddleware := CreateRequestInfoMiddleware()
+	router.Use(requestInfoMiddleware)
+	router.Use(CreateRouteTemplateMiddleware(""))
+
+	var tracerProvider *sdktrace.TracerProvider
+	if err := container.Resolve(&tracerProvider); err != nil {
+		return nil, err
+	}
+
+	if tracerProvider != nil {
+		router.Use(CreateTracingMiddleware())
+	}
+
 	requestEventsMiddleware := CreateRequestEventsMiddleware(emitter, "skinsystem")
 	router.Use(requestEventsMiddleware)
 	// NotFoundHandler doesn't call for registered middlewares, so we must wrap it manually.
 	// See https://github.com/gorilla/mux/issues/416#issuecomment-600079279
-	router.NotFoundHandler = requestEventsMiddleware(http.HandlerFunc(NotFoundHandler))
+	router.NotFoundHandler = requestInfoMiddleware(requestEventsMiddleware(http.HandlerFunc(NotFoundHandler)))
 
 	// Enable the worker module before api to allow gorilla.mux to correctly find the target router
 	// as it uses the first matching and /api overrides the more accurate /api/worker
@@ -55,7 +71,15 @@ func newHandlerFactory(
 			return nil, err
 		}
 
+		var authenticator Authenticator
+		if err := container.Resolve(&authenticator); err != nil {
+			return nil, err
+		}
+
+		// Mount the router before the authentication middleware is registered,
+		// so the rejected requests will also be reported with their route
 		mount(router, "/api/worker", workerRouter)
+		workerRouter.Use(CreateAuthenticationMiddleware(authenticator, WorkerScope))
 	}
 
 	if hasValue(enabledModules, "api") {
@@ -64,13 +88,6 @@ func newHandlerFactory(
 			return nil, err
 		}
 
-		var authenticator Authenticator
-		if err := container.Resolve(&authenticator); err != nil {
-			return nil, err
-		}
-
-		apiRouter.Use(CreateAuthenticationMiddleware(authenticator))
-
 		mount(router, "/api", apiRouter)
 	}
 
@@ -79,6 +96,15 @@ func newHandlerFactory(
 		return nil, err
 	}
 
+	var prometheusRegistry *prometheus.Registry
+	if err := container.Resolve(&prometheusRegistry); err != nil {
+		return nil, err
+	}
+
+	if prometheusRegistry != nil {
+		router.Handle("/metrics", promhttp.HandlerFor(prometheusRegistry, promhttp.HandlerOpts{})).Methods("GET")
+	}
+
 	// Resolve health checkers last, because all the services required by the application
 	// must first be initialized and each of them can publish its own checkers
 	var healthCheckers []*namedHealthChecker
@@ -101,33 +127,48 @@ func newHandlerFactory(
 func newSkinsystemHandler(
 	config *viper.Viper,
 	emitter Emitter,
-	skinsRepository SkinsRepository,
-	capesRepository CapesRepository,
+	texturesRepository TexturesRepository,
 	mojangTexturesProvider MojangTexturesProvider,
 ) *mux.Router {
 	config.SetDefault("textures.extra_param_name", "chrly")
 	config.SetDefault("textures.extra_param_value", "how do you tame a horse in Minecraft?")
+	config.SetDefault("textures.stream_local_skins", false)
 
 	return (&Skinsystem{
 		Emitter:                 emitter,
-		SkinsRepo:               skinsRepository,
-		CapesRepo:               capesRepository,
+		SkinsRepo:               texturesRepository,
+		SkinsFilesRepo:          texturesRepository,
+		CapesRepo:               texturesRepository,
 		MojangTexturesProvider:  mojangTexturesProvider,
 		TexturesExtraParamName:  config.GetString("textures.extra_param_name"),
 		TexturesExtraParamValue: config.GetString("textures.extra_param_value"),
+		StreamLocalSkins:        config.GetBool("textures.stream_local_skins"),
 	}).Handler()
 }
 
-func newApiHandler(emitter Emitter, skinsRepository SkinsRepository) *mux.Router {
+func newApiHandler(
+	config *viper.Viper,
+	emitter Emitter,
+	authenticator Authenticator,
+	texturesRepository TexturesRepository,
+) *mux.Router {
 	return (&Api{
-		Emitter:   emitter,
-		SkinsRepo: skinsRepository,
+		Emitter:        emitter,
+		Authenticator:  authenticator,
+		SkinsRepo:      texturesRepository,
+		SkinsFilesRepo: texturesRepository,
+		CapesRepo:      texturesRepository,
+		PublicUrl:      config.GetString("textures.public_url"),
 	}).Handler()
 }
 
-func newUUIDsWorkerHandler(mojangUUIDsProvider *mojangtextures.BatchUuidsProvider) *mux.Router {
+func newUUIDsWorkerHandler(
+	mojangUUIDsProvider *mojangtextures.BatchUuidsProvider,
+	mojangTexturesProvider mojangtextures.TexturesProvider,
+) *mux.Router {
 	return (&UUIDsWorker{
-		MojangUuidsProvider: mojangUUIDsProvider,
+		MojangUuidsProvider:          mojangUUIDsProvider,
+		MojangSignedTexturesProvider: mojangTexturesProvider,
 	}).Handler()
 }
 
@@ -141,7 +182,8 @@ func hasValue(slice []string, needle string) bool {
 	return false
 }
 
-func mount(router *mux.Router, path string, handler http.Handler) {
+func mount(router *mux.Router, path string, handler *mux.Router) {
+	handler.Use(CreateRouteTemplateMiddleware(strings.TrimSuffix(path, "/")))
 	router.PathPrefix(path).Handler(
 		http.StripPrefix(
 			strings.TrimSuffix(path, "/"),
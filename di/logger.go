@@ -1,6 +1,7 @@
 package di
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/getsentry/raven-go"
@@ -11,9 +12,11 @@ import (
 	"github.com/mono83/slf/recievers/statsd"
 	"github.com/mono83/slf/recievers/writer"
 	"github.com/mono83/slf/wd"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
 
 	"github.com/elyby/chrly/eventsubscribers"
+	"github.com/elyby/chrly/logging"
 	"github.com/elyby/chrly/version"
 )
 
@@ -21,20 +24,31 @@ var logger = di.Options(
 	di.Provide(newLogger),
 	di.Provide(newSentry),
 	di.Provide(newStatsReporter),
+	di.Provide(newPrometheusRegistry),
 )
 
 type loggerParams struct {
 	di.Inject
 
+	Config      *viper.Viper  `di:""`
 	SentryRaven *raven.Client `di:"" optional:"true"`
 }
 
-func newLogger(params loggerParams) slf.Logger {
+func newLogger(params loggerParams) (slf.Logger, error) {
+	params.Config.SetDefault("log.format", "text")
+
 	dispatcher := &slf.Dispatcher{}
-	dispatcher.AddReceiver(writer.New(writer.Options{
-		Marker:     false,
-		TimeFormat: "15:04:05.000",
-	}))
+	switch format := params.Config.GetString("log.format"); format {
+	case "text":
+		dispatcher.AddReceiver(writer.New(writer.Options{
+			Marker:     false,
+			TimeFormat: "15:04:05.000",
+		}))
+	case "json":
+		dispatcher.AddReceiver(&logging.JsonReceiver{Target: os.Stdout})
+	default:
+		return nil, fmt.Errorf("unknown log format \"%s\"", format)
+	}
 
 	if params.SentryRaven != nil {
 		sentryReceiver, _ := sentry.NewReceiverWithCustomRaven(
@@ -49,7 +63,7 @@ func newLogger(params loggerParams) slf.Logger {
 	logger := wd.Custom("", "", dispatcher)
 	logger.WithParams(rays.Host)
 
-	return logger
+	return logger, nil
 }
 
 func newSentry(config *viper.Viper) (*raven.Client, error) {
@@ -97,6 +111,21 @@ func newStatsReporter(config *viper.Viper) (slf.StatsReporter, error) {
 	return wd.Custom("", "", dispatcher), nil
 }
 
+func newPrometheusRegistry(config *viper.Viper) *prometheus.Registry {
+	config.SetDefault("prometheus.enabled", false)
+	if !config.GetBool("prometheus.enabled") {
+		return nil
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return registry
+}
+
 func enableReporters(reporter slf.StatsReporter, factories []eventsubscribers.Reporter) {
 	for _, factory := range factories {
 		factory.Enable(reporter)
@@ -2,6 +2,7 @@ package di
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 	"time"
@@ -17,13 +18,16 @@ import (
 
 var mojangTextures = di.Options(
 	di.Invoke(interceptMojangApiUrls),
+	di.Invoke(configureMojangSessionServerOutbound),
 	di.Provide(newMojangTexturesProviderFactory),
 	di.Provide(newMojangTexturesProvider),
 	di.Provide(newMojangTexturesUuidsProviderFactory),
+	di.Provide(newMojangTexturesRetryPolicy),
 	di.Provide(newMojangTexturesBatchUUIDsProvider),
 	di.Provide(newMojangTexturesBatchUUIDsProviderStrategyFactory),
 	di.Provide(newMojangTexturesBatchUUIDsProviderDelayedStrategy),
 	di.Provide(newMojangTexturesBatchUUIDsProviderFullBusStrategy),
+	di.Provide(newMojangTexturesBatchUUIDsProviderTokenBucketStrategy),
 	di.Provide(newMojangTexturesRemoteUUIDsProvider),
 	di.Provide(newMojangSignedTexturesProvider),
 	di.Provide(newMojangTexturesStorageFactory),
@@ -53,54 +57,216 @@ func interceptMojangApiUrls(config *viper.Viper) error {
 	return nil
 }
 
+func configureMojangSessionServerOutbound(config *viper.Viper, emitter mojangtextures.Emitter) error {
+	config.SetDefault("mojang_textures.outbound.cool_down", time.Minute)
+
+	var endpoints []*mojangtextures.OutboundEndpoint
+	for _, addr := range config.GetStringSlice("mojang_textures.outbound.addresses") {
+		endpoint, err := mojangtextures.NewLocalAddrEndpoint(addr)
+		if err != nil {
+			return err
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	for _, proxyUrl := range config.GetStringSlice("mojang_textures.outbound.proxies") {
+		endpoint, err := mojangtextures.NewProxyEndpoint(proxyUrl)
+		if err != nil {
+			return err
+		}
+
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	client := *mojang.HttpClient
+	client.Transport = &mojangtextures.OutboundPool{
+		Emitter:   emitter,
+		Endpoints: endpoints,
+		CoolDown:  config.GetDuration("mojang_textures.outbound.cool_down"),
+	}
+	mojang.SessionServerHttpClient = &client
+
+	return nil
+}
+
 func newMojangTexturesProviderFactory(
 	container *di.Container,
 	config *viper.Viper,
+	emitter mojangtextures.Emitter,
 ) (http.MojangTexturesProvider, error) {
-	config.SetDefault("mojang_textures.enabled", true)
-	if !config.GetBool("mojang_textures.enabled") {
+	config.SetDefault("textures_sources.chain", []string{"mojang"})
+
+	var sources []*mojangtextures.TexturesSource
+	for _, name := range config.GetStringSlice("textures_sources.chain") {
+		source, err := newTexturesSource(container, config, emitter, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if source != nil {
+			sources = append(sources, source)
+		}
+	}
+
+	if len(sources) == 0 {
 		return &mojangtextures.NilProvider{}, nil
 	}
 
-	var provider *mojangtextures.Provider
-	err := container.Resolve(&provider)
-	if err != nil {
-		return nil, err
+	// Keep the single Mojang's source without the chain to avoid the extra events.
+	// Other sources need the chain to record their names as the textures source of the request
+	if _, isMojang := sources[0].Provider.(*mojangtextures.Provider); len(sources) == 1 && isMojang && sources[0].Timeout == 0 {
+		return sources[0].Provider, nil
 	}
 
-	return provider, nil
+	return &mojangtextures.TexturesSourcesChain{
+		Emitter: emitter,
+		Sources: sources,
+	}, nil
+}
+
+// Returns nil when the source is disabled
+func newTexturesSource(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+	name string,
+) (*mojangtextures.TexturesSource, error) {
+	config.SetDefault("mojang_textures.enabled", true)
+	config.SetDefault("textures_sources.elyby.driver", "chrly")
+	config.SetDefault("textures_sources.elyby.url", "http://skinsystem.ely.by")
+
+	key := "textures_sources." + name
+	config.SetDefault(key+".driver", name)
+
+	var provider mojangtextures.UsernameTexturesProvider
+	driver := config.GetString(key + ".driver")
+	switch driver {
+	case "mojang":
+		if !config.GetBool("mojang_textures.enabled") {
+			return nil, nil
+		}
+
+		var mojangProvider *mojangtextures.Provider
+		err := container.Resolve(&mojangProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		provider = mojangProvider
+	case "chrly":
+		// The shared http client has no timeout, so the hung remote skin system would hold the requests forever.
+		// Mojang's source isn't limited by default, since the usernames wait in its queue before the request
+		config.SetDefault(key+".timeout", 3*time.Second)
+
+		remoteUrl, err := url.Parse(config.GetString(key + ".url"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse remote url of the \"%s\" textures source: %w", name, err)
+		}
+
+		provider = &mojangtextures.RemoteSkinsystemTexturesProvider{
+			Emitter: emitter,
+			Url:     *remoteUrl,
+			Proxy:   config.GetBool(key + ".proxy"),
+		}
+	default:
+		return nil, fmt.Errorf("unknown textures source driver \"%s\"", driver)
+	}
+
+	return &mojangtextures.TexturesSource{
+		Name:     name,
+		Provider: provider,
+		Timeout:  config.GetDuration(key + ".timeout"),
+	}, nil
 }
 
 func newMojangTexturesProvider(
+	config *viper.Viper,
 	emitter mojangtextures.Emitter,
 	uuidsProvider mojangtextures.UUIDsProvider,
 	texturesProvider mojangtextures.TexturesProvider,
 	storage mojangtextures.Storage,
 ) *mojangtextures.Provider {
+	config.SetDefault("mojang_textures.refreshes_per_minute", 60)
+
 	return &mojangtextures.Provider{
-		Emitter:          emitter,
-		UUIDsProvider:    uuidsProvider,
-		TexturesProvider: texturesProvider,
-		Storage:          storage,
+		Emitter:            emitter,
+		UUIDsProvider:      uuidsProvider,
+		TexturesProvider:   texturesProvider,
+		Storage:            storage,
+		RefreshesPerMinute: config.GetInt("mojang_textures.refreshes_per_minute"),
 	}
 }
 
 func newMojangTexturesUuidsProviderFactory(
 	config *viper.Viper,
 	container *di.Container,
+	emitter mojangtextures.Emitter,
+	retryPolicy *mojangtextures.RetryPolicy,
 ) (mojangtextures.UUIDsProvider, error) {
+	var provider mojangtextures.UUIDsProvider
 	preferredUuidsProvider := config.GetString("mojang_textures.uuids_provider.driver")
 	if preferredUuidsProvider == "remote" {
-		var provider *mojangtextures.RemoteApiUuidsProvider
-		err := container.Resolve(&provider)
+		var remoteProvider *mojangtextures.RemoteApiUuidsProvider
+		err := container.Resolve(&remoteProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		provider = remoteProvider
+	} else {
+		var batchProvider *mojangtextures.BatchUuidsProvider
+		err := container.Resolve(&batchProvider)
+		if err != nil {
+			return nil, err
+		}
+
+		provider = batchProvider
 
-		return provider, err
+		breaker, err := newMojangTexturesCircuitBreaker(container, config, emitter, "usernames")
+		if err != nil {
+			return nil, err
+		}
+
+		if breaker != nil {
+			provider = &mojangtextures.CircuitBreakingUuidsProvider{
+				UUIDsProvider:  provider,
+				CircuitBreaker: breaker,
+			}
+		}
 	}
 
-	var provider *mojangtextures.BatchUuidsProvider
-	err := container.Resolve(&provider)
+	if retryPolicy.Attempts <= 1 {
+		return provider, nil
+	}
 
-	return provider, err
+	return &mojangtextures.RetryingUuidsProvider{
+		Emitter:       emitter,
+		UUIDsProvider: provider,
+		RetryPolicy:   retryPolicy,
+	}, nil
+}
+
+func newMojangTexturesRetryPolicy(config *viper.Viper) *mojangtextures.RetryPolicy {
+	config.SetDefault("mojang_textures.retry.attempts", 3)
+	config.SetDefault("mojang_textures.retry.delay", 200*time.Millisecond)
+	config.SetDefault("mojang_textures.retry.max_delay", 2*time.Second)
+	config.SetDefault("mojang_textures.retry.errors", []string{
+		mojangtextures.ServerErrorKind,
+		mojangtextures.TimeoutErrorKind,
+		mojangtextures.ConnectionErrorKind,
+	})
+
+	return &mojangtextures.RetryPolicy{
+		Attempts: config.GetInt("mojang_textures.retry.attempts"),
+		Delay:    config.GetDuration("mojang_textures.retry.delay"),
+		MaxDelay: config.GetDuration("mojang_textures.retry.max_delay"),
+		Errors:   config.GetStringSlice("mojang_textures.retry.errors"),
+	}
 }
 
 func newMojangTexturesBatchUUIDsProvider(
@@ -162,6 +328,14 @@ func newMojangTexturesBatchUUIDsProviderStrategyFactory(
 			return nil, err
 		}
 
+		return strategy, nil
+	case "token-bucket":
+		var strategy *mojangtextures.TokenBucketStrategy
+		err := container.Resolve(&strategy)
+		if err != nil {
+			return nil, err
+		}
+
 		return strategy, nil
 	default:
 		return nil, fmt.Errorf("unknown queue strategy \"%s\"", strategyName)
@@ -188,6 +362,39 @@ func newMojangTexturesBatchUUIDsProviderFullBusStrategy(config *viper.Viper) *mo
 	)
 }
 
+func newMojangTexturesBatchUUIDsProviderTokenBucketStrategy(config *viper.Viper) (*mojangtextures.TokenBucketStrategy, error) {
+	config.SetDefault("queue.batch_size", 10)
+	config.SetDefault("queue.token_bucket.capacity", 10)
+	config.SetDefault("queue.token_bucket.refill_interval", time.Second)
+	config.SetDefault("queue.token_bucket.backoff", 5*time.Second)
+	config.SetDefault("queue.token_bucket.max_backoff", time.Minute)
+	config.SetDefault("queue.token_bucket.max_requeues", 5)
+
+	capacity := config.GetInt("queue.token_bucket.capacity")
+	if capacity <= 0 {
+		return nil, errors.New("queue.token_bucket.capacity must be greater than zero")
+	}
+
+	refillInterval := config.GetDuration("queue.token_bucket.refill_interval")
+	if refillInterval <= 0 {
+		return nil, errors.New("queue.token_bucket.refill_interval must be greater than zero")
+	}
+
+	maxRequeues := config.GetInt("queue.token_bucket.max_requeues")
+	if maxRequeues < 0 {
+		return nil, errors.New("queue.token_bucket.max_requeues must not be negative")
+	}
+
+	return mojangtextures.NewTokenBucketStrategy(
+		config.GetInt("queue.batch_size"),
+		capacity,
+		refillInterval,
+		config.GetDuration("queue.token_bucket.backoff"),
+		config.GetDuration("queue.token_bucket.max_backoff"),
+		maxRequeues,
+	), nil
+}
+
 func newMojangTexturesRemoteUUIDsProvider(
 	container *di.Container,
 	config *viper.Viper,
@@ -212,16 +419,110 @@ func newMojangTexturesRemoteUUIDsProvider(
 		return nil, err
 	}
 
-	return &mojangtextures.RemoteApiUuidsProvider{
+	provider := &mojangtextures.RemoteApiUuidsProvider{
 		Emitter: emitter,
 		Url:     *remoteUrl,
-	}, nil
+		Token:   config.GetString("mojang_textures.uuids_provider.token"),
+	}
+
+	config.SetDefault("mojang_textures.uuids_provider.batch_delay", 50*time.Millisecond)
+	config.SetDefault("mojang_textures.uuids_provider.batch_size", 100)
+	if batchUrl := config.GetString("mojang_textures.uuids_provider.batch_url"); batchUrl != "" {
+		remoteBatchUrl, err := url.Parse(batchUrl)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse remote batch url: %w", err)
+		}
+
+		// The worker rejects the whole batch, when it's larger than the limit
+		batchSize := config.GetInt("mojang_textures.uuids_provider.batch_size")
+		if batchSize < 1 || batchSize > http.MaxUsernamesPerBatch {
+			return nil, fmt.Errorf("mojang_textures.uuids_provider.batch_size must be between 1 and %d", http.MaxUsernamesPerBatch)
+		}
+
+		provider.BatchUrl = remoteBatchUrl
+		provider.BatchDelay = config.GetDuration("mojang_textures.uuids_provider.batch_delay")
+		provider.BatchSize = batchSize
+	}
+
+	return provider, nil
 }
 
-func newMojangSignedTexturesProvider(emitter mojangtextures.Emitter) mojangtextures.TexturesProvider {
-	return &mojangtextures.MojangApiTexturesProvider{
+func newMojangSignedTexturesProvider(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+	retryPolicy *mojangtextures.RetryPolicy,
+) (mojangtextures.TexturesProvider, error) {
+	if config.GetString("mojang_textures.textures_provider.driver") == "remote" {
+		remoteUrl, err := url.Parse(config.GetString("mojang_textures.textures_provider.url"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse remote url: %w", err)
+		}
+
+		return &mojangtextures.RemoteApiTexturesProvider{
+			Emitter: emitter,
+			Url:     *remoteUrl,
+			Token:   config.GetString("mojang_textures.textures_provider.token"),
+		}, nil
+	}
+
+	var provider mojangtextures.TexturesProvider = &mojangtextures.MojangApiTexturesProvider{
 		Emitter: emitter,
 	}
+
+	breaker, err := newMojangTexturesCircuitBreaker(container, config, emitter, "textures")
+	if err != nil {
+		return nil, err
+	}
+
+	if breaker != nil {
+		provider = &mojangtextures.CircuitBreakingTexturesProvider{
+			TexturesProvider: provider,
+			CircuitBreaker:   breaker,
+		}
+	}
+
+	if retryPolicy.Attempts <= 1 {
+		return provider, nil
+	}
+
+	return &mojangtextures.RetryingTexturesProvider{
+		Emitter:          emitter,
+		TexturesProvider: provider,
+		RetryPolicy:      retryPolicy,
+	}, nil
+}
+
+// Returns nil when the circuit breaker is disabled
+func newMojangTexturesCircuitBreaker(
+	container *di.Container,
+	config *viper.Viper,
+	emitter mojangtextures.Emitter,
+	name string,
+) (*mojangtextures.CircuitBreaker, error) {
+	config.SetDefault("mojang_textures.circuit_breaker.threshold", 5)
+	config.SetDefault("mojang_textures.circuit_breaker.open_duration", 30*time.Second)
+
+	threshold := config.GetInt("mojang_textures.circuit_breaker.threshold")
+	if threshold <= 0 {
+		return nil, nil
+	}
+
+	if err := container.Provide(func(emitter es.Subscriber) *namedHealthChecker {
+		return &namedHealthChecker{
+			Name:    fmt.Sprintf("mojang-%s-circuit-breaker", name),
+			Checker: es.MojangCircuitBreakerChecker(emitter, name),
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return &mojangtextures.CircuitBreaker{
+		Emitter:      emitter,
+		Name:         name,
+		Threshold:    threshold,
+		OpenDuration: config.GetDuration("mojang_textures.circuit_breaker.open_duration"),
+	}, nil
 }
 
 func newMojangTexturesStorageFactory(
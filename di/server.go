@@ -14,7 +14,8 @@ import (
 )
 
 var server = di.Options(
-	di.Provide(newAuthenticator, di.As(new(Authenticator))),
+	di.Provide(newAuthenticator),
+	di.Provide(newRevocationAwareAuthenticator),
 	di.Provide(newServer),
 )
 
@@ -30,6 +31,14 @@ func newAuthenticator(config *viper.Viper, emitter Emitter) (*JwtAuth, error) {
 	}, nil
 }
 
+// The plain *JwtAuth is enough to issue tokens, so the token command doesn't require the storage connection.
+// The server checks the revoked tokens denylist, which is kept in the configured storage
+func newRevocationAwareAuthenticator(auth *JwtAuth, revokedTokensRepository RevokedTokensRepository) Authenticator {
+	auth.RevokedTokensRepo = revokedTokensRepository
+
+	return auth
+}
+
 type serverParams struct {
 	di.Inject
 
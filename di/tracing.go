@@ -0,0 +1,89 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/goava/di"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/elyby/chrly/version"
+)
+
+var tracing = di.Options(
+	di.Provide(newTracerProvider),
+)
+
+// The tracing is disabled when no exporter is configured. In that case the global
+// no-op tracer provider stays in place and the instrumented code does nothing
+func newTracerProvider(config *viper.Viper) (*sdktrace.TracerProvider, error) {
+	config.SetDefault("tracing.exporter", "")
+	config.SetDefault("tracing.sample_ratio", 1)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName := config.GetString("tracing.exporter"); exporterName {
+	case "":
+		return nil, nil
+	case "otlp":
+		var options []otlptracehttp.Option
+		if endpoint := config.GetString("tracing.otlp.endpoint"); endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+		}
+
+		exporter, err = otlptracehttp.New(context.Background(), options...)
+	case "file":
+		exporter, err = newFileSpanExporter(config)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter \"%s\"", exporterName)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.GetFloat64("tracing.sample_ratio")))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName("chrly"),
+			semconv.ServiceVersion(version.Version()),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider, nil
+}
+
+func newFileSpanExporter(config *viper.Viper) (sdktrace.SpanExporter, error) {
+	config.SetDefault("storage.filesystem.basePath", "data")
+	config.SetDefault("tracing.file.fileName", "traces.json")
+
+	basePath := config.GetString("storage.filesystem.basePath")
+	err := os.MkdirAll(basePath, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path.Join(basePath, config.GetString("tracing.file.fileName")), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return stdouttrace.New(stdouttrace.WithWriter(file))
+}
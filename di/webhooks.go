@@ -0,0 +1,63 @@
+package di
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/goava/di"
+	"github.com/spf13/viper"
+
+	w "github.com/elyby/chrly/webhooks"
+)
+
+var webhooks = di.Options(
+	di.Provide(newWebhooks),
+)
+
+// Webhooks are disabled when no targets are configured
+func newWebhooks(config *viper.Viper, emitter w.Emitter) (*w.Webhooks, error) {
+	config.SetDefault("webhooks.attempts", 5)
+	config.SetDefault("webhooks.delay", time.Second)
+	config.SetDefault("webhooks.max_delay", time.Minute)
+	config.SetDefault("webhooks.timeout", 5*time.Second)
+	config.SetDefault("webhooks.queue_size", 1000)
+	config.SetDefault("webhooks.workers", 2)
+	config.SetDefault("webhooks.dead_letters.fileName", "webhooks_dead_letters.jsonl")
+	config.SetDefault("storage.filesystem.basePath", "data")
+
+	targets := config.GetStringSlice("webhooks.urls")
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	secret := config.GetString("webhooks.secret")
+	if secret == "" {
+		return nil, errors.New("webhooks.secret must be set in order to send webhooks")
+	}
+
+	basePath := config.GetString("storage.filesystem.basePath")
+	err := os.MkdirAll(basePath, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	return &w.Webhooks{
+		Emitter: emitter,
+		Targets: targets,
+		Secret:  []byte(secret),
+		Client: &http.Client{
+			Timeout: config.GetDuration("webhooks.timeout"),
+		},
+		DeadLetters: &w.FileDeadLetterQueue{
+			Path: path.Join(basePath, config.GetString("webhooks.dead_letters.fileName")),
+		},
+		Attempts:  config.GetInt("webhooks.attempts"),
+		Delay:     config.GetDuration("webhooks.delay"),
+		MaxDelay:  config.GetDuration("webhooks.max_delay"),
+		QueueSize: config.GetInt("webhooks.queue_size"),
+		Workers:   config.GetInt("webhooks.workers"),
+	}, nil
+}
@@ -0,0 +1,176 @@
+package dispatcher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Transport delivers the messages between the application instances
+type Transport interface {
+	Publish(channel string, message []byte) error
+	// Subscribe blocks and passes the received messages to the handler until
+	// the context is cancelled or the connection fails
+	Subscribe(ctx context.Context, channel string, handler func(message []byte)) error
+}
+
+type distributedMessage struct {
+	Instance string            `json:"instance"`
+	Topic    string            `json:"topic"`
+	Args     []json.RawMessage `json:"args"`
+}
+
+// Distributed delivers the events to the local subscribers and forwards the events of the selected topics
+// to the other instances through the transport. The arguments of the forwarded events are serialized to JSON
+// and decoded into the types of the remote handlers' params, so only the serializable payloads can be forwarded.
+// Errors are emitted locally into the "dispatcher:error" topic
+type Distributed struct {
+	Transport Transport
+	Channel   string
+	// ReconnectDelay is the pause before the next subscription attempt after the connection failure
+	ReconnectDelay time.Duration
+
+	local      Dispatcher
+	instanceId string
+	topics     map[string]bool
+	lock       sync.RWMutex
+	handlers   map[string][]reflect.Value
+}
+
+func NewDistributed(transport Transport, channel string, topics []string) *Distributed {
+	instanceId := make([]byte, 8)
+	_, _ = rand.Read(instanceId)
+
+	topicsMap := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		topicsMap[topic] = true
+	}
+
+	return &Distributed{
+		Transport:      transport,
+		Channel:        channel,
+		ReconnectDelay: time.Second,
+		local:          New(),
+		instanceId:     hex.EncodeToString(instanceId),
+		topics:         topicsMap,
+		handlers:       make(map[string][]reflect.Value),
+	}
+}
+
+func (d *Distributed) Subscribe(topic string, fn interface{}) {
+	d.local.Subscribe(topic, fn)
+	if !d.topics[topic] {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.handlers[topic] = append(d.handlers[topic], reflect.ValueOf(fn))
+}
+
+func (d *Distributed) Emit(topic string, args ...interface{}) {
+	d.local.Emit(topic, args...)
+	if !d.topics[topic] {
+		return
+	}
+
+	message := &distributedMessage{
+		Instance: d.instanceId,
+		Topic:    topic,
+		Args:     make([]json.RawMessage, len(args)),
+	}
+	for i, arg := range args {
+		encodedArg, err := json.Marshal(arg)
+		if err != nil {
+			d.emitError(fmt.Errorf("unable to encode the argument #%d of the \"%s\" event: %w", i, topic, err))
+			return
+		}
+
+		message.Args[i] = encodedArg
+	}
+
+	encodedMessage, _ := json.Marshal(message)
+	if err := d.Transport.Publish(d.Channel, encodedMessage); err != nil {
+		d.emitError(fmt.Errorf("unable to publish the \"%s\" event: %w", topic, err))
+	}
+}
+
+// Local returns the subscriber, that receives only the events emitted by this instance.
+// It should be used by the handlers, that must process each event only once across all instances
+func (d *Distributed) Local() Subscriber {
+	return d.local
+}
+
+// Listen receives the events of the other instances until the context is cancelled.
+// When the transport's connection fails, the subscription is restored after the ReconnectDelay
+func (d *Distributed) Listen(ctx context.Context) {
+	for {
+		err := d.Transport.Subscribe(ctx, d.Channel, d.handleMessage)
+		if ctx.Err() != nil {
+			return
+		}
+
+		d.emitError(fmt.Errorf("the subscription to the \"%s\" channel has failed: %w", d.Channel, err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(d.ReconnectDelay):
+		}
+	}
+}
+
+func (d *Distributed) handleMessage(encodedMessage []byte) {
+	message := &distributedMessage{}
+	if err := json.Unmarshal(encodedMessage, message); err != nil {
+		d.emitError(fmt.Errorf("unable to decode the received message: %w", err))
+		return
+	}
+
+	// The transport delivers the message to the sender too, but the local subscribers have already received it
+	if message.Instance == d.instanceId || !d.topics[message.Topic] {
+		return
+	}
+
+	d.lock.RLock()
+	handlers := d.handlers[message.Topic]
+	d.lock.RUnlock()
+
+	for _, handler := range handlers {
+		args, err := decodeArgs(handler.Type(), message.Args)
+		if err != nil {
+			d.emitError(fmt.Errorf("unable to decode the arguments of the \"%s\" event: %w", message.Topic, err))
+			continue
+		}
+
+		handler.Call(args)
+	}
+}
+
+func (d *Distributed) emitError(err error) {
+	d.local.Emit("dispatcher:error", err)
+}
+
+func decodeArgs(handlerType reflect.Type, encodedArgs []json.RawMessage) ([]reflect.Value, error) {
+	if handlerType.NumIn() != len(encodedArgs) {
+		return nil, fmt.Errorf("the handler expects %d arguments, but %d are received", handlerType.NumIn(), len(encodedArgs))
+	}
+
+	args := make([]reflect.Value, len(encodedArgs))
+	for i, encodedArg := range encodedArgs {
+		arg := reflect.New(handlerType.In(i))
+		if err := json.Unmarshal(encodedArg, arg.Interface()); err != nil {
+			return nil, err
+		}
+
+		args[i] = arg.Elem()
+	}
+
+	return args, nil
+}
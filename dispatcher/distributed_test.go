@@ -0,0 +1,170 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type payload struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// inMemoryTransport delivers the published messages to all the subscribers, including the publisher itself
+type inMemoryTransport struct {
+	lock        sync.Mutex
+	subscribers []func(message []byte)
+	subscribed  chan bool
+	publishErr  error
+}
+
+func newInMemoryTransport() *inMemoryTransport {
+	return &inMemoryTransport{subscribed: make(chan bool, 10)}
+}
+
+func (t *inMemoryTransport) Publish(channel string, message []byte) error {
+	if t.publishErr != nil {
+		return t.publishErr
+	}
+
+	t.lock.Lock()
+	subscribers := t.subscribers
+	t.lock.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(message)
+	}
+
+	return nil
+}
+
+func (t *inMemoryTransport) Subscribe(ctx context.Context, channel string, handler func(message []byte)) error {
+	t.lock.Lock()
+	t.subscribers = append(t.subscribers, handler)
+	t.lock.Unlock()
+	t.subscribed <- true
+
+	<-ctx.Done()
+
+	return ctx.Err()
+}
+
+func startListening(t *testing.T, transport *inMemoryTransport, dispatchers ...*Distributed) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, d := range dispatchers {
+		go d.Listen(ctx)
+		select {
+		case <-transport.subscribed:
+		case <-time.After(time.Second):
+			t.Fatal("the dispatcher hasn't subscribed")
+		}
+	}
+
+	return cancel
+}
+
+func TestDistributed(t *testing.T) {
+	t.Run("should deliver forwarded topics to the local and remote subscribers", func(t *testing.T) {
+		transport := newInMemoryTransport()
+		sender := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		receiver := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		defer startListening(t, transport, sender, receiver)()
+
+		var localCalls []*payload
+		sender.Subscribe("forwarded", func(p *payload, count int) {
+			localCalls = append(localCalls, p)
+		})
+
+		var remotePayload *payload
+		var remoteCount int
+		receiver.Subscribe("forwarded", func(p *payload, count int) {
+			remotePayload = p
+			remoteCount = count
+		})
+
+		sender.Emit("forwarded", &payload{Id: 1, Name: "mock"}, 5)
+
+		// The sender must not receive its own event twice
+		require.Len(t, localCalls, 1)
+		assert.Equal(t, &payload{Id: 1, Name: "mock"}, localCalls[0])
+		assert.Equal(t, &payload{Id: 1, Name: "mock"}, remotePayload)
+		assert.Equal(t, 5, remoteCount)
+	})
+
+	t.Run("should not forward other topics", func(t *testing.T) {
+		transport := newInMemoryTransport()
+		sender := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		receiver := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		defer startListening(t, transport, sender, receiver)()
+
+		isLocalCalled := false
+		sender.Subscribe("local", func(value string) {
+			isLocalCalled = true
+		})
+		receiver.Subscribe("local", func(value string) {
+			t.Fatal("the local event must not be forwarded")
+		})
+
+		sender.Emit("local", "value")
+
+		assert.True(t, isLocalCalled)
+	})
+
+	t.Run("should not deliver remote events to the local only subscribers", func(t *testing.T) {
+		transport := newInMemoryTransport()
+		sender := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		receiver := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		defer startListening(t, transport, sender, receiver)()
+
+		localCalls := 0
+		receiver.Local().Subscribe("forwarded", func(value string) {
+			localCalls++
+		})
+
+		sender.Emit("forwarded", "remote")
+		receiver.Emit("forwarded", "local")
+
+		assert.Equal(t, 1, localCalls)
+	})
+
+	t.Run("should emit an error when the arguments can't be decoded", func(t *testing.T) {
+		transport := newInMemoryTransport()
+		sender := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		receiver := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+		defer startListening(t, transport, sender, receiver)()
+
+		receiver.Subscribe("forwarded", func(value int) {
+			t.Fatal("the handler must not be called")
+		})
+
+		var receivedErr error
+		receiver.Subscribe("dispatcher:error", func(err error) {
+			receivedErr = err
+		})
+
+		sender.Emit("forwarded", "not a number")
+
+		assert.Error(t, receivedErr)
+	})
+
+	t.Run("should emit an error when the event can't be published", func(t *testing.T) {
+		transport := newInMemoryTransport()
+		transport.publishErr = errors.New("mock error")
+		sender := NewDistributed(transport, "mock-channel", []string{"forwarded"})
+
+		var receivedErr error
+		sender.Subscribe("dispatcher:error", func(err error) {
+			receivedErr = err
+		})
+
+		sender.Emit("forwarded", "value")
+
+		assert.EqualError(t, receivedErr, `unable to publish the "forwarded" event: mock error`)
+	})
+}
@@ -5,6 +5,14 @@ if [ ! -d /data/capes ]; then
     mkdir -p /data/capes
 fi
 
+if [ ! -d /data/skins ]; then
+    mkdir -p /data/skins
+fi
+
+if [ ! -d /data/accounts ]; then
+    mkdir -p /data/accounts
+fi
+
 if [ "$1" = "serve" ] || [ "$1" = "worker" ] || [ "$1" = "token" ] || [ "$1" = "version" ]; then
     set -- /usr/local/bin/chrly "$@"
 fi
@@ -80,6 +80,31 @@ func MojangApiTexturesProviderResponseChecker(dispatcher Subscriber, resetDurati
 	}
 }
 
+func MojangCircuitBreakerChecker(dispatcher Subscriber, name string) healthcheck.CheckerFunc {
+	var mutex sync.Mutex
+	isOpen := false
+	dispatcher.Subscribe("mojang_textures:circuit_breaker:state_changed", func(breakerName string, state string) {
+		if breakerName != name {
+			return
+		}
+
+		mutex.Lock()
+		isOpen = state == "open"
+		mutex.Unlock()
+	})
+
+	return func(ctx context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if isOpen {
+			return errors.New("the circuit breaker is open")
+		}
+
+		return nil
+	}
+}
+
 type expiringErrHolder struct {
 	D   time.Duration
 	err error
@@ -144,3 +144,36 @@ func TestMojangApiTexturesProviderResponseChecker(t *testing.T) {
 		assert.Nil(t, checker(context.Background()))
 	})
 }
+
+func TestMojangCircuitBreakerChecker(t *testing.T) {
+	t.Run("empty state", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := MojangCircuitBreakerChecker(d, "textures")
+		assert.Nil(t, checker(context.Background()))
+	})
+
+	t.Run("when the circuit is open", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := MojangCircuitBreakerChecker(d, "textures")
+		d.Emit("mojang_textures:circuit_breaker:state_changed", "textures", "open")
+		checkResult := checker(context.Background())
+		if assert.Error(t, checkResult) {
+			assert.Equal(t, "the circuit breaker is open", checkResult.Error())
+		}
+	})
+
+	t.Run("should ignore other breakers", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := MojangCircuitBreakerChecker(d, "textures")
+		d.Emit("mojang_textures:circuit_breaker:state_changed", "usernames", "open")
+		assert.Nil(t, checker(context.Background()))
+	})
+
+	t.Run("should reset the state after the circuit is closed", func(t *testing.T) {
+		d := dispatcher.New()
+		checker := MojangCircuitBreakerChecker(d, "textures")
+		d.Emit("mojang_textures:circuit_breaker:state_changed", "textures", "open")
+		d.Emit("mojang_textures:circuit_breaker:state_changed", "textures", "half-open")
+		assert.Nil(t, checker(context.Background()))
+	})
+}
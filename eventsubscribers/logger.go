@@ -6,11 +6,14 @@ import (
 	"net/url"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/mono83/slf"
 	"github.com/mono83/slf/wd"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/logging"
+	"github.com/elyby/chrly/model"
 )
 
 type Logger struct {
@@ -22,6 +25,17 @@ func (l *Logger) ConfigureWithDispatcher(d Subscriber) {
 
 	d.Subscribe("mojang_textures:usernames:after_call", l.createMojangTexturesErrorHandler("usernames"))
 	d.Subscribe("mojang_textures:textures:after_call", l.createMojangTexturesErrorHandler("textures"))
+	d.Subscribe("mojang_textures:usernames:retry", l.createMojangTexturesRetryHandler("usernames"))
+	d.Subscribe("mojang_textures:textures:retry", l.createMojangTexturesRetryHandler("textures"))
+	d.Subscribe("dispatcher:error", l.handleDispatcherError)
+	// When the distributed dispatcher is used, the changes made through the other instances are logged too
+	d.Subscribe("skinsystem:skin:saved", l.createSkinChangeHandler("saved"))
+	d.Subscribe("skinsystem:skin:removed", l.createSkinChangeHandler("removed"))
+	d.Subscribe("skinsystem:cape:saved", l.createCapeChangeHandler("saved"))
+	d.Subscribe("skinsystem:cape:removed", l.createCapeChangeHandler("removed"))
+	d.Subscribe("webhooks:failed", l.handleWebhookFailure)
+	d.Subscribe("webhooks:dead_lettered", l.handleWebhookDeadLetter)
+	d.Subscribe("webhooks:error", l.handleWebhooksError)
 }
 
 func (l *Logger) handleAfterSkinsystemRequest(req *http.Request, statusCode int) {
@@ -30,17 +44,73 @@ func (l *Logger) handleAfterSkinsystemRequest(req *http.Request, statusCode int)
 		path += "?" + req.URL.RawQuery
 	}
 
-	l.Info(
-		":ip - - \":method :path\" :statusCode - \":userAgent\" \":forwardedIp\"",
+	params := []slf.Param{
 		wd.StringParam("ip", trimPort(req.RemoteAddr)),
 		wd.StringParam("method", req.Method),
 		wd.StringParam("path", path),
 		wd.IntParam("statusCode", statusCode),
 		wd.StringParam("userAgent", req.UserAgent()),
 		wd.StringParam("forwardedIp", req.Header.Get("X-Forwarded-For")),
+	}
+
+	// These params aren't a part of the message, but they're written by the structured logs receivers
+	if info := logging.RequestInfoFromContext(req.Context()); info != nil {
+		params = append(
+			params,
+			wd.StringParam("requestId", info.Id),
+			wd.StringParam("texturesSource", info.TexturesSource),
+			wd.Int64Param("responseSize", info.ResponseSize),
+			wd.Int64Param("durationUs", time.Since(info.StartedAt).Microseconds()),
+		)
+	}
+
+	l.Info(
+		":ip - - \":method :path\" :statusCode - \":userAgent\" \":forwardedIp\"",
+		params...,
+	)
+}
+
+func (l *Logger) handleDispatcherError(err error) {
+	l.Error("Distributed dispatcher error: :err", wd.ErrParam(err))
+}
+
+func (l *Logger) createSkinChangeHandler(action string) func(skin *model.Skin) {
+	actionParam := wd.StringParam("action", action)
+	return func(skin *model.Skin) {
+		l.Info("Skin of :username has been :action", wd.StringParam("username", skin.Username), actionParam)
+	}
+}
+
+func (l *Logger) createCapeChangeHandler(action string) func(username string) {
+	actionParam := wd.StringParam("action", action)
+	return func(username string) {
+		l.Info("Cape of :username has been :action", wd.StringParam("username", username), actionParam)
+	}
+}
+
+func (l *Logger) handleWebhookFailure(url string, event string, attempt int, err error) {
+	l.Warning(
+		"Webhook :event delivery to :url failed on the attempt #:attempt: :err",
+		wd.StringParam("event", event),
+		wd.StringParam("url", url),
+		wd.IntParam("attempt", attempt),
+		wd.ErrParam(err),
+	)
+}
+
+func (l *Logger) handleWebhookDeadLetter(url string, event string, err error) {
+	l.Error(
+		"Webhook :event delivery to :url has been dead-lettered: :err",
+		wd.StringParam("event", event),
+		wd.StringParam("url", url),
+		wd.ErrParam(err),
 	)
 }
 
+func (l *Logger) handleWebhooksError(err error) {
+	l.Error("Webhooks error: :err", wd.ErrParam(err))
+}
+
 func (l *Logger) createMojangTexturesErrorHandler(provider string) func(identity string, result interface{}, err error) {
 	providerParam := wd.NameParam(provider)
 	return func(identity string, result interface{}, err error) {
@@ -82,6 +152,19 @@ func (l *Logger) createMojangTexturesErrorHandler(provider string) func(identity
 	}
 }
 
+func (l *Logger) createMojangTexturesRetryHandler(provider string) func(identity string, attempt int, err error) {
+	providerParam := wd.NameParam(provider)
+	return func(identity string, attempt int, err error) {
+		l.Warning(
+			":name: Retrying the request for :identity after the attempt #:attempt failed: :err",
+			providerParam,
+			wd.StringParam("identity", identity),
+			wd.IntParam("attempt", attempt),
+			wd.ErrParam(err),
+		)
+	}
+}
+
 func (l *Logger) logMojangTexturesWarning(providerParam slf.Param, errParam slf.Param) {
 	l.Warning(":name: :err", providerParam, errParam)
 }
@@ -1,12 +1,14 @@
 package eventsubscribers
 
 import (
+	"errors"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/mono83/slf"
 	"github.com/mono83/slf/params"
@@ -14,6 +16,8 @@ import (
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/logging"
+	"github.com/elyby/chrly/model"
 )
 
 type LoggerMock struct {
@@ -128,6 +132,132 @@ var loggerTestCases = map[string]*LoggerTestCase{
 			},
 		},
 	},
+	"should log the request info if it's available": {
+		Events: [][]interface{}{
+			{"skinsystem:after_request",
+				(func() *http.Request {
+					req := httptest.NewRequest("GET", "http://localhost/skins/username.png", nil)
+
+					return req.WithContext(logging.WithRequestInfo(req.Context(), &logging.RequestInfo{
+						Id:             "mock-request-id",
+						StartedAt:      time.Now().Add(-time.Second),
+						TexturesSource: logging.MojangCacheTexturesSource,
+						ResponseSize:   1024,
+					}))
+				})(),
+				200,
+			},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Info",
+				":ip - - \":method :path\" :statusCode - \":userAgent\" \":forwardedIp\"",
+				mock.Anything, // Already tested
+				mock.Anything, // Already tested
+				mock.Anything, // Already tested
+				mock.Anything, // Already tested
+				mock.Anything, // Already tested
+				mock.Anything, // Already tested
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "requestId" && strParam.Value == "mock-request-id"
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "texturesSource" && strParam.Value == "mojang_cache"
+				}),
+				mock.MatchedBy(func(intParam params.Int64) bool {
+					return intParam.Key == "responseSize" && intParam.Value == 1024
+				}),
+				mock.MatchedBy(func(intParam params.Int64) bool {
+					return intParam.Key == "durationUs" && intParam.Value >= int64(time.Second/time.Microsecond)
+				}),
+			},
+		},
+	},
+	"should log webhooks delivery failures": {
+		Events: [][]interface{}{
+			{"webhooks:failed", "http://localhost/hook", "skin.saved", 2, errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Warning",
+				"Webhook :event delivery to :url failed on the attempt #:attempt: :err",
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "event" && strParam.Value == "skin.saved"
+				}),
+				mock.MatchedBy(func(strParam params.String) bool {
+					return strParam.Key == "url" && strParam.Value == "http://localhost/hook"
+				}),
+				mock.MatchedBy(func(intParam params.Int) bool {
+					return intParam.Key == "attempt" && intParam.Value == 2
+				}),
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+			},
+		},
+	},
+	"should log dead-lettered webhooks": {
+		Events: [][]interface{}{
+			{"webhooks:dead_lettered", "http://localhost/hook", "skin.saved", errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Error",
+				"Webhook :event delivery to :url has been dead-lettered: :err",
+				mock.Anything,
+				mock.Anything,
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+			},
+		},
+	},
+	"should log the skins changes": {
+		Events: [][]interface{}{
+			{"skinsystem:skin:saved", &model.Skin{Username: "mock_username"}},
+			{"skinsystem:skin:removed", &model.Skin{Username: "mock_username"}},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Info",
+				"Skin of :username has been :action",
+				params.String{Key: "username", Value: "mock_username"},
+				params.String{Key: "action", Value: "saved"},
+			},
+			{"Info",
+				"Skin of :username has been :action",
+				params.String{Key: "username", Value: "mock_username"},
+				params.String{Key: "action", Value: "removed"},
+			},
+		},
+	},
+	"should log the capes changes": {
+		Events: [][]interface{}{
+			{"skinsystem:cape:saved", "mock_username"},
+			{"skinsystem:cape:removed", "mock_username"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Info",
+				"Cape of :username has been :action",
+				params.String{Key: "username", Value: "mock_username"},
+				params.String{Key: "action", Value: "saved"},
+			},
+			{"Info",
+				"Cape of :username has been :action",
+				params.String{Key: "username", Value: "mock_username"},
+				params.String{Key: "action", Value: "removed"},
+			},
+		},
+	},
+	"should log distributed dispatcher errors": {
+		Events: [][]interface{}{
+			{"dispatcher:error", errors.New("mock error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"Error",
+				"Distributed dispatcher error: :err",
+				mock.MatchedBy(func(errParam params.Error) bool {
+					return errParam.Key == "err" && errParam.Value.Error() == "mock error"
+				}),
+			},
+		},
+	},
 }
 
 type timeoutError struct{}
@@ -196,6 +326,30 @@ func init() {
 			},
 		}
 
+		loggerTestCases["should log retries for "+pn+" provider"] = &LoggerTestCase{
+			Events: [][]interface{}{
+				{"mojang_textures:" + pn + ":retry", "identity", 1, &mojang.ServerError{Status: 500}},
+			},
+			ExpectedCalls: [][]interface{}{
+				{"Warning",
+					":name: Retrying the request for :identity after the attempt #:attempt failed: :err",
+					mock.MatchedBy(func(strParam params.String) bool {
+						return strParam.Key == "name" && strParam.Value == pn
+					}),
+					mock.MatchedBy(func(strParam params.String) bool {
+						return strParam.Key == "identity" && strParam.Value == "identity"
+					}),
+					mock.MatchedBy(func(intParam params.Int) bool {
+						return intParam.Key == "attempt" && intParam.Value == 1
+					}),
+					mock.MatchedBy(func(errParam params.Error) bool {
+						_, ok := errParam.Value.(*mojang.ServerError)
+						return errParam.Key == "err" && ok
+					}),
+				},
+			},
+		}
+
 		loggerTestCases["should call error when unexpected error occurred for "+pn+" provider"] = &LoggerTestCase{
 			Events: [][]interface{}{
 				{"mojang_textures:" + pn + ":after_call", pn, nil, &mojang.ServerError{Status: 500}},
@@ -0,0 +1,334 @@
+package eventsubscribers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/logging"
+)
+
+const prometheusNamespace = "chrly"
+
+// PrometheusReporter collects metrics from the same events as the StatsReporter, but instead of
+// pushing them to the statsd server, it keeps them in the registry, that is exposed by the /metrics endpoint.
+// Names of the routes, providers and endpoints are passed as labels instead of being a part of the metric name
+type PrometheusReporter struct {
+	Registerer prometheus.Registerer
+
+	timersMap   map[string]time.Time
+	timersMutex sync.Mutex
+}
+
+func (p *PrometheusReporter) ConfigureWithDispatcher(d Subscriber) {
+	p.timersMap = make(map[string]time.Time)
+	factory := promauto.With(p.Registerer)
+
+	// Per request metrics
+	requestsTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of the handled HTTP requests.",
+	}, []string{"route", "method", "status"})
+	requestDuration := factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time spent on the HTTP request handling.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	d.Subscribe("skinsystem:before_request", func(req *http.Request) {
+		p.startTimeRecording(buildRequestTimerKey(req))
+	})
+	d.Subscribe("skinsystem:after_request", func(req *http.Request, code int) {
+		labels := prometheus.Labels{
+			"route":  routeLabel(req),
+			"method": req.Method,
+			"status": strconv.Itoa(code),
+		}
+
+		requestsTotal.With(labels).Inc()
+		if duration, ok := p.finalizeTimeRecording(buildRequestTimerKey(req)); ok {
+			requestDuration.With(labels).Observe(duration.Seconds())
+		}
+	})
+
+	// Authentication metrics
+	authenticationTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Name:      "authentication_total",
+		Help:      "Number of the authentication attempts by their result.",
+	}, []string{"result"})
+	d.Subscribe("authentication:success", func(...interface{}) {
+		authenticationTotal.WithLabelValues("success").Inc()
+	})
+	d.Subscribe("authentication:error", func(...interface{}) {
+		authenticationTotal.WithLabelValues("failed").Inc()
+	})
+
+	// Mojang signed textures source metrics
+	mojangTexturesRequestsTotal := factory.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "requests_total",
+		Help:      "Number of the Mojang's textures requests.",
+	})
+	d.Subscribe("mojang_textures:call", func(...interface{}) {
+		mojangTexturesRequestsTotal.Inc()
+	})
+
+	cacheHitsTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "cache_hits_total",
+		Help:      "Number of the Mojang's UUIDs and textures found in the cache.",
+	}, []string{"cache", "result"})
+	d.Subscribe("mojang_textures:usernames:after_cache", func(username string, uuid string, found bool, err error) {
+		if err != nil || !found {
+			return
+		}
+
+		cacheHitsTotal.WithLabelValues("usernames", nilResultLabel(uuid == "")).Inc()
+	})
+	d.Subscribe("mojang_textures:textures:after_cache", func(uuid string, textures *mojang.SignedTexturesResponse, found bool, err error) {
+		if err != nil || !found {
+			return
+		}
+
+		cacheHitsTotal.WithLabelValues("textures", nilResultLabel(textures == nil)).Inc()
+	})
+
+	providerCallsTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "provider_calls_total",
+		Help:      "Number of the Mojang's API calls by the provider and their result.",
+	}, []string{"provider", "result"})
+	d.Subscribe("mojang_textures:usernames:after_call", func(username string, profile *mojang.ProfileInfo, err error) {
+		providerCallsTotal.WithLabelValues("usernames", callResultLabel(profile == nil, err)).Inc()
+	})
+	d.Subscribe("mojang_textures:textures:after_call", func(uuid string, textures *mojang.SignedTexturesResponse, err error) {
+		providerCallsTotal.WithLabelValues("textures", callResultLabel(textures == nil, err)).Inc()
+	})
+
+	retriesTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "retries_total",
+		Help:      "Number of the retried Mojang's API calls.",
+	}, []string{"provider"})
+	d.Subscribe("mojang_textures:usernames:retry", func(...interface{}) {
+		retriesTotal.WithLabelValues("usernames").Inc()
+	})
+	d.Subscribe("mojang_textures:textures:retry", func(...interface{}) {
+		retriesTotal.WithLabelValues("textures").Inc()
+	})
+
+	resultDuration := factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "result_duration_seconds",
+		Help:      "Time spent on obtaining the Mojang's textures for the username.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	d.Subscribe("mojang_textures:before_result", func(username string, uuid string) {
+		p.startTimeRecording("mojang_textures_result_time_" + username)
+	})
+	d.Subscribe("mojang_textures:after_result", func(username string, textures *mojang.SignedTexturesResponse, err error) {
+		if duration, ok := p.finalizeTimeRecording("mojang_textures_result_time_" + username); ok {
+			resultDuration.Observe(duration.Seconds())
+		}
+	})
+
+	texturesRequestDuration := factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "textures_request_duration_seconds",
+		Help:      "Time spent on the Mojang's textures provider call.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	d.Subscribe("mojang_textures:textures:before_call", func(uuid string) {
+		p.startTimeRecording("mojang_textures_provider_time_" + uuid)
+	})
+	d.Subscribe("mojang_textures:textures:after_call", func(uuid string, textures *mojang.SignedTexturesResponse, err error) {
+		if duration, ok := p.finalizeTimeRecording("mojang_textures_provider_time_" + uuid); ok {
+			texturesRequestDuration.Observe(duration.Seconds())
+		}
+	})
+
+	circuitBreakerOpen := factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "circuit_breaker_open",
+		Help:      "Whether the circuit breaker is open (1) or not (0).",
+	}, []string{"provider"})
+	d.Subscribe("mojang_textures:circuit_breaker:state_changed", func(name string, state string) {
+		var value float64
+		if state == "open" {
+			value = 1
+		}
+
+		circuitBreakerOpen.WithLabelValues(name).Set(value)
+	})
+
+	// Textures sources chain metrics
+	sourcesCallsTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "textures_sources",
+		Name:      "calls_total",
+		Help:      "Number of the textures sources calls by their result.",
+	}, []string{"provider", "result"})
+	d.Subscribe("mojang_textures:sources:after_call", func(source string, username string, textures *mojang.SignedTexturesResponse, err error) {
+		sourcesCallsTotal.WithLabelValues(source, callResultLabel(textures == nil, err)).Inc()
+	})
+
+	// Mojang session server outbound pool metrics
+	outboundRequestsTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "outbound_requests_total",
+		Help:      "Number of the session server requests performed through the outbound endpoint.",
+	}, []string{"endpoint"})
+	outboundUnhealthyTotal := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "outbound_unhealthy_total",
+		Help:      "Number of times the outbound endpoint was marked as unhealthy.",
+	}, []string{"endpoint"})
+	outboundHealthyEndpoints := factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "outbound_healthy_endpoints",
+		Help:      "Number of the healthy outbound endpoints.",
+	})
+	d.Subscribe("mojang_textures:outbound_pool:request", func(endpoint string) {
+		outboundRequestsTotal.WithLabelValues(endpoint).Inc()
+	})
+	d.Subscribe("mojang_textures:outbound_pool:unhealthy", func(endpoint string, healthyCount int) {
+		outboundUnhealthyTotal.WithLabelValues(endpoint).Inc()
+		outboundHealthyEndpoints.Set(float64(healthyCount))
+	})
+
+	// Mojang UUIDs batch provider metrics
+	usernamesQueuedTotal := factory.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "usernames_queued_total",
+		Help:      "Number of the usernames added to the batch UUIDs provider's queue.",
+	})
+	usernamesRequeuedTotal := factory.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "usernames_requeued_total",
+		Help:      "Number of the usernames returned to the queue after the rate limit error.",
+	})
+	usernamesQueueSize := factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "usernames_queue_size",
+		Help:      "Number of the usernames waiting in the batch UUIDs provider's queue.",
+	})
+	usernamesRoundDuration := factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "mojang_textures",
+		Name:      "usernames_round_duration_seconds",
+		Help:      "Time spent on the batch UUIDs provider's round.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	d.Subscribe("mojang_textures:batch_uuids_provider:queued", func(...interface{}) {
+		usernamesQueuedTotal.Inc()
+	})
+	d.Subscribe("mojang_textures:batch_uuids_provider:requeued", func(usernames []string) {
+		usernamesRequeuedTotal.Add(float64(len(usernames)))
+	})
+	d.Subscribe("mojang_textures:batch_uuids_provider:round", func(usernames []string, queueSize int) {
+		usernamesQueueSize.Set(float64(queueSize))
+		if len(usernames) != 0 {
+			p.startTimeRecording("batch_uuids_provider_round_time_" + strings.Join(usernames, "|"))
+		}
+	})
+	d.Subscribe("mojang_textures:batch_uuids_provider:result", func(usernames []string, profiles []*mojang.ProfileInfo, err error) {
+		if duration, ok := p.finalizeTimeRecording("batch_uuids_provider_round_time_" + strings.Join(usernames, "|")); ok {
+			usernamesRoundDuration.Observe(duration.Seconds())
+		}
+	})
+
+	// Webhooks
+	webhooksDeliveries := factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: "webhooks",
+		Name:      "deliveries_total",
+		Help:      "Number of the webhooks delivery attempts by the event and their result.",
+	}, []string{"event", "result"})
+	d.Subscribe("webhooks:delivered", func(url string, event string, attempt int) {
+		webhooksDeliveries.WithLabelValues(event, "delivered").Inc()
+	})
+	d.Subscribe("webhooks:failed", func(url string, event string, attempt int, err error) {
+		webhooksDeliveries.WithLabelValues(event, "failed").Inc()
+	})
+	d.Subscribe("webhooks:dead_lettered", func(url string, event string, err error) {
+		webhooksDeliveries.WithLabelValues(event, "dead_lettered").Inc()
+	})
+}
+
+func (p *PrometheusReporter) startTimeRecording(timeKey string) {
+	p.timersMutex.Lock()
+	defer p.timersMutex.Unlock()
+	p.timersMap[timeKey] = time.Now()
+}
+
+func (p *PrometheusReporter) finalizeTimeRecording(timeKey string) (time.Duration, bool) {
+	p.timersMutex.Lock()
+	defer p.timersMutex.Unlock()
+	startedAt, ok := p.timersMap[timeKey]
+	if !ok {
+		return 0, false
+	}
+
+	delete(p.timersMap, timeKey)
+
+	return time.Since(startedAt), true
+}
+
+// The same request object is passed to both before and after request events
+func buildRequestTimerKey(req *http.Request) string {
+	return fmt.Sprintf("request_time_%p", req)
+}
+
+// Paths contain usernames and hashes, so they are replaced with the templates of the matched routes
+// to keep the number of the label values limited
+func routeLabel(req *http.Request) string {
+	if info := logging.RequestInfoFromContext(req.Context()); info != nil && info.Route != "" {
+		return info.Route
+	}
+
+	return "other"
+}
+
+func nilResultLabel(isNil bool) string {
+	if isNil {
+		return "hit_nil"
+	}
+
+	return "hit"
+}
+
+func callResultLabel(isNil bool, err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	if isNil {
+		return "miss"
+	}
+
+	return "hit"
+}
@@ -0,0 +1,241 @@
+package eventsubscribers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/logging"
+)
+
+type prometheusReporterTestCase struct {
+	Name     string
+	Events   [][]interface{}
+	Metric   string
+	Expected string
+}
+
+func createRoutedRequest(method string, url string, route string) *http.Request {
+	req := httptest.NewRequest(method, url, nil)
+
+	return req.WithContext(logging.WithRequestInfo(req.Context(), &logging.RequestInfo{Route: route}))
+}
+
+var prometheusReporterTestCases = []*prometheusReporterTestCase{
+	{
+		Name: "should count requests by the route template",
+		Events: [][]interface{}{
+			{"skinsystem:before_request", createRoutedRequest("GET", "http://localhost/skins/username", "/skins/{username}")},
+			{"skinsystem:after_request", createRoutedRequest("GET", "http://localhost/skins/username", "/skins/{username}"), 301},
+			{"skinsystem:after_request", createRoutedRequest("GET", "http://localhost/skins/otherUsername", "/skins/{username}"), 301},
+			{"skinsystem:after_request", createRoutedRequest("DELETE", "http://localhost/api/skins/id:1", "/api/skins/id:{id:[0-9]+}"), 204},
+			{"skinsystem:after_request", createRoutedRequest("GET", "http://localhost/not/exists", ""), 404},
+			{"skinsystem:after_request", httptest.NewRequest("GET", "http://localhost/without/info", nil), 500},
+		},
+		Metric: "chrly_http_requests_total",
+		Expected: `
+			# HELP chrly_http_requests_total Number of the handled HTTP requests.
+			# TYPE chrly_http_requests_total counter
+			chrly_http_requests_total{method="DELETE",route="/api/skins/id:{id:[0-9]+}",status="204"} 1
+			chrly_http_requests_total{method="GET",route="/skins/{username}",status="301"} 2
+			chrly_http_requests_total{method="GET",route="other",status="404"} 1
+			chrly_http_requests_total{method="GET",route="other",status="500"} 1
+		`,
+	},
+	{
+		Name: "should count authentication results",
+		Events: [][]interface{}{
+			{"authentication:success"},
+			{"authentication:error", errors.New("error")},
+			{"authentication:error", errors.New("error")},
+		},
+		Metric: "chrly_authentication_total",
+		Expected: `
+			# HELP chrly_authentication_total Number of the authentication attempts by their result.
+			# TYPE chrly_authentication_total counter
+			chrly_authentication_total{result="failed"} 2
+			chrly_authentication_total{result="success"} 1
+		`,
+	},
+	{
+		Name: "should count cache hits",
+		Events: [][]interface{}{
+			{"mojang_textures:usernames:after_cache", "username", "", true, nil},
+			{"mojang_textures:usernames:after_cache", "username", "uuid", true, nil},
+			{"mojang_textures:usernames:after_cache", "username", "", false, nil},
+			{"mojang_textures:textures:after_cache", "uuid", &mojang.SignedTexturesResponse{}, true, nil},
+			{"mojang_textures:textures:after_cache", "uuid", nil, true, errors.New("error")},
+		},
+		Metric: "chrly_mojang_textures_cache_hits_total",
+		Expected: `
+			# HELP chrly_mojang_textures_cache_hits_total Number of the Mojang's UUIDs and textures found in the cache.
+			# TYPE chrly_mojang_textures_cache_hits_total counter
+			chrly_mojang_textures_cache_hits_total{cache="textures",result="hit"} 1
+			chrly_mojang_textures_cache_hits_total{cache="usernames",result="hit"} 1
+			chrly_mojang_textures_cache_hits_total{cache="usernames",result="hit_nil"} 1
+		`,
+	},
+	{
+		Name: "should count provider calls",
+		Events: [][]interface{}{
+			{"mojang_textures:usernames:after_call", "username", &mojang.ProfileInfo{}, nil},
+			{"mojang_textures:usernames:after_call", "username", nil, nil},
+			{"mojang_textures:textures:after_call", "uuid", nil, errors.New("error")},
+		},
+		Metric: "chrly_mojang_textures_provider_calls_total",
+		Expected: `
+			# HELP chrly_mojang_textures_provider_calls_total Number of the Mojang's API calls by the provider and their result.
+			# TYPE chrly_mojang_textures_provider_calls_total counter
+			chrly_mojang_textures_provider_calls_total{provider="textures",result="error"} 1
+			chrly_mojang_textures_provider_calls_total{provider="usernames",result="hit"} 1
+			chrly_mojang_textures_provider_calls_total{provider="usernames",result="miss"} 1
+		`,
+	},
+	{
+		Name: "should count textures sources calls by the provider name",
+		Events: [][]interface{}{
+			{"mojang_textures:sources:after_call", "elyby", "username", &mojang.SignedTexturesResponse{}, nil},
+			{"mojang_textures:sources:after_call", "mojang", "username", nil, nil},
+		},
+		Metric: "chrly_textures_sources_calls_total",
+		Expected: `
+			# HELP chrly_textures_sources_calls_total Number of the textures sources calls by their result.
+			# TYPE chrly_textures_sources_calls_total counter
+			chrly_textures_sources_calls_total{provider="elyby",result="hit"} 1
+			chrly_textures_sources_calls_total{provider="mojang",result="miss"} 1
+		`,
+	},
+	{
+		Name: "should track the circuit breaker state",
+		Events: [][]interface{}{
+			{"mojang_textures:circuit_breaker:state_changed", "usernames", "open"},
+			{"mojang_textures:circuit_breaker:state_changed", "textures", "open"},
+			{"mojang_textures:circuit_breaker:state_changed", "textures", "half-open"},
+		},
+		Metric: "chrly_mojang_textures_circuit_breaker_open",
+		Expected: `
+			# HELP chrly_mojang_textures_circuit_breaker_open Whether the circuit breaker is open (1) or not (0).
+			# TYPE chrly_mojang_textures_circuit_breaker_open gauge
+			chrly_mojang_textures_circuit_breaker_open{provider="textures"} 0
+			chrly_mojang_textures_circuit_breaker_open{provider="usernames"} 1
+		`,
+	},
+	{
+		Name: "should count outbound requests",
+		Events: [][]interface{}{
+			{"mojang_textures:outbound_pool:request", "127_0_0_1"},
+			{"mojang_textures:outbound_pool:request", "127_0_0_1"},
+		},
+		Metric: "chrly_mojang_textures_outbound_requests_total",
+		Expected: `
+			# HELP chrly_mojang_textures_outbound_requests_total Number of the session server requests performed through the outbound endpoint.
+			# TYPE chrly_mojang_textures_outbound_requests_total counter
+			chrly_mojang_textures_outbound_requests_total{endpoint="127_0_0_1"} 2
+		`,
+	},
+	{
+		Name: "should update the usernames queue size",
+		Events: [][]interface{}{
+			{"mojang_textures:batch_uuids_provider:round", []string{"username1", "username2"}, 5},
+		},
+		Metric: "chrly_mojang_textures_usernames_queue_size",
+		Expected: `
+			# HELP chrly_mojang_textures_usernames_queue_size Number of the usernames waiting in the batch UUIDs provider's queue.
+			# TYPE chrly_mojang_textures_usernames_queue_size gauge
+			chrly_mojang_textures_usernames_queue_size 5
+		`,
+	},
+	{
+		Name: "should count webhooks deliveries",
+		Events: [][]interface{}{
+			{"webhooks:failed", "http://localhost/hook", "skin.saved", 1, errors.New("error")},
+			{"webhooks:delivered", "http://localhost/hook", "skin.saved", 2},
+			{"webhooks:dead_lettered", "http://localhost/hook", "cape.removed", errors.New("error")},
+		},
+		Metric: "chrly_webhooks_deliveries_total",
+		Expected: `
+			# HELP chrly_webhooks_deliveries_total Number of the webhooks delivery attempts by the event and their result.
+			# TYPE chrly_webhooks_deliveries_total counter
+			chrly_webhooks_deliveries_total{event="cape.removed",result="dead_lettered"} 1
+			chrly_webhooks_deliveries_total{event="skin.saved",result="delivered"} 1
+			chrly_webhooks_deliveries_total{event="skin.saved",result="failed"} 1
+		`,
+	},
+}
+
+func TestPrometheusReporter(t *testing.T) {
+	for _, c := range prometheusReporterTestCases {
+		t.Run(c.Name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			reporter := &PrometheusReporter{Registerer: registry}
+
+			d := dispatcher.New()
+			reporter.ConfigureWithDispatcher(d)
+			for _, e := range c.Events {
+				eventName, _ := e[0].(string)
+				d.Emit(eventName, e[1:]...)
+			}
+
+			err := testutil.GatherAndCompare(registry, strings.NewReader(c.Expected), c.Metric)
+			require.Nil(t, err)
+		})
+	}
+
+	t.Run("should observe the request duration", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		reporter := &PrometheusReporter{Registerer: registry}
+
+		d := dispatcher.New()
+		reporter.ConfigureWithDispatcher(d)
+
+		req := httptest.NewRequest("GET", "http://localhost/textures/signed/username", nil)
+		d.Emit("skinsystem:before_request", req)
+		d.Emit("skinsystem:after_request", req, 200)
+		// The request without the before event must not be observed
+		d.Emit("skinsystem:after_request", httptest.NewRequest("GET", "http://localhost/textures/signed/username", nil), 200)
+
+		requireHistogramSampleCount(t, registry, "chrly_http_request_duration_seconds", 1)
+	})
+
+	t.Run("should observe the Mojang's textures result time", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		reporter := &PrometheusReporter{Registerer: registry}
+
+		d := dispatcher.New()
+		reporter.ConfigureWithDispatcher(d)
+
+		d.Emit("mojang_textures:before_result", "username", "")
+		d.Emit("mojang_textures:after_result", "username", &mojang.SignedTexturesResponse{}, nil)
+
+		requireHistogramSampleCount(t, registry, "chrly_mojang_textures_result_duration_seconds", 1)
+	})
+}
+
+func requireHistogramSampleCount(t *testing.T, registry *prometheus.Registry, name string, expected uint64) {
+	families, err := registry.Gather()
+	require.Nil(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		var count uint64
+		for _, metric := range family.GetMetric() {
+			count += metric.GetHistogram().GetSampleCount()
+		}
+
+		require.Equal(t, expected, count)
+
+		return
+	}
+
+	t.Fatalf("metric %s not found", name)
+}
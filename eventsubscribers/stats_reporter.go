@@ -57,16 +57,22 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 			s.IncCounter("mojang_textures.usernames.cache_hit", 1)
 		}
 	})
-	d.Subscribe("mojang_textures:textures:after_cache", func(uuid string, textures *mojang.SignedTexturesResponse, err error) {
-		if err != nil {
+	d.Subscribe("mojang_textures:textures:after_cache", func(uuid string, textures *mojang.SignedTexturesResponse, found bool, err error) {
+		if err != nil || !found {
 			return
 		}
 
-		if textures != nil {
+		if textures == nil {
+			s.IncCounter("mojang_textures.textures.cache_hit_nil", 1)
+		} else {
 			s.IncCounter("mojang_textures.textures.cache_hit", 1)
 		}
 	})
 	d.Subscribe("mojang_textures:already_processing", s.incCounterHandler("mojang_textures.already_scheduled"))
+	d.Subscribe("mojang_textures:textures:evicted", s.incCounterHandler("mojang_textures.textures.evicted"))
+	d.Subscribe("mojang_textures:textures:stale", s.incCounterHandler("mojang_textures.textures.stale_hit"))
+	d.Subscribe("mojang_textures:refresh:scheduled", s.incCounterHandler("mojang_textures.refresh.scheduled"))
+	d.Subscribe("mojang_textures:refresh:budget_exceeded", s.incCounterHandler("mojang_textures.refresh.budget_exceeded"))
 	d.Subscribe("mojang_textures:usernames:after_call", func(username string, profile *mojang.ProfileInfo, err error) {
 		if err != nil {
 			return
@@ -79,6 +85,8 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 		}
 	})
 	d.Subscribe("mojang_textures:textures:before_call", s.incCounterHandler("mojang_textures.textures.request"))
+	d.Subscribe("mojang_textures:usernames:retry", s.incCounterHandler("mojang_textures.usernames.retry"))
+	d.Subscribe("mojang_textures:textures:retry", s.incCounterHandler("mojang_textures.textures.retry"))
 	d.Subscribe("mojang_textures:textures:after_call", func(uuid string, textures *mojang.SignedTexturesResponse, err error) {
 		if err != nil {
 			return
@@ -103,8 +111,34 @@ func (s *StatsReporter) ConfigureWithDispatcher(d Subscriber) {
 		s.finalizeTimeRecording("mojang_textures_provider_time_"+uuid, "mojang_textures.textures.request_time")
 	})
 
+	// Textures sources chain metrics
+	d.Subscribe("mojang_textures:sources:before_call", func(source string, username string) {
+		s.IncCounter("textures_sources."+source+".request", 1)
+	})
+	d.Subscribe("mojang_textures:sources:after_call", func(source string, username string, textures *mojang.SignedTexturesResponse, err error) {
+		if err != nil {
+			s.IncCounter("textures_sources."+source+".error", 1)
+		} else if textures == nil {
+			s.IncCounter("textures_sources."+source+".miss", 1)
+		} else {
+			s.IncCounter("textures_sources."+source+".hit", 1)
+		}
+	})
+
+	// Mojang session server outbound pool metrics
+	d.Subscribe("mojang_textures:outbound_pool:request", func(endpoint string) {
+		s.IncCounter("mojang_textures.outbound."+endpoint+".request", 1)
+	})
+	d.Subscribe("mojang_textures:outbound_pool:unhealthy", func(endpoint string, healthyCount int) {
+		s.IncCounter("mojang_textures.outbound."+endpoint+".unhealthy", 1)
+		s.UpdateGauge("mojang_textures.outbound.healthy_endpoints", int64(healthyCount))
+	})
+
 	// Mojang UUIDs batch provider metrics
 	d.Subscribe("mojang_textures:batch_uuids_provider:queued", s.incCounterHandler("mojang_textures.usernames.queued"))
+	d.Subscribe("mojang_textures:batch_uuids_provider:requeued", func(usernames []string) {
+		s.IncCounter("mojang_textures.usernames.requeued", int64(len(usernames)))
+	})
 	d.Subscribe("mojang_textures:batch_uuids_provider:round", func(usernames []string, queueSize int) {
 		s.UpdateGauge("mojang_textures.usernames.iteration_size", int64(len(usernames)))
 		s.UpdateGauge("mojang_textures.usernames.queue_size", int64(queueSize))
@@ -137,6 +171,10 @@ func (s *StatsReporter) handleBeforeRequest(req *http.Request) {
 		key = "api.skins.post.request"
 	} else if m == http.MethodDelete && strings.HasPrefix(p, "/api/skins/") {
 		key = "api.skins.delete.request"
+	} else if m == http.MethodPost && p == "/api/capes" {
+		key = "api.capes.post.request"
+	} else if m == http.MethodDelete && strings.HasPrefix(p, "/api/capes/") {
+		key = "api.capes.delete.request"
 	} else {
 		return
 	}
@@ -156,6 +194,14 @@ func (s *StatsReporter) handleAfterRequest(req *http.Request, code int) {
 		key = "api.skins.delete.success"
 	} else if m == http.MethodDelete && strings.HasPrefix(p, "/api/skins/") && code == http.StatusNotFound {
 		key = "api.skins.delete.not_found"
+	} else if m == http.MethodPost && p == "/api/capes" && code == http.StatusCreated {
+		key = "api.capes.post.success"
+	} else if m == http.MethodPost && p == "/api/capes" && code == http.StatusBadRequest {
+		key = "api.capes.post.validation_failed"
+	} else if m == http.MethodDelete && strings.HasPrefix(p, "/api/capes/") && code == http.StatusNoContent {
+		key = "api.capes.delete.success"
+	} else if m == http.MethodDelete && strings.HasPrefix(p, "/api/capes/") && code == http.StatusNotFound {
+		key = "api.capes.delete.not_found"
 	} else {
 		return
 	}
@@ -123,6 +123,22 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 			{"IncCounter", "api.skins.delete.request", int64(1)},
 		},
 	},
+	{
+		Events: [][]interface{}{
+			{"skinsystem:before_request", httptest.NewRequest("POST", "http://localhost/api/capes", nil)},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "api.capes.post.request", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"skinsystem:before_request", httptest.NewRequest("DELETE", "http://localhost/api/capes/username", nil)},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "api.capes.delete.request", int64(1)},
+		},
+	},
 	{
 		Events: [][]interface{}{
 			{"skinsystem:before_request", httptest.NewRequest("GET", "http://localhost/unknown", nil)},
@@ -178,6 +194,38 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 			{"IncCounter", "api.skins.delete.not_found", int64(1)},
 		},
 	},
+	{
+		Events: [][]interface{}{
+			{"skinsystem:after_request", httptest.NewRequest("POST", "http://localhost/api/capes", nil), 201},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "api.capes.post.success", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"skinsystem:after_request", httptest.NewRequest("POST", "http://localhost/api/capes", nil), 400},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "api.capes.post.validation_failed", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/capes/id:1", nil), 204},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "api.capes.delete.success", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/api/capes/username", nil), 404},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "api.capes.delete.not_found", int64(1)},
+		},
+	},
 	{
 		Events: [][]interface{}{
 			{"skinsystem:after_request", httptest.NewRequest("DELETE", "http://localhost/unknown", nil), 404},
@@ -242,19 +290,27 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, errors.New("error")},
+			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, false, errors.New("error")},
 		},
 		ExpectedCalls: [][]interface{}{},
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, nil},
+			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, false, nil},
 		},
 		ExpectedCalls: [][]interface{}{},
 	},
 	{
 		Events: [][]interface{}{
-			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", &mojang.SignedTexturesResponse{}, nil},
+			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil, true, nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.textures.cache_hit_nil", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", &mojang.SignedTexturesResponse{}, true, nil},
 		},
 		ExpectedCalls: [][]interface{}{
 			{"IncCounter", "mojang_textures.textures.cache_hit", int64(1)},
@@ -268,6 +324,38 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 			{"IncCounter", "mojang_textures.already_scheduled", int64(1)},
 		},
 	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:textures:evicted", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.textures.evicted", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:textures:stale", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", &mojang.SignedTexturesResponse{}},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.textures.stale_hit", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:refresh:scheduled", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.refresh.scheduled", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:refresh:budget_exceeded", "username"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.refresh.budget_exceeded", int64(1)},
+		},
+	},
 	{
 		Events: [][]interface{}{
 			{"mojang_textures:usernames:after_call", "username", nil, errors.New("error")},
@@ -341,6 +429,79 @@ var statsReporterTestCases = []*StatsReporterTestCase{
 			{"IncCounter", "mojang_textures.usernames.queued", int64(1)},
 		},
 	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:batch_uuids_provider:requeued", []string{"username1", "username2"}},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.usernames.requeued", int64(2)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:usernames:retry", "username", 1, &mojang.ServerError{Status: 500}},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.usernames.retry", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:textures:retry", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", 1, &mojang.ServerError{Status: 500}},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.textures.retry", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:sources:before_call", "elyby", "username"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "textures_sources.elyby.request", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:sources:after_call", "elyby", "username", &mojang.SignedTexturesResponse{}, nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "textures_sources.elyby.hit", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:sources:after_call", "elyby", "username", nil, nil},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "textures_sources.elyby.miss", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:sources:after_call", "elyby", "username", nil, errors.New("error")},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "textures_sources.elyby.error", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:outbound_pool:request", "127_0_0_1"},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.outbound.127_0_0_1.request", int64(1)},
+		},
+	},
+	{
+		Events: [][]interface{}{
+			{"mojang_textures:outbound_pool:unhealthy", "127_0_0_1", 2},
+		},
+		ExpectedCalls: [][]interface{}{
+			{"IncCounter", "mojang_textures.outbound.127_0_0_1.unhealthy", int64(1)},
+			{"UpdateGauge", "mojang_textures.outbound.healthy_endpoints", int64(2)},
+		},
+	},
 	{
 		Events: [][]interface{}{
 			{"mojang_textures:batch_uuids_provider:round", []string{"username1", "username2"}, 5},
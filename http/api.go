@@ -1,11 +1,19 @@
 package http
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/thedevsaddam/govalidator"
@@ -19,12 +27,57 @@ const UUID_ANY = "^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$
 var regexUuidAny = regexp.MustCompile(UUID_ANY)
 
 func init() {
-	govalidator.AddCustomRule("skinUploadingNotAvailable", func(field string, rule string, message string, value interface{}) error {
-		if message == "" {
-			message = "Skin uploading is temporary unavailable"
+	govalidator.AddCustomRule("skin_dimensions", func(field string, rule string, message string, value interface{}) error {
+		file, ok := value.(multipart.File)
+		if !ok {
+			return nil
 		}
 
-		return errors.New(message)
+		config, err := png.DecodeConfig(file)
+		_, _ = file.Seek(0, io.SeekStart)
+		if err != nil || !isValidSkinDimensions(config.Width, config.Height) {
+			if message == "" {
+				message = fmt.Sprintf("The %s field must be a 64x32 or 64x64 PNG image", field)
+			}
+
+			return errors.New(message)
+		}
+
+		return nil
+	})
+
+	govalidator.AddCustomRule("cape_dimensions", func(field string, rule string, message string, value interface{}) error {
+		file, ok := value.(multipart.File)
+		if !ok {
+			return nil
+		}
+
+		config, err := png.DecodeConfig(file)
+		_, _ = file.Seek(0, io.SeekStart)
+		if err != nil || !isValidCapeDimensions(config.Width, config.Height) {
+			if message == "" {
+				message = fmt.Sprintf("The %s field must be a PNG image with 2:1 ratio and width multiple of 64", field)
+			}
+
+			return errors.New(message)
+		}
+
+		return nil
+	})
+
+	// Usernames are used as the files names by the filesystem storages,
+	// so they mustn't contain anything that can change the path
+	govalidator.AddCustomRule("username", func(field string, rule string, message string, value interface{}) error {
+		str := value.(string)
+		if strings.ContainsAny(str, "/\\\x00") || strings.Contains(str, "..") || str == "." {
+			if message == "" {
+				message = fmt.Sprintf("The %s field must contain valid username", field)
+			}
+
+			return errors.New(message)
+		}
+
+		return nil
 	})
 
 	// Add ability to validate any possible uuid form
@@ -44,18 +97,31 @@ func init() {
 
 type Api struct {
 	Emitter
-	SkinsRepo SkinsRepository
+	Authenticator
+	SkinsRepo      SkinsRepository
+	SkinsFilesRepo SkinsFilesRepository
+	CapesRepo      CapesRepository
+	// PublicUrl is used to build links to the uploaded skins files.
+	// When it's empty, the host of the current request will be used
+	PublicUrl string
 }
 
 func (ctx *Api) Handler() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
-	router.HandleFunc("/skins", ctx.postSkinHandler).Methods(http.MethodPost)
-	router.HandleFunc("/skins/id:{id:[0-9]+}", ctx.deleteSkinByUserIdHandler).Methods(http.MethodDelete)
-	router.HandleFunc("/skins/{username}", ctx.deleteSkinByUsernameHandler).Methods(http.MethodDelete)
+	router.Handle("/skins", ctx.withScope(SkinsWriteScope, ctx.postSkinHandler)).Methods(http.MethodPost)
+	router.Handle("/skins/id:{id:[0-9]+}", ctx.withScope(SkinsDeleteScope, ctx.deleteSkinByUserIdHandler)).Methods(http.MethodDelete)
+	router.Handle("/skins/{username}", ctx.withScope(SkinsDeleteScope, ctx.deleteSkinByUsernameHandler)).Methods(http.MethodDelete)
+	router.Handle("/capes", ctx.withScope(CapesWriteScope, ctx.postCapeHandler)).Methods(http.MethodPost)
+	router.Handle("/capes/id:{id:[0-9]+}", ctx.withScope(CapesDeleteScope, ctx.deleteCapeByUserIdHandler)).Methods(http.MethodDelete)
+	router.Handle("/capes/{username}", ctx.withScope(CapesDeleteScope, ctx.deleteCapeByUsernameHandler)).Methods(http.MethodDelete)
 
 	return router
 }
 
+func (ctx *Api) withScope(scope Scope, handler http.HandlerFunc) http.Handler {
+	return CreateAuthenticationMiddleware(ctx.Authenticator, scope)(handler)
+}
+
 func (ctx *Api) postSkinHandler(resp http.ResponseWriter, req *http.Request) {
 	validationErrors := validatePostSkinRequest(req)
 	if validationErrors != nil {
@@ -89,6 +155,17 @@ func (ctx *Api) postSkinHandler(resp http.ResponseWriter, req *http.Request) {
 	record.Is1_8 = is18
 	record.IsSlim = isSlim
 	record.Url = req.Form.Get("url")
+	record.SkinHash = ""
+	if skinFile, _, err := req.FormFile("skin"); err == nil {
+		err = ctx.storeSkinFile(record, skinFile, req)
+		_ = skinFile.Close()
+		if err != nil {
+			ctx.Emit("skinsystem:error", fmt.Errorf("unable to save skin file to the repository: %w", err))
+			apiServerError(resp)
+			return
+		}
+	}
+
 	record.MojangTextures = req.Form.Get("mojangTextures")
 	record.MojangSignature = req.Form.Get("mojangSignature")
 
@@ -99,9 +176,43 @@ func (ctx *Api) postSkinHandler(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	ctx.Emit("skinsystem:skin:saved", record)
+
 	resp.WriteHeader(http.StatusCreated)
 }
 
+func (ctx *Api) storeSkinFile(record *model.Skin, file io.Reader, req *http.Request) error {
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	hashBytes := sha256.Sum256(data)
+	hash := hex.EncodeToString(hashBytes[:])
+	err = ctx.SkinsFilesRepo.SaveSkinFile(hash, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	baseUrl := ctx.PublicUrl
+	if baseUrl == "" {
+		// Use statically http since the application doesn't support TLS
+		baseUrl = "http://" + req.Host
+	}
+
+	record.Url = strings.TrimSuffix(baseUrl, "/") + "/files/skins/" + hash + ".png"
+	record.SkinHash = hash
+	// The skin format can be determined from the file itself
+	record.Is1_8 = config.Height == 64
+
+	return nil
+}
+
 func (ctx *Api) deleteSkinByUserIdHandler(resp http.ResponseWriter, req *http.Request) {
 	id, _ := strconv.Atoi(mux.Vars(req)["id"])
 	skin, err := ctx.SkinsRepo.FindSkinByUserId(id)
@@ -133,6 +244,89 @@ func (ctx *Api) deleteSkin(skin *model.Skin, err error, resp http.ResponseWriter
 		return
 	}
 
+	ctx.Emit("skinsystem:skin:removed", skin)
+
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (ctx *Api) postCapeHandler(resp http.ResponseWriter, req *http.Request) {
+	validationErrors := validatePostCapeRequest(req)
+	if validationErrors != nil {
+		apiBadRequest(resp, validationErrors)
+		return
+	}
+
+	file, _, err := req.FormFile("cape")
+	if err != nil {
+		apiBadRequest(resp, map[string][]string{
+			"cape": {"The cape field is required"},
+		})
+		return
+	}
+	defer file.Close()
+
+	err = ctx.CapesRepo.SaveCape(&model.Cape{
+		Username: req.Form.Get("username"),
+		File:     file,
+	})
+	if err != nil {
+		ctx.Emit("skinsystem:error", fmt.Errorf("unable to save cape to the repository: %w", err))
+		apiServerError(resp)
+		return
+	}
+
+	ctx.Emit("skinsystem:cape:saved", req.Form.Get("username"))
+
+	resp.WriteHeader(http.StatusCreated)
+}
+
+func (ctx *Api) deleteCapeByUserIdHandler(resp http.ResponseWriter, req *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(req)["id"])
+	skin, err := ctx.SkinsRepo.FindSkinByUserId(id)
+	if err != nil {
+		ctx.Emit("skinsystem:error", fmt.Errorf("unable to find skin info from the repository: %w", err))
+		apiServerError(resp)
+		return
+	}
+
+	if skin == nil {
+		apiNotFound(resp, "Cannot find record for the requested identifier")
+		return
+	}
+
+	ctx.deleteCape(skin.Username, resp)
+}
+
+func (ctx *Api) deleteCapeByUsernameHandler(resp http.ResponseWriter, req *http.Request) {
+	ctx.deleteCape(mux.Vars(req)["username"], resp)
+}
+
+func (ctx *Api) deleteCape(username string, resp http.ResponseWriter) {
+	cape, err := ctx.CapesRepo.FindCapeByUsername(username)
+	if err != nil {
+		ctx.Emit("skinsystem:error", fmt.Errorf("unable to find cape from the repository: %w", err))
+		apiServerError(resp)
+		return
+	}
+
+	if cape == nil {
+		apiNotFound(resp, "Cannot find cape for the requested identifier")
+		return
+	}
+
+	if closer, ok := cape.File.(io.Closer); ok {
+		_ = closer.Close()
+	}
+
+	err = ctx.CapesRepo.RemoveCapeByUsername(username)
+	if err != nil {
+		ctx.Emit("skinsystem:error", fmt.Errorf("cannot delete cape by error: %w", err))
+		apiServerError(resp)
+		return
+	}
+
+	ctx.Emit("skinsystem:cape:removed", username)
+
 	resp.WriteHeader(http.StatusNoContent)
 }
 
@@ -179,7 +373,7 @@ func validatePostSkinRequest(request *http.Request) map[string][]string {
 
 	validationRules := govalidator.MapData{
 		"identityId": {"required", "numeric", "min:1"},
-		"username":   {"required"},
+		"username":   {"required", "username"},
 		"uuid":       {"required", "uuid_any"},
 		"skinId":     {"required", "numeric", "min:1"},
 		"url":        {"url"},
@@ -194,7 +388,7 @@ func validatePostSkinRequest(request *http.Request) map[string][]string {
 	if (url != "" && skinErr == nil) || (url == "" && skinErr != nil) {
 		shouldAppendSkinRequiredError = true
 	} else if skinErr == nil {
-		validationRules["file:skin"] = append(validationRules["file:skin"], "skinUploadingNotAvailable")
+		validationRules["file:skin"] = append(validationRules["file:skin"], "skin_dimensions")
 	} else if url != "" {
 		validationRules["is1_8"] = append(validationRules["is1_8"], "required")
 		validationRules["isSlim"] = append(validationRules["isSlim"], "required")
@@ -223,3 +417,33 @@ func validatePostSkinRequest(request *http.Request) map[string][]string {
 
 	return nil
 }
+
+func validatePostCapeRequest(request *http.Request) map[string][]string {
+	const maxMultipartMemory int64 = 32 << 20
+
+	_ = request.ParseMultipartForm(maxMultipartMemory)
+
+	validator := govalidator.New(govalidator.Options{
+		Request: request,
+		Rules: govalidator.MapData{
+			"username":  {"required", "username"},
+			"file:cape": {"required", "ext:png", "size:262144", "mime:image/png", "cape_dimensions"},
+		},
+		RequiredDefault: false,
+		FormSize:        maxMultipartMemory,
+	})
+	validationResults := validator.Validate()
+	if len(validationResults) != 0 {
+		return validationResults
+	}
+
+	return nil
+}
+
+func isValidSkinDimensions(width int, height int) bool {
+	return width == 64 && (height == 32 || height == 64)
+}
+
+func isValidCapeDimensions(width int, height int) bool {
+	return width != 0 && width%64 == 0 && width == height*2
+}
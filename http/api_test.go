@@ -2,7 +2,9 @@ package http
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"io"
 	"io/ioutil"
@@ -27,8 +29,11 @@ type apiTestSuite struct {
 
 	App *Api
 
-	SkinsRepository *skinsRepositoryMock
-	Emitter         *emitterMock
+	SkinsRepository      *skinsRepositoryMock
+	SkinsFilesRepository *skinsFilesRepositoryMock
+	CapesRepository      *capesRepositoryMock
+	Emitter              *emitterMock
+	Authenticator        *authCheckerMock
 }
 
 /********************
@@ -37,16 +42,26 @@ type apiTestSuite struct {
 
 func (suite *apiTestSuite) SetupTest() {
 	suite.SkinsRepository = &skinsRepositoryMock{}
+	suite.SkinsFilesRepository = &skinsFilesRepositoryMock{}
+	suite.CapesRepository = &capesRepositoryMock{}
 	suite.Emitter = &emitterMock{}
+	suite.Authenticator = &authCheckerMock{}
+	// Scopes of the endpoints are checked by the separate test
+	suite.Authenticator.On("Authenticate", mock.Anything, mock.Anything).Return(nil)
 
 	suite.App = &Api{
-		SkinsRepo: suite.SkinsRepository,
-		Emitter:   suite.Emitter,
+		Authenticator:  suite.Authenticator,
+		SkinsRepo:      suite.SkinsRepository,
+		SkinsFilesRepo: suite.SkinsFilesRepository,
+		CapesRepo:      suite.CapesRepository,
+		Emitter:        suite.Emitter,
 	}
 }
 
 func (suite *apiTestSuite) TearDownTest() {
 	suite.SkinsRepository.AssertExpectations(suite.T())
+	suite.SkinsFilesRepository.AssertExpectations(suite.T())
+	suite.CapesRepository.AssertExpectations(suite.T())
 	suite.Emitter.AssertExpectations(suite.T())
 }
 
@@ -64,6 +79,35 @@ func TestApi(t *testing.T) {
 	suite.Run(t, new(apiTestSuite))
 }
 
+func (suite *apiTestSuite) TestEndpointsScopes() {
+	for _, testCase := range []struct {
+		Method string
+		Url    string
+		Scope  Scope
+	}{
+		{"POST", "http://chrly/skins", SkinsWriteScope},
+		{"DELETE", "http://chrly/skins/id:1", SkinsDeleteScope},
+		{"DELETE", "http://chrly/skins/mock_username", SkinsDeleteScope},
+		{"POST", "http://chrly/capes", CapesWriteScope},
+		{"DELETE", "http://chrly/capes/id:1", CapesDeleteScope},
+		{"DELETE", "http://chrly/capes/mock_username", CapesDeleteScope},
+	} {
+		suite.RunSubTest(testCase.Method+" "+testCase.Url, func() {
+			authenticator := &authCheckerMock{}
+			authenticator.On("Authenticate", mock.Anything, testCase.Scope).Once().Return(errors.New("forbidden"))
+			suite.App.Authenticator = authenticator
+
+			req := httptest.NewRequest(testCase.Method, testCase.Url, nil)
+			w := httptest.NewRecorder()
+
+			suite.App.Handler().ServeHTTP(w, req)
+
+			suite.Equal(403, w.Result().StatusCode)
+			authenticator.AssertExpectations(suite.T())
+		})
+	}
+}
+
 /*************************
  * Post skin tests cases *
  *************************/
@@ -88,6 +132,7 @@ var postSkinTestsCases = []*postSkinTestCase{
 			"url":        {"http://example.com/skin.png"},
 		}.Encode()),
 		BeforeTest: func(suite *apiTestSuite) {
+			suite.Emitter.On("Emit", "skinsystem:skin:saved", mock.AnythingOfType("*model.Skin")).Once()
 			suite.SkinsRepository.On("FindSkinByUserId", 1).Return(nil, nil)
 			suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(nil, nil)
 			suite.SkinsRepository.On("SaveSkin", mock.MatchedBy(func(model *model.Skin) bool {
@@ -120,6 +165,7 @@ var postSkinTestsCases = []*postSkinTestCase{
 			"url":        {"http://textures-server.com/skin.png"},
 		}.Encode()),
 		BeforeTest: func(suite *apiTestSuite) {
+			suite.Emitter.On("Emit", "skinsystem:skin:saved", mock.AnythingOfType("*model.Skin")).Once()
 			suite.SkinsRepository.On("FindSkinByUserId", 1).Return(createSkinModel("mock_username", false), nil)
 			suite.SkinsRepository.On("SaveSkin", mock.MatchedBy(func(model *model.Skin) bool {
 				suite.Equal(1, model.UserId)
@@ -151,6 +197,7 @@ var postSkinTestsCases = []*postSkinTestCase{
 			"url":        {"http://example.com/skin.png"},
 		}.Encode()),
 		BeforeTest: func(suite *apiTestSuite) {
+			suite.Emitter.On("Emit", "skinsystem:skin:saved", mock.AnythingOfType("*model.Skin")).Once()
 			suite.SkinsRepository.On("FindSkinByUserId", 2).Return(nil, nil)
 			suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
 			suite.SkinsRepository.On("RemoveSkinByUsername", "mock_username").Times(1).Return(nil)
@@ -180,6 +227,7 @@ var postSkinTestsCases = []*postSkinTestCase{
 			"url":        {"http://example.com/skin.png"},
 		}.Encode()),
 		BeforeTest: func(suite *apiTestSuite) {
+			suite.Emitter.On("Emit", "skinsystem:skin:saved", mock.AnythingOfType("*model.Skin")).Once()
 			suite.SkinsRepository.On("FindSkinByUserId", 1).Return(createSkinModel("mock_username", false), nil)
 			suite.SkinsRepository.On("RemoveSkinByUserId", 1).Times(1).Return(nil)
 			suite.SkinsRepository.On("SaveSkin", mock.MatchedBy(func(model *model.Skin) bool {
@@ -310,24 +358,83 @@ func (suite *apiTestSuite) TestPostSkin() {
 	})
 
 	suite.RunSubTest("Upload textures with skin as file", func() {
-		inputBody := &bytes.Buffer{}
-		writer := multipart.NewWriter(inputBody)
+		suite.Emitter.On("Emit", "skinsystem:skin:saved", mock.AnythingOfType("*model.Skin")).Once()
+		skinHash := createSkinHash()
+		suite.SkinsRepository.On("FindSkinByUserId", 1).Return(nil, nil)
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_user").Return(nil, nil)
+		suite.SkinsFilesRepository.On("SaveSkinFile", skinHash, mock.Anything).Once().Run(func(args mock.Arguments) {
+			data, _ := ioutil.ReadAll(args.Get(1).(io.Reader))
+			suite.Equal(createSkin(), data)
+		}).Return(nil)
+		suite.SkinsRepository.On("SaveSkin", mock.MatchedBy(func(model *model.Skin) bool {
+			suite.Equal(1, model.UserId)
+			suite.Equal("mock_user", model.Username)
+			suite.Equal(5, model.SkinId)
+			suite.True(model.Is1_8)
+			suite.False(model.IsSlim)
+			suite.Equal("http://chrly/files/skins/"+skinHash+".png", model.Url)
+			suite.Equal(skinHash, model.SkinHash)
+
+			return true
+		})).Once().Return(nil)
+
+		req := createSkinUploadRequest(createSkin())
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(201, resp.StatusCode)
+		responseBody, _ := ioutil.ReadAll(resp.Body)
+		suite.Empty(responseBody)
+	})
+
+	suite.RunSubTest("Upload textures with skin as file and the public url set", func() {
+		suite.Emitter.On("Emit", "skinsystem:skin:saved", mock.AnythingOfType("*model.Skin")).Once()
+		suite.App.PublicUrl = "https://skins.example.com/"
+		skinHash := createSkinHash()
+		suite.SkinsRepository.On("FindSkinByUserId", 1).Return(nil, nil)
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_user").Return(nil, nil)
+		suite.SkinsFilesRepository.On("SaveSkinFile", skinHash, mock.Anything).Once().Return(nil)
+		suite.SkinsRepository.On("SaveSkin", mock.MatchedBy(func(model *model.Skin) bool {
+			suite.Equal("https://skins.example.com/files/skins/"+skinHash+".png", model.Url)
+
+			return true
+		})).Once().Return(nil)
+
+		req := createSkinUploadRequest(createSkin())
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
 
-		part, _ := writer.CreateFormFile("skin", "char.png")
-		_, _ = part.Write(loadSkinFile())
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(201, resp.StatusCode)
+	})
+
+	suite.RunSubTest("Handle an error when saving the skin file into the repository", func() {
+		err := errors.New("mock error")
+		suite.SkinsRepository.On("FindSkinByUserId", 1).Return(nil, nil)
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_user").Return(nil, nil)
+		suite.SkinsFilesRepository.On("SaveSkinFile", createSkinHash(), mock.Anything).Once().Return(err)
+		suite.Emitter.On("Emit", "skinsystem:error", mock.MatchedBy(func(cErr error) bool {
+			return cErr.Error() == "unable to save skin file to the repository: mock error" &&
+				errors.Is(cErr, err)
+		})).Once()
+
+		req := createSkinUploadRequest(createSkin())
+		w := httptest.NewRecorder()
 
-		_ = writer.WriteField("identityId", "1")
-		_ = writer.WriteField("username", "mock_user")
-		_ = writer.WriteField("uuid", "0f657aa8-bfbe-415d-b700-5750090d3af3")
-		_ = writer.WriteField("skinId", "5")
+		suite.App.Handler().ServeHTTP(w, req)
 
-		err := writer.Close()
-		if err != nil {
-			panic(err)
-		}
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(500, resp.StatusCode)
+	})
 
-		req := httptest.NewRequest("POST", "http://chrly/skins", inputBody)
-		req.Header.Add("Content-Type", writer.FormDataContentType())
+	suite.RunSubTest("Upload skin file with invalid dimensions", func() {
+		req := createSkinUploadRequest(loadSkinFile())
 		w := httptest.NewRecorder()
 
 		suite.App.Handler().ServeHTTP(w, req)
@@ -339,7 +446,7 @@ func (suite *apiTestSuite) TestPostSkin() {
 		suite.JSONEq(`{
 			"errors": {
 				"skin": [
-					"Skin uploading is temporary unavailable"
+					"The skin field must be a 64x32 or 64x64 PNG image"
 				]
 			}
 		}`, string(responseBody))
@@ -352,6 +459,9 @@ func (suite *apiTestSuite) TestPostSkin() {
 
 func (suite *apiTestSuite) TestDeleteByUserId() {
 	suite.RunSubTest("Delete skin by its identity id", func() {
+		suite.Emitter.On("Emit", "skinsystem:skin:removed", mock.MatchedBy(func(skin *model.Skin) bool {
+			return skin.UserId == 1
+		})).Once()
 		suite.SkinsRepository.On("FindSkinByUserId", 1).Return(createSkinModel("mock_username", false), nil)
 		suite.SkinsRepository.On("RemoveSkinByUserId", 1).Once().Return(nil)
 
@@ -391,6 +501,9 @@ func (suite *apiTestSuite) TestDeleteByUserId() {
 
 func (suite *apiTestSuite) TestDeleteByUsername() {
 	suite.RunSubTest("Delete skin by its identity username", func() {
+		suite.Emitter.On("Emit", "skinsystem:skin:removed", mock.MatchedBy(func(skin *model.Skin) bool {
+			return skin.UserId == 1
+		})).Once()
 		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
 		suite.SkinsRepository.On("RemoveSkinByUserId", 1).Once().Return(nil)
 
@@ -424,6 +537,204 @@ func (suite *apiTestSuite) TestDeleteByUsername() {
 	})
 }
 
+/*************************
+ * Post cape tests cases *
+ *************************/
+
+func (suite *apiTestSuite) TestPostCape() {
+	suite.RunSubTest("Upload cape", func() {
+		suite.Emitter.On("Emit", "skinsystem:cape:saved", "mock_user").Once()
+		suite.CapesRepository.On("SaveCape", mock.Anything).Once().Run(func(args mock.Arguments) {
+			cape := args.Get(0).(*model.Cape)
+			suite.Equal("mock_user", cape.Username)
+			data, _ := ioutil.ReadAll(cape.File)
+			suite.Equal(createCape(), data)
+		}).Return(nil)
+
+		req := createCapeUploadRequest(createCape(), "mock_user")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(201, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.Empty(body)
+	})
+
+	suite.RunSubTest("Handle an error when saving the cape into the repository", func() {
+		err := errors.New("mock error")
+		suite.CapesRepository.On("SaveCape", mock.Anything).Once().Return(err)
+		suite.Emitter.On("Emit", "skinsystem:error", mock.MatchedBy(func(cErr error) bool {
+			return cErr.Error() == "unable to save cape to the repository: mock error" &&
+				errors.Is(cErr, err)
+		})).Once()
+
+		req := createCapeUploadRequest(createCape(), "mock_user")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(500, resp.StatusCode)
+	})
+
+	suite.RunSubTest("Upload cape with invalid dimensions", func() {
+		req := createCapeUploadRequest(loadSkinFile(), "mock_user")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(400, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.JSONEq(`{
+			"errors": {
+				"cape": [
+					"The cape field must be a PNG image with 2:1 ratio and width multiple of 64"
+				]
+			}
+		}`, string(body))
+	})
+
+	suite.RunSubTest("Upload cape with invalid username", func() {
+		req := createCapeUploadRequest(createCape(), "../mock_user")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(400, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.JSONEq(`{
+			"errors": {
+				"username": [
+					"The username field must contain valid username"
+				]
+			}
+		}`, string(body))
+	})
+
+	suite.RunSubTest("Get errors about required fields", func() {
+		req := httptest.NewRequest("POST", "http://chrly/capes", bytes.NewBufferString(url.Values{}.Encode()))
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(400, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.JSONEq(`{
+			"errors": {
+				"username": [
+					"The username field is required"
+				],
+				"cape": [
+					"The cape field is required"
+				]
+			}
+		}`, string(body))
+	})
+}
+
+/***************************
+ * Delete cape tests cases *
+ ***************************/
+
+func (suite *apiTestSuite) TestDeleteCape() {
+	suite.RunSubTest("Delete cape by username", func() {
+		suite.Emitter.On("Emit", "skinsystem:cape:removed", "mock_username").Once()
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+		suite.CapesRepository.On("RemoveCapeByUsername", "mock_username").Once().Return(nil)
+
+		req := httptest.NewRequest("DELETE", "http://chrly/capes/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(204, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.Empty(body)
+	})
+
+	suite.RunSubTest("Delete cape by identity id", func() {
+		suite.Emitter.On("Emit", "skinsystem:cape:removed", "mock_username").Once()
+		suite.SkinsRepository.On("FindSkinByUserId", 1).Return(createSkinModel("mock_username", false), nil)
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+		suite.CapesRepository.On("RemoveCapeByUsername", "mock_username").Once().Return(nil)
+
+		req := httptest.NewRequest("DELETE", "http://chrly/capes/id:1", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(204, resp.StatusCode)
+	})
+
+	suite.RunSubTest("Try to remove cape for not exists identity id", func() {
+		suite.SkinsRepository.On("FindSkinByUserId", 1).Return(nil, nil)
+
+		req := httptest.NewRequest("DELETE", "http://chrly/capes/id:1", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(404, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.JSONEq(`[
+			"Cannot find record for the requested identifier"
+		]`, string(body))
+	})
+
+	suite.RunSubTest("Try to remove not exists cape", func() {
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(nil, nil)
+
+		req := httptest.NewRequest("DELETE", "http://chrly/capes/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(404, resp.StatusCode)
+		body, _ := ioutil.ReadAll(resp.Body)
+		suite.JSONEq(`[
+			"Cannot find cape for the requested identifier"
+		]`, string(body))
+	})
+
+	suite.RunSubTest("Handle an error when removing the cape from the repository", func() {
+		err := errors.New("mock error")
+		suite.CapesRepository.On("FindCapeByUsername", "mock_username").Return(createCapeModel(), nil)
+		suite.CapesRepository.On("RemoveCapeByUsername", "mock_username").Once().Return(err)
+		suite.Emitter.On("Emit", "skinsystem:error", mock.MatchedBy(func(cErr error) bool {
+			return cErr.Error() == "cannot delete cape by error: mock error" &&
+				errors.Is(cErr, err)
+		})).Once()
+
+		req := httptest.NewRequest("DELETE", "http://chrly/capes/mock_username", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+		suite.Equal(500, resp.StatusCode)
+	})
+}
+
 /*************
  * Utilities *
  *************/
@@ -431,6 +742,54 @@ func (suite *apiTestSuite) TestDeleteByUsername() {
 // base64 https://github.com/mathiasbynens/small/blob/0ca3c51/png-transparent.png
 var OnePxPng = []byte("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAACklEQVR4nGMAAQAABQABDQottAAAAABJRU5ErkJggg==")
 
+func createSkinHash() string {
+	hash := sha256.Sum256(createSkin())
+	return hex.EncodeToString(hash[:])
+}
+
+func createSkinUploadRequest(skin []byte) *http.Request {
+	inputBody := &bytes.Buffer{}
+	writer := multipart.NewWriter(inputBody)
+
+	part, _ := writer.CreateFormFile("skin", "char.png")
+	_, _ = part.Write(skin)
+
+	_ = writer.WriteField("identityId", "1")
+	_ = writer.WriteField("username", "mock_user")
+	_ = writer.WriteField("uuid", "0f657aa8-bfbe-415d-b700-5750090d3af3")
+	_ = writer.WriteField("skinId", "5")
+
+	err := writer.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	req := httptest.NewRequest("POST", "http://chrly/skins", inputBody)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func createCapeUploadRequest(cape []byte, username string) *http.Request {
+	inputBody := &bytes.Buffer{}
+	writer := multipart.NewWriter(inputBody)
+
+	part, _ := writer.CreateFormFile("cape", "cape.png")
+	_, _ = part.Write(cape)
+
+	_ = writer.WriteField("username", username)
+
+	err := writer.Close()
+	if err != nil {
+		panic(err)
+	}
+
+	req := httptest.NewRequest("POST", "http://chrly/capes", inputBody)
+	req.Header.Add("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
 func loadSkinFile() []byte {
 	result := make([]byte, 92)
 	_, err := base64.StdEncoding.Decode(result, OnePxPng)
@@ -2,28 +2,53 @@ package http
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/mono83/slf"
 	"github.com/mono83/slf/wd"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/logging"
+	"github.com/elyby/chrly/tracing"
 	v "github.com/elyby/chrly/version"
 )
 
+var tracer = otel.Tracer("github.com/elyby/chrly/http")
+
+// The request id is written into the logs as is, so only the safe ids are accepted from the client
+var allowedRequestIdRegex = regexp.MustCompile(`^[\w.\-]{1,128}$`)
+
 type Emitter interface {
 	dispatcher.Emitter
 }
 
-func StartServer(server *http.Server, logger slf.Logger) {
+// The tracerProvider may be nil when the tracing is disabled
+func StartServer(server *http.Server, logger slf.Logger, tracerProvider *sdktrace.TracerProvider) {
 	logger.Debug("Chrly :v (:c)", wd.StringParam("v", v.Version()), wd.StringParam("c", v.Commit()))
 
+	// Requests contexts are derived from this context, so the long-running operations,
+	// like waiting for Mojang's textures, will be aborted when the shutdown starts
+	ctx, cancel := context.WithCancel(context.Background())
+	server.BaseContext = func(net.Listener) context.Context {
+		return ctx
+	}
+
 	done := make(chan bool, 1)
 	go func() {
 		logger.Info("Starting the server, HTTP on: :addr", wd.StringParam("addr", server.Addr))
@@ -36,7 +61,15 @@ func StartServer(server *http.Server, logger slf.Logger) {
 	go func() {
 		s := waitForExitSignal()
 		logger.Info("Got signal: :signal, starting graceful shutdown", wd.StringParam("signal", s.String()))
+		cancel()
 		server.Shutdown(context.Background())
+		if tracerProvider != nil {
+			// Flush the spans of the last requests, that are still in the batcher's queue
+			if err := tracerProvider.Shutdown(context.Background()); err != nil {
+				logger.Warning("Unable to flush the traces: :err", wd.ErrParam(err))
+			}
+		}
+
 		logger.Info("Graceful shutdown succeed, exiting", wd.StringParam("signal", s.String()))
 		close(done)
 	}()
@@ -61,6 +94,64 @@ func (lrw *loggingResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 }
 
+type requestInfoResponseWriter struct {
+	http.ResponseWriter
+	info *logging.RequestInfo
+}
+
+func (w *requestInfoResponseWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.info.ResponseSize += int64(n)
+
+	return n, err
+}
+
+// CreateRequestInfoMiddleware should be the outermost middleware, so the collected info
+// will be available in the context of the request, that is passed to the request events
+func CreateRequestInfoMiddleware() mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			info := &logging.RequestInfo{
+				Id:        req.Header.Get("X-Request-Id"),
+				StartedAt: time.Now(),
+			}
+			if !allowedRequestIdRegex.MatchString(info.Id) {
+				info.Id = generateRequestId()
+			}
+
+			resp.Header().Set("X-Request-Id", info.Id)
+			handler.ServeHTTP(
+				&requestInfoResponseWriter{ResponseWriter: resp, info: info},
+				req.WithContext(logging.WithRequestInfo(req.Context(), info)),
+			)
+		})
+	}
+}
+
+// CreateRouteTemplateMiddleware records the template of the matched route into the request info.
+// The mounted routers receive the path without their prefix, so it must be passed to restore the full template
+func CreateRouteTemplateMiddleware(prefix string) mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			info := logging.RequestInfoFromContext(req.Context())
+			if route := mux.CurrentRoute(req); info != nil && route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					info.Route = prefix + template
+				}
+			}
+
+			handler.ServeHTTP(resp, req)
+		})
+	}
+}
+
+func generateRequestId() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
 func CreateRequestEventsMiddleware(emitter Emitter, prefix string) mux.MiddlewareFunc {
 	beforeTopic := strings.Join([]string{prefix, "before_request"}, ":")
 	afterTopic := strings.Join([]string{prefix, "after_request"}, ":")
@@ -80,14 +171,58 @@ func CreateRequestEventsMiddleware(emitter Emitter, prefix string) mux.Middlewar
 	}
 }
 
+func CreateTracingMiddleware() mux.MiddlewareFunc {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			req = tracing.ExtractHeaders(req)
+
+			spanName := req.Method
+			var routeTemplate string
+			if route := mux.CurrentRoute(req); route != nil {
+				routeTemplate, _ = route.GetPathTemplate()
+				if routeTemplate != "" {
+					spanName += " " + routeTemplate
+				}
+			}
+
+			ctx, span := tracer.Start(req.Context(), spanName,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", req.Method),
+					attribute.String("url.path", req.URL.Path),
+					attribute.String("http.route", routeTemplate),
+				),
+			)
+			defer span.End()
+
+			lrw := &loggingResponseWriter{
+				ResponseWriter: resp,
+				statusCode:     http.StatusOK,
+			}
+			handler.ServeHTTP(lrw, req.WithContext(ctx))
+
+			// The mounted routers match their routes only after the span has been started
+			if info := logging.RequestInfoFromContext(req.Context()); info != nil && info.Route != "" && info.Route != routeTemplate {
+				span.SetName(req.Method + " " + info.Route)
+				span.SetAttributes(attribute.String("http.route", info.Route))
+			}
+
+			span.SetAttributes(attribute.Int("http.response.status_code", lrw.statusCode))
+			if lrw.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(lrw.statusCode))
+			}
+		})
+	}
+}
+
 type Authenticator interface {
-	Authenticate(req *http.Request) error
+	Authenticate(req *http.Request, scope Scope) error
 }
 
-func CreateAuthenticationMiddleware(checker Authenticator) mux.MiddlewareFunc {
+func CreateAuthenticationMiddleware(checker Authenticator, scope Scope) mux.MiddlewareFunc {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
-			err := checker.Authenticate(req)
+			err := checker.Authenticate(req, scope)
 			if err != nil {
 				apiForbidden(resp, err.Error())
 				return
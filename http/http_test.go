@@ -7,8 +7,18 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/gorilla/mux"
 	testify "github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/elyby/chrly/logging"
 )
 
 type emitterMock struct {
@@ -41,12 +51,139 @@ func TestCreateRequestEventsMiddleware(t *testing.T) {
 	emitter.AssertExpectations(t)
 }
 
+func TestCreateTracingMiddleware(t *testing.T) {
+	// The package's tracer delegates to the first provider set globally, so it's set only once
+	spanRecorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Run("should start the span of the request", func(t *testing.T) {
+		spanRecorder.Reset()
+		req := httptest.NewRequest("GET", "http://example.com/skins/mock_username", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		resp := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		router.Use(CreateTracingMiddleware())
+		router.HandleFunc("/skins/{username}", func(resp http.ResponseWriter, req *http.Request) {
+			testify.True(t, trace.SpanContextFromContext(req.Context()).IsValid())
+			resp.WriteHeader(500)
+		})
+		router.ServeHTTP(resp, req)
+
+		spans := spanRecorder.Ended()
+		if testify.Len(t, spans, 1) {
+			span := spans[0]
+			testify.Equal(t, "GET /skins/{username}", span.Name())
+			testify.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+			testify.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+			testify.Contains(t, span.Attributes(), attribute.String("http.route", "/skins/{username}"))
+			testify.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", 500))
+			testify.Equal(t, codes.Error, span.Status().Code)
+		}
+	})
+
+	t.Run("should name the span by the route of the mounted router", func(t *testing.T) {
+		spanRecorder.Reset()
+		req := httptest.NewRequest("DELETE", "http://example.com/api/skins/mock_username", nil)
+		req = req.WithContext(logging.WithRequestInfo(req.Context(), &logging.RequestInfo{}))
+
+		apiRouter := mux.NewRouter()
+		apiRouter.Use(CreateRouteTemplateMiddleware("/api"))
+		apiRouter.HandleFunc("/skins/{username}", func(resp http.ResponseWriter, req *http.Request) {})
+
+		router := mux.NewRouter()
+		router.Use(CreateRouteTemplateMiddleware(""))
+		router.Use(CreateTracingMiddleware())
+		router.PathPrefix("/api").Handler(http.StripPrefix("/api", apiRouter))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := spanRecorder.Ended()
+		if testify.Len(t, spans, 1) {
+			testify.Equal(t, "DELETE /api/skins/{username}", spans[0].Name())
+			testify.Contains(t, spans[0].Attributes(), attribute.String("http.route", "/api/skins/{username}"))
+		}
+	})
+}
+
+func TestCreateRequestInfoMiddleware(t *testing.T) {
+	t.Run("should generate the request id and collect the response size", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		resp := httptest.NewRecorder()
+
+		var info *logging.RequestInfo
+		middlewareFunc := CreateRequestInfoMiddleware()
+		middlewareFunc.Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			info = logging.RequestInfoFromContext(req.Context())
+			_, _ = resp.Write([]byte("mock body"))
+		})).ServeHTTP(resp, req)
+
+		if testify.NotNil(t, info) {
+			testify.Regexp(t, "^[0-9a-f]{32}$", info.Id)
+			testify.Equal(t, info.Id, resp.Header().Get("X-Request-Id"))
+			testify.Equal(t, int64(9), info.ResponseSize)
+			testify.False(t, info.StartedAt.IsZero())
+		}
+	})
+
+	t.Run("should use the valid request id from the request", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("X-Request-Id", "mock-request.id_1")
+		resp := httptest.NewRecorder()
+
+		CreateRequestInfoMiddleware().Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {})).ServeHTTP(resp, req)
+
+		testify.Equal(t, "mock-request.id_1", resp.Header().Get("X-Request-Id"))
+	})
+
+	t.Run("should replace the invalid request id", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("X-Request-Id", "\"injected\" value")
+		resp := httptest.NewRecorder()
+
+		CreateRequestInfoMiddleware().Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {})).ServeHTTP(resp, req)
+
+		testify.Regexp(t, "^[0-9a-f]{32}$", resp.Header().Get("X-Request-Id"))
+	})
+}
+
+func TestCreateRouteTemplateMiddleware(t *testing.T) {
+	t.Run("should record the template of the route", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "http://example.com/api/skins/id:1", nil)
+		info := &logging.RequestInfo{}
+		req = req.WithContext(logging.WithRequestInfo(req.Context(), info))
+
+		apiRouter := mux.NewRouter()
+		apiRouter.Use(CreateRouteTemplateMiddleware("/api"))
+		apiRouter.HandleFunc("/skins/id:{id:[0-9]+}", func(resp http.ResponseWriter, req *http.Request) {})
+
+		router := mux.NewRouter()
+		router.Use(CreateRouteTemplateMiddleware(""))
+		router.PathPrefix("/api").Handler(http.StripPrefix("/api", apiRouter))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		testify.Equal(t, "/api/skins/id:{id:[0-9]+}", info.Route)
+	})
+
+	t.Run("should do nothing when the route isn't matched", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "http://example.com/not/exists", nil)
+		info := &logging.RequestInfo{}
+		req = req.WithContext(logging.WithRequestInfo(req.Context(), info))
+
+		router := mux.NewRouter()
+		router.Use(CreateRouteTemplateMiddleware(""))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		testify.Empty(t, info.Route)
+	})
+}
+
 type authCheckerMock struct {
 	mock.Mock
 }
 
-func (m *authCheckerMock) Authenticate(req *http.Request) error {
-	args := m.Called(req)
+func (m *authCheckerMock) Authenticate(req *http.Request, scope Scope) error {
+	args := m.Called(req, scope)
 	return args.Error(0)
 }
 
@@ -56,10 +193,10 @@ func TestCreateAuthenticationMiddleware(t *testing.T) {
 		resp := httptest.NewRecorder()
 
 		auth := &authCheckerMock{}
-		auth.On("Authenticate", req).Once().Return(nil)
+		auth.On("Authenticate", req, SkinScope).Once().Return(nil)
 
 		isHandlerCalled := false
-		middlewareFunc := CreateAuthenticationMiddleware(auth)
+		middlewareFunc := CreateAuthenticationMiddleware(auth, SkinScope)
 		middlewareFunc.Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 			isHandlerCalled = true
 		})).ServeHTTP(resp, req)
@@ -74,10 +211,10 @@ func TestCreateAuthenticationMiddleware(t *testing.T) {
 		resp := httptest.NewRecorder()
 
 		auth := &authCheckerMock{}
-		auth.On("Authenticate", req).Once().Return(errors.New("error reason"))
+		auth.On("Authenticate", req, SkinScope).Once().Return(errors.New("error reason"))
 
 		isHandlerCalled := false
-		middlewareFunc := CreateAuthenticationMiddleware(auth)
+		middlewareFunc := CreateAuthenticationMiddleware(auth, SkinScope)
 		middlewareFunc.Middleware(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
 			isHandlerCalled = true
 		})).ServeHTTP(resp, req)
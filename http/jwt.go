@@ -1,6 +1,8 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strings"
@@ -8,6 +10,7 @@ import (
 
 	"github.com/SermoDigital/jose/crypto"
 	"github.com/SermoDigital/jose/jws"
+	josejwt "github.com/SermoDigital/jose/jwt"
 )
 
 var hashAlg = crypto.SigningMethodHS256
@@ -17,22 +20,56 @@ const scopesClaim = "scopes"
 type Scope string
 
 var (
-	SkinScope = Scope("skin")
+	// SkinScope is the legacy scope, that grants access to the whole records manipulating API
+	SkinScope        = Scope("skin")
+	SkinsWriteScope  = Scope("skins:write")
+	SkinsDeleteScope = Scope("skins:delete")
+	CapesWriteScope  = Scope("capes:write")
+	CapesDeleteScope = Scope("capes:delete")
+	WorkerScope      = Scope("worker")
 )
 
+var Scopes = []Scope{SkinScope, SkinsWriteScope, SkinsDeleteScope, CapesWriteScope, CapesDeleteScope, WorkerScope}
+
+// Tokens, issued before the fine-grained scopes were introduced, still have access to the same endpoints
+var impliedScopes = map[Scope][]Scope{
+	SkinScope: {SkinsWriteScope, SkinsDeleteScope, CapesWriteScope, CapesDeleteScope},
+}
+
+type RevokedTokensRepository interface {
+	IsTokenRevoked(jti string) (bool, error)
+	// RevokeToken adds the token to the denylist. The record can be removed after the expiresAt,
+	// since the token will be rejected anyway. Zero expiresAt means that the token never expires
+	RevokeToken(jti string, expiresAt time.Time) error
+}
+
 type JwtAuth struct {
 	Emitter
 	Key []byte
+	// RevokedTokensRepo is optional. Revocation isn't checked when it's not set
+	RevokedTokensRepo RevokedTokensRepository
 }
 
-func (t *JwtAuth) NewToken(scopes ...Scope) ([]byte, error) {
+// NewToken creates the token with the passed scopes. Zero ttl means that the token never expires
+func (t *JwtAuth) NewToken(ttl time.Duration, scopes ...Scope) ([]byte, error) {
 	if len(t.Key) == 0 {
 		return nil, errors.New("signing key not available")
 	}
 
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	claims := jws.Claims{}
 	claims.Set(scopesClaim, scopes)
-	claims.SetIssuedAt(time.Now())
+	claims.SetIssuedAt(now)
+	claims.SetJWTID(hex.EncodeToString(jti))
+	if ttl > 0 {
+		claims.SetExpiration(now.Add(ttl))
+	}
+
 	encoder := jws.NewJWT(claims, hashAlg)
 	token, err := encoder.Serialize(t.Key)
 	if err != nil {
@@ -42,7 +79,29 @@ func (t *JwtAuth) NewToken(scopes ...Scope) ([]byte, error) {
 	return token, nil
 }
 
-func (t *JwtAuth) Authenticate(req *http.Request) error {
+// RevokeToken adds the valid token to the denylist
+func (t *JwtAuth) RevokeToken(tokenStr string) error {
+	if t.RevokedTokensRepo == nil {
+		return errors.New("revoked tokens repository not available")
+	}
+
+	token, err := t.parseToken(tokenStr)
+	if err != nil {
+		return err
+	}
+
+	claims := jws.Claims(token.Claims())
+	jti, ok := claims.JWTID()
+	if !ok {
+		return errors.New("the token has no jti claim and can't be revoked")
+	}
+
+	expiresAt, _ := claims.Expiration()
+
+	return t.RevokedTokensRepo.RevokeToken(jti, expiresAt)
+}
+
+func (t *JwtAuth) Authenticate(req *http.Request, scope Scope) error {
 	if len(t.Key) == 0 {
 		return t.emitErr(errors.New("Signing key not set"))
 	}
@@ -56,15 +115,26 @@ func (t *JwtAuth) Authenticate(req *http.Request) error {
 		return t.emitErr(errors.New("Cannot recognize JWT token in passed value"))
 	}
 
-	tokenStr := bearerToken[7:]
-	token, err := jws.ParseJWT([]byte(tokenStr))
+	token, err := t.parseToken(bearerToken[7:])
 	if err != nil {
-		return t.emitErr(errors.New("Cannot parse passed JWT token"))
+		return t.emitErr(err)
 	}
 
-	err = token.Validate(t.Key, hashAlg)
-	if err != nil {
-		return t.emitErr(errors.New("JWT token have invalid signature. It may be corrupted or expired"))
+	if !hasScope(token.Claims().Get(scopesClaim), scope) {
+		return t.emitErr(errors.New("JWT token doesn't have the required scope"))
+	}
+
+	if t.RevokedTokensRepo != nil {
+		if jti, ok := jws.Claims(token.Claims()).JWTID(); ok {
+			isRevoked, err := t.RevokedTokensRepo.IsTokenRevoked(jti)
+			if err != nil {
+				return t.emitErr(errors.New("Unable to check whether the JWT token is revoked"))
+			}
+
+			if isRevoked {
+				return t.emitErr(errors.New("JWT token is revoked"))
+			}
+		}
 	}
 
 	t.Emit("authentication:success")
@@ -72,7 +142,56 @@ func (t *JwtAuth) Authenticate(req *http.Request) error {
 	return nil
 }
 
+func (t *JwtAuth) parseToken(tokenStr string) (josejwt.JWT, error) {
+	token, err := jws.ParseJWT([]byte(tokenStr))
+	if err != nil {
+		return nil, errors.New("Cannot parse passed JWT token")
+	}
+
+	err = token.Validate(t.Key, hashAlg)
+	switch err {
+	case nil:
+		return token, nil
+	case josejwt.ErrTokenIsExpired:
+		return nil, errors.New("JWT token is expired")
+	case josejwt.ErrTokenNotYetValid:
+		return nil, errors.New("JWT token is not valid yet")
+	default:
+		return nil, errors.New("JWT token have invalid signature. It may be corrupted or expired")
+	}
+}
+
 func (t *JwtAuth) emitErr(err error) error {
 	t.Emit("authentication:error", err)
 	return err
 }
+
+// The scopes claim may be either a single scope or an array of them
+func hasScope(claim interface{}, scope Scope) bool {
+	switch value := claim.(type) {
+	case string:
+		return grantsScope(Scope(value), scope)
+	case []interface{}:
+		for _, item := range value {
+			if str, ok := item.(string); ok && grantsScope(Scope(str), scope) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func grantsScope(granted Scope, required Scope) bool {
+	if granted == required {
+		return true
+	}
+
+	for _, implied := range impliedScopes[granted] {
+		if implied == required {
+			return true
+		}
+	}
+
+	return false
+}
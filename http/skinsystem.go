@@ -1,15 +1,20 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/logging"
 	"github.com/elyby/chrly/model"
+	"github.com/elyby/chrly/tracing"
 )
 
 type SkinsRepository interface {
@@ -20,21 +25,47 @@ type SkinsRepository interface {
 	RemoveSkinByUsername(username string) error
 }
 
+type SkinsFilesRepository interface {
+	FindSkinFileByHash(hash string) (*model.SkinFile, error)
+	SaveSkinFile(hash string, file io.Reader) error
+}
+
 type CapesRepository interface {
 	FindCapeByUsername(username string) (*model.Cape, error)
+	SaveCape(cape *model.Cape) error
+	RemoveCapeByUsername(username string) error
+}
+
+// TexturesRepository is the unified storage of the skins, capes and their metadata for each identity
+type TexturesRepository interface {
+	SkinsRepository
+	SkinsFilesRepository
+	CapesRepository
+}
+
+// SeparatedTexturesRepository allows you to use separate storage engines to satisfy
+// the TexturesRepository interface
+type SeparatedTexturesRepository struct {
+	SkinsRepository
+	SkinsFilesRepository
+	CapesRepository
 }
 
 type MojangTexturesProvider interface {
-	GetForUsername(username string) (*mojang.SignedTexturesResponse, error)
+	GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error)
 }
 
 type Skinsystem struct {
 	Emitter
 	SkinsRepo               SkinsRepository
+	SkinsFilesRepo          SkinsFilesRepository
 	CapesRepo               CapesRepository
 	MojangTexturesProvider  MojangTexturesProvider
 	TexturesExtraParamName  string
 	TexturesExtraParamValue string
+	// When enabled, the skins uploaded to Chrly will be served directly from the /skins/{username}
+	// endpoint instead of the redirect to the skin's url
+	StreamLocalSkins bool
 }
 
 func (ctx *Skinsystem) Handler() *mux.Router {
@@ -44,6 +75,7 @@ func (ctx *Skinsystem) Handler() *mux.Router {
 	router.HandleFunc("/cloaks/{username}", ctx.capeHandler).Methods(http.MethodGet).Name("cloaks")
 	router.HandleFunc("/textures/{username}", ctx.texturesHandler).Methods(http.MethodGet)
 	router.HandleFunc("/textures/signed/{username}", ctx.signedTexturesHandler).Methods(http.MethodGet)
+	router.HandleFunc("/files/skins/{hash:[0-9a-f]{64}}.png", ctx.skinFileHandler).Methods(http.MethodGet)
 	// Legacy
 	router.HandleFunc("/skins", ctx.skinGetHandler).Methods(http.MethodGet)
 	router.HandleFunc("/cloaks", ctx.capeGetHandler).Methods(http.MethodGet)
@@ -53,13 +85,24 @@ func (ctx *Skinsystem) Handler() *mux.Router {
 
 func (ctx *Skinsystem) skinHandler(response http.ResponseWriter, request *http.Request) {
 	username := parseUsername(mux.Vars(request)["username"])
-	rec, err := ctx.SkinsRepo.FindSkinByUsername(username)
+	rec, err := ctx.findSkinByUsername(request.Context(), username)
 	if err == nil && rec != nil && rec.SkinId != 0 {
+		logging.SetTexturesSource(request.Context(), logging.LocalTexturesSource)
+		if ctx.StreamLocalSkins && rec.SkinHash != "" {
+			file, err := ctx.findSkinFileByHash(request.Context(), rec.SkinHash)
+			if err != nil {
+				ctx.Emit("skinsystem:error", fmt.Errorf("unable to find skin file in the repository: %w", err))
+			} else if file != nil {
+				serveSkinFile(response, request, file)
+				return
+			}
+		}
+
 		http.Redirect(response, request, rec.Url, 301)
 		return
 	}
 
-	mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(username)
+	mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(request.Context(), username)
 	if err != nil || mojangTextures == nil {
 		response.WriteHeader(http.StatusNotFound)
 		return
@@ -93,16 +136,34 @@ func (ctx *Skinsystem) skinGetHandler(response http.ResponseWriter, request *htt
 	ctx.skinHandler(response, request)
 }
 
+func (ctx *Skinsystem) skinFileHandler(response http.ResponseWriter, request *http.Request) {
+	hash := mux.Vars(request)["hash"]
+	file, err := ctx.findSkinFileByHash(request.Context(), hash)
+	if err != nil {
+		ctx.Emit("skinsystem:error", fmt.Errorf("unable to find skin file in the repository: %w", err))
+		apiServerError(response)
+		return
+	}
+
+	if file == nil {
+		response.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	serveSkinFile(response, request, file)
+}
+
 func (ctx *Skinsystem) capeHandler(response http.ResponseWriter, request *http.Request) {
 	username := parseUsername(mux.Vars(request)["username"])
-	rec, err := ctx.CapesRepo.FindCapeByUsername(username)
+	rec, err := ctx.findCapeByUsername(request.Context(), username)
 	if err == nil && rec != nil {
+		logging.SetTexturesSource(request.Context(), logging.LocalTexturesSource)
 		request.Header.Set("Content-Type", "image/png")
 		_, _ = io.Copy(response, rec.File)
 		return
 	}
 
-	mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(username)
+	mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(request.Context(), username)
 	if err != nil || mojangTextures == nil {
 		response.WriteHeader(http.StatusNotFound)
 		return
@@ -140,9 +201,10 @@ func (ctx *Skinsystem) texturesHandler(response http.ResponseWriter, request *ht
 	username := parseUsername(mux.Vars(request)["username"])
 
 	var textures *mojang.TexturesResponse
-	skin, skinErr := ctx.SkinsRepo.FindSkinByUsername(username)
-	cape, capeErr := ctx.CapesRepo.FindCapeByUsername(username)
+	skin, skinErr := ctx.findSkinByUsername(request.Context(), username)
+	cape, capeErr := ctx.findCapeByUsername(request.Context(), username)
 	if (skinErr == nil && skin != nil && skin.SkinId != 0) || (capeErr == nil && cape != nil) {
+		logging.SetTexturesSource(request.Context(), logging.LocalTexturesSource)
 		textures = &mojang.TexturesResponse{}
 		if skinErr == nil && skin != nil && skin.SkinId != 0 {
 			skinTextures := &mojang.SkinTexturesResponse{
@@ -165,7 +227,7 @@ func (ctx *Skinsystem) texturesHandler(response http.ResponseWriter, request *ht
 			}
 		}
 	} else {
-		mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(username)
+		mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(request.Context(), username)
 		if err != nil || mojangTextures == nil {
 			response.WriteHeader(http.StatusNoContent)
 			return
@@ -194,8 +256,9 @@ func (ctx *Skinsystem) signedTexturesHandler(response http.ResponseWriter, reque
 
 	var responseData *mojang.SignedTexturesResponse
 
-	rec, err := ctx.SkinsRepo.FindSkinByUsername(username)
+	rec, err := ctx.findSkinByUsername(request.Context(), username)
 	if err == nil && rec != nil && rec.SkinId != 0 && rec.MojangTextures != "" {
+		logging.SetTexturesSource(request.Context(), logging.LocalTexturesSource)
 		responseData = &mojang.SignedTexturesResponse{
 			Id:   strings.Replace(rec.Uuid, "-", "", -1),
 			Name: rec.Username,
@@ -208,7 +271,7 @@ func (ctx *Skinsystem) signedTexturesHandler(response http.ResponseWriter, reque
 			},
 		}
 	} else if request.URL.Query().Get("proxy") != "" {
-		mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(username)
+		mojangTextures, err := ctx.MojangTexturesProvider.GetForUsername(request.Context(), username)
 		if err == nil && mojangTextures != nil {
 			responseData = mojangTextures
 		}
@@ -229,6 +292,49 @@ func (ctx *Skinsystem) signedTexturesHandler(response http.ResponseWriter, reque
 	_, _ = response.Write(responseJson)
 }
 
+// The repositories don't accept the context, so their calls are traced here
+func (ctx *Skinsystem) findSkinByUsername(reqCtx context.Context, username string) (skin *model.Skin, err error) {
+	_, span := tracer.Start(reqCtx, "SkinsRepository.FindSkinByUsername")
+	span.SetAttributes(attribute.String("username", username))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	return ctx.SkinsRepo.FindSkinByUsername(username)
+}
+
+func (ctx *Skinsystem) findSkinFileByHash(reqCtx context.Context, hash string) (file *model.SkinFile, err error) {
+	_, span := tracer.Start(reqCtx, "SkinsFilesRepository.FindSkinFileByHash")
+	span.SetAttributes(attribute.String("hash", hash))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	return ctx.SkinsFilesRepo.FindSkinFileByHash(hash)
+}
+
+func (ctx *Skinsystem) findCapeByUsername(reqCtx context.Context, username string) (cape *model.Cape, err error) {
+	_, span := tracer.Start(reqCtx, "CapesRepository.FindCapeByUsername")
+	span.SetAttributes(attribute.String("username", username))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
+
+	return ctx.CapesRepo.FindCapeByUsername(username)
+}
+
+func serveSkinFile(response http.ResponseWriter, request *http.Request, file *model.SkinFile) {
+	if closer, ok := file.File.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// The files are addressed by the hash of their contents, so the hash is a strong validator.
+	// http.ServeContent will handle If-None-Match and If-Modified-Since headers and set Content-Length
+	response.Header().Set("Content-Type", "image/png")
+	response.Header().Set("ETag", `"`+file.Hash+`"`)
+	http.ServeContent(response, request, file.Hash+".png", file.ModifiedAt, file.File)
+}
+
 func parseUsername(username string) string {
 	return strings.TrimSuffix(username, ".png")
 }
@@ -1,12 +1,16 @@
 package http
 
 import (
+	"context"
 	"bytes"
+	"errors"
 	"image"
 	"image/png"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -61,6 +65,25 @@ func (m *skinsRepositoryMock) RemoveSkinByUsername(username string) error {
 	return args.Error(0)
 }
 
+type skinsFilesRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *skinsFilesRepositoryMock) FindSkinFileByHash(hash string) (*model.SkinFile, error) {
+	args := m.Called(hash)
+	var result *model.SkinFile
+	if casted, ok := args.Get(0).(*model.SkinFile); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+func (m *skinsFilesRepositoryMock) SaveSkinFile(hash string, file io.Reader) error {
+	args := m.Called(hash, file)
+	return args.Error(0)
+}
+
 type capesRepositoryMock struct {
 	mock.Mock
 }
@@ -75,11 +98,21 @@ func (m *capesRepositoryMock) FindCapeByUsername(username string) (*model.Cape,
 	return result, args.Error(1)
 }
 
+func (m *capesRepositoryMock) SaveCape(cape *model.Cape) error {
+	args := m.Called(cape)
+	return args.Error(0)
+}
+
+func (m *capesRepositoryMock) RemoveCapeByUsername(username string) error {
+	args := m.Called(username)
+	return args.Error(0)
+}
+
 type mojangTexturesProviderMock struct {
 	mock.Mock
 }
 
-func (m *mojangTexturesProviderMock) GetForUsername(username string) (*mojang.SignedTexturesResponse, error) {
+func (m *mojangTexturesProviderMock) GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error) {
 	args := m.Called(username)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
@@ -95,6 +128,7 @@ type skinsystemTestSuite struct {
 	App *Skinsystem
 
 	SkinsRepository        *skinsRepositoryMock
+	SkinsFilesRepository   *skinsFilesRepositoryMock
 	CapesRepository        *capesRepositoryMock
 	MojangTexturesProvider *mojangTexturesProviderMock
 	Emitter                *emitterMock
@@ -106,12 +140,14 @@ type skinsystemTestSuite struct {
 
 func (suite *skinsystemTestSuite) SetupTest() {
 	suite.SkinsRepository = &skinsRepositoryMock{}
+	suite.SkinsFilesRepository = &skinsFilesRepositoryMock{}
 	suite.CapesRepository = &capesRepositoryMock{}
 	suite.MojangTexturesProvider = &mojangTexturesProviderMock{}
 	suite.Emitter = &emitterMock{}
 
 	suite.App = &Skinsystem{
 		SkinsRepo:               suite.SkinsRepository,
+		SkinsFilesRepo:          suite.SkinsFilesRepository,
 		CapesRepo:               suite.CapesRepository,
 		MojangTexturesProvider:  suite.MojangTexturesProvider,
 		Emitter:                 suite.Emitter,
@@ -122,6 +158,7 @@ func (suite *skinsystemTestSuite) SetupTest() {
 
 func (suite *skinsystemTestSuite) TearDownTest() {
 	suite.SkinsRepository.AssertExpectations(suite.T())
+	suite.SkinsFilesRepository.AssertExpectations(suite.T())
 	suite.CapesRepository.AssertExpectations(suite.T())
 	suite.MojangTexturesProvider.AssertExpectations(suite.T())
 	suite.Emitter.AssertExpectations(suite.T())
@@ -233,6 +270,110 @@ func (suite *skinsystemTestSuite) TestSkin() {
 	})
 }
 
+func (suite *skinsystemTestSuite) TestSkinStreaming() {
+	hash := "3b2b8e6c5b3b9b4ff3b36fbeb4c8f1fb8f1ec1f0e0f0c5a9f3e2c0b4d8c2a1e0"
+	createLocalSkinModel := func() *model.Skin {
+		skin := createSkinModel("mock_username", false)
+		skin.SkinHash = hash
+
+		return skin
+	}
+
+	suite.RunSubTest("Stream skin stored in Chrly", func() {
+		suite.App.StreamLocalSkins = true
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createLocalSkinModel(), nil)
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(createSkinFileModel(hash), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.Equal("image/png", resp.Header.Get("Content-Type"))
+		suite.Equal(`"`+hash+`"`, resp.Header.Get("ETag"))
+		responseData, _ := ioutil.ReadAll(resp.Body)
+		suite.Equal(createSkin(), responseData)
+	})
+
+	suite.RunSubTest("Respond with not modified status for stored skin", func() {
+		suite.App.StreamLocalSkins = true
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createLocalSkinModel(), nil)
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(createSkinFileModel(hash), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username.png", nil)
+		req.Header.Set("If-None-Match", `"`+hash+`"`)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(304, resp.StatusCode)
+	})
+
+	suite.RunSubTest("Redirect to the skin url when streaming is disabled", func() {
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createLocalSkinModel(), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(301, resp.StatusCode)
+		suite.Equal("http://chrly/skin.png", resp.Header.Get("Location"))
+	})
+
+	suite.RunSubTest("Redirect to the skin url when the skin isn't stored in Chrly", func() {
+		suite.App.StreamLocalSkins = true
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createSkinModel("mock_username", false), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(301, resp.StatusCode)
+		suite.Equal("http://chrly/skin.png", resp.Header.Get("Location"))
+	})
+
+	suite.RunSubTest("Redirect to the skin url when the file is missing", func() {
+		suite.App.StreamLocalSkins = true
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createLocalSkinModel(), nil)
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(301, resp.StatusCode)
+		suite.Equal("http://chrly/skin.png", resp.Header.Get("Location"))
+	})
+
+	suite.RunSubTest("Handle an error when loading the file from the repository", func() {
+		suite.App.StreamLocalSkins = true
+		err := errors.New("mock error")
+		suite.SkinsRepository.On("FindSkinByUsername", "mock_username").Return(createLocalSkinModel(), nil)
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(nil, err)
+		suite.Emitter.On("Emit", "skinsystem:error", mock.MatchedBy(func(cErr error) bool {
+			return errors.Is(cErr, err)
+		})).Once()
+
+		req := httptest.NewRequest("GET", "http://chrly/skins/mock_username.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(301, resp.StatusCode)
+		suite.Equal("http://chrly/skin.png", resp.Header.Get("Location"))
+	})
+}
+
 func (suite *skinsystemTestSuite) TestSkinGET() {
 	for _, testCase := range skinsTestsCases {
 		suite.RunSubTest(testCase.Name, func() {
@@ -259,6 +400,86 @@ func (suite *skinsystemTestSuite) TestSkinGET() {
 	})
 }
 
+/*****************************
+ * Get skin file tests cases *
+ *****************************/
+
+func (suite *skinsystemTestSuite) TestSkinFile() {
+	hash := "3b2b8e6c5b3b9b4ff3b36fbeb4c8f1fb8f1ec1f0e0f0c5a9f3e2c0b4d8c2a1e0"
+
+	suite.RunSubTest("Skin file exists in the repository", func() {
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(createSkinFileModel(hash), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/files/skins/"+hash+".png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(200, resp.StatusCode)
+		suite.Equal("image/png", resp.Header.Get("Content-Type"))
+		suite.Equal(`"`+hash+`"`, resp.Header.Get("ETag"))
+		suite.Equal("Fri, 01 May 2020 12:00:00 GMT", resp.Header.Get("Last-Modified"))
+		suite.Equal(strconv.Itoa(len(createSkin())), resp.Header.Get("Content-Length"))
+		responseData, _ := ioutil.ReadAll(resp.Body)
+		suite.Equal(createSkin(), responseData)
+	})
+
+	suite.RunSubTest("Skin file wasn't modified since the last request", func() {
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(createSkinFileModel(hash), nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/files/skins/"+hash+".png", nil)
+		req.Header.Set("If-None-Match", `"`+hash+`"`)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(304, resp.StatusCode)
+		responseData, _ := ioutil.ReadAll(resp.Body)
+		suite.Empty(responseData)
+	})
+
+	suite.RunSubTest("Skin file doesn't exist in the repository", func() {
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/files/skins/"+hash+".png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(404, resp.StatusCode)
+	})
+
+	suite.RunSubTest("Handle an error when loading the file from the repository", func() {
+		err := errors.New("mock error")
+		suite.SkinsFilesRepository.On("FindSkinFileByHash", hash).Return(nil, err)
+		suite.Emitter.On("Emit", "skinsystem:error", mock.MatchedBy(func(cErr error) bool {
+			return cErr.Error() == "unable to find skin file in the repository: mock error" &&
+				errors.Is(cErr, err)
+		})).Once()
+
+		req := httptest.NewRequest("GET", "http://chrly/files/skins/"+hash+".png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(500, resp.StatusCode)
+	})
+
+	suite.RunSubTest("Pass invalid hash", func() {
+		req := httptest.NewRequest("GET", "http://chrly/files/skins/not-a-hash.png", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		resp := w.Result()
+		suite.Equal(404, resp.StatusCode)
+	})
+}
+
 /************************
  * Get cape tests cases *
  ************************/
@@ -684,6 +905,23 @@ func createSkinModel(username string, isSlim bool) *model.Skin {
 	}
 }
 
+func createSkin() []byte {
+	img := image.NewAlpha(image.Rect(0, 0, 64, 64))
+	writer := &bytes.Buffer{}
+	_ = png.Encode(writer, img)
+	pngBytes, _ := ioutil.ReadAll(writer)
+
+	return pngBytes
+}
+
+func createSkinFileModel(hash string) *model.SkinFile {
+	return &model.SkinFile{
+		Hash:       hash,
+		File:       bytes.NewReader(createSkin()),
+		ModifiedAt: time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
 func createCape() []byte {
 	img := image.NewAlpha(image.Rect(0, 0, 64, 32))
 	writer := &bytes.Buffer{}
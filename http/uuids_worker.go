@@ -1,8 +1,12 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"regexp"
+	"sync"
 
 	"github.com/gorilla/mux"
 
@@ -10,35 +14,39 @@ import (
 )
 
 type MojangUuidsProvider interface {
-	GetUuid(username string) (*mojang.ProfileInfo, error)
+	GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error)
 }
 
+type MojangSignedTexturesProvider interface {
+	GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error)
+}
+
+// The worker splits the usernames into batches, that are acceptable by Mojang's API,
+// so the limit only protects it from the unreasonably large requests
+const MaxUsernamesPerBatch = 100
+
+// Mojang's UUIDs are accepted both with and without dashes
+var regexMojangUuid = regexp.MustCompile(`^(?i:[0-9a-f]{32}|[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})$`)
+
 type UUIDsWorker struct {
 	MojangUuidsProvider
+	MojangSignedTexturesProvider
 }
 
 func (ctx *UUIDsWorker) Handler() *mux.Router {
 	router := mux.NewRouter().StrictSlash(true)
 	router.Handle("/mojang-uuid/{username}", http.HandlerFunc(ctx.getUUIDHandler)).Methods("GET")
+	router.Handle("/mojang-uuids", http.HandlerFunc(ctx.getUUIDsHandler)).Methods("POST")
+	router.Handle("/mojang-textures/{uuid}", http.HandlerFunc(ctx.getTexturesHandler)).Methods("GET")
 
 	return router
 }
 
 func (ctx *UUIDsWorker) getUUIDHandler(response http.ResponseWriter, request *http.Request) {
 	username := mux.Vars(request)["username"]
-	profile, err := ctx.GetUuid(username)
+	profile, err := ctx.GetUuid(request.Context(), username)
 	if err != nil {
-		if _, ok := err.(*mojang.TooManyRequestsError); ok {
-			response.WriteHeader(http.StatusTooManyRequests)
-			return
-		}
-
-		response.Header().Set("Content-Type", "application/json")
-		response.WriteHeader(http.StatusInternalServerError)
-		result, _ := json.Marshal(map[string]interface{}{
-			"provider": err.Error(),
-		})
-		_, _ = response.Write(result)
+		writeProviderErrorResponse(response, err)
 		return
 	}
 
@@ -51,3 +59,104 @@ func (ctx *UUIDsWorker) getUUIDHandler(response http.ResponseWriter, request *ht
 	responseData, _ := json.Marshal(profile)
 	_, _ = response.Write(responseData)
 }
+
+type uuidsBatchResult struct {
+	Username string              `json:"username"`
+	Profile  *mojang.ProfileInfo `json:"profile"`
+	Error    *providerError      `json:"error,omitempty"`
+}
+
+type providerError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// Each username is pushed through the provider separately, so the worker's queue can combine
+// them with the usernames from the other requests
+func (ctx *UUIDsWorker) getUUIDsHandler(response http.ResponseWriter, request *http.Request) {
+	var usernames []string
+	if err := json.NewDecoder(request.Body).Decode(&usernames); err != nil {
+		apiBadRequest(response, map[string][]string{
+			"usernames": {"The body must be a JSON array of usernames"},
+		})
+		return
+	}
+
+	if len(usernames) == 0 || len(usernames) > MaxUsernamesPerBatch {
+		apiBadRequest(response, map[string][]string{
+			"usernames": {fmt.Sprintf("The number of usernames must be between 1 and %d", MaxUsernamesPerBatch)},
+		})
+		return
+	}
+
+	results := make([]*uuidsBatchResult, len(usernames))
+	var wg sync.WaitGroup
+	for i, username := range usernames {
+		wg.Add(1)
+		go func(i int, username string) {
+			defer wg.Done()
+			result := &uuidsBatchResult{Username: username}
+			profile, err := ctx.GetUuid(request.Context(), username)
+			if err != nil {
+				result.Error = newProviderError(err)
+			} else {
+				result.Profile = profile
+			}
+
+			results[i] = result
+		}(i, username)
+	}
+
+	wg.Wait()
+
+	response.Header().Set("Content-Type", "application/json")
+	responseData, _ := json.Marshal(results)
+	_, _ = response.Write(responseData)
+}
+
+func (ctx *UUIDsWorker) getTexturesHandler(response http.ResponseWriter, request *http.Request) {
+	uuid := mux.Vars(request)["uuid"]
+	if !regexMojangUuid.MatchString(uuid) {
+		apiBadRequest(response, map[string][]string{
+			"uuid": {"The uuid must be a valid UUID"},
+		})
+		return
+	}
+
+	textures, err := ctx.GetTextures(request.Context(), uuid)
+	if err != nil {
+		writeProviderErrorResponse(response, err)
+		return
+	}
+
+	if textures == nil {
+		response.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	responseData, _ := json.Marshal(textures)
+	_, _ = response.Write(responseData)
+}
+
+func newProviderError(err error) *providerError {
+	if _, ok := err.(*mojang.TooManyRequestsError); ok {
+		return &providerError{http.StatusTooManyRequests, err.Error()}
+	}
+
+	return &providerError{http.StatusInternalServerError, err.Error()}
+}
+
+func writeProviderErrorResponse(response http.ResponseWriter, err error) {
+	if _, ok := err.(*mojang.TooManyRequestsError); ok {
+		response.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/json")
+	response.WriteHeader(http.StatusInternalServerError)
+	result, _ := json.Marshal(map[string]interface{}{
+		"provider": err.Error(),
+	})
+	_, _ = response.Write(result)
+}
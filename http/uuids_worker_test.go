@@ -1,10 +1,12 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -21,7 +23,7 @@ type uuidsProviderMock struct {
 	mock.Mock
 }
 
-func (m *uuidsProviderMock) GetUuid(username string) (*mojang.ProfileInfo, error) {
+func (m *uuidsProviderMock) GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
 	args := m.Called(username)
 	var result *mojang.ProfileInfo
 	if casted, ok := args.Get(0).(*mojang.ProfileInfo); ok {
@@ -31,12 +33,27 @@ func (m *uuidsProviderMock) GetUuid(username string) (*mojang.ProfileInfo, error
 	return result, args.Error(1)
 }
 
+type texturesProviderMock struct {
+	mock.Mock
+}
+
+func (m *texturesProviderMock) GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	args := m.Called(uuid)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
 type uuidsWorkerTestSuite struct {
 	suite.Suite
 
 	App *UUIDsWorker
 
-	UuidsProvider *uuidsProviderMock
+	UuidsProvider    *uuidsProviderMock
+	TexturesProvider *texturesProviderMock
 }
 
 /********************
@@ -45,14 +62,17 @@ type uuidsWorkerTestSuite struct {
 
 func (suite *uuidsWorkerTestSuite) SetupTest() {
 	suite.UuidsProvider = &uuidsProviderMock{}
+	suite.TexturesProvider = &texturesProviderMock{}
 
 	suite.App = &UUIDsWorker{
-		MojangUuidsProvider: suite.UuidsProvider,
+		MojangUuidsProvider:          suite.UuidsProvider,
+		MojangSignedTexturesProvider: suite.TexturesProvider,
 	}
 }
 
 func (suite *uuidsWorkerTestSuite) TearDownTest() {
 	suite.UuidsProvider.AssertExpectations(suite.T())
+	suite.TexturesProvider.AssertExpectations(suite.T())
 }
 
 func (suite *uuidsWorkerTestSuite) RunSubTest(name string, subTest func()) {
@@ -152,3 +172,211 @@ func (suite *uuidsWorkerTestSuite) TestGetUUID() {
 		})
 	}
 }
+
+func (suite *uuidsWorkerTestSuite) TestGetUUIDs() {
+	suite.RunSubTest("Resolve usernames batch", func() {
+		suite.UuidsProvider.On("GetUuid", "username1").Return(&mojang.ProfileInfo{
+			Id:   "0fcc38620f1845f3a54e1b523c1bd1c7",
+			Name: "username1",
+		}, nil)
+		suite.UuidsProvider.On("GetUuid", "username2").Return(nil, nil)
+		suite.UuidsProvider.On("GetUuid", "username3").Return(nil, &mojang.TooManyRequestsError{})
+		suite.UuidsProvider.On("GetUuid", "username4").Return(nil, errors.New("this is an error"))
+
+		req := httptest.NewRequest("POST", "http://chrly/mojang-uuids", strings.NewReader(`["username1","username2","username3","username4"]`))
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		response := w.Result()
+		suite.Equal(200, response.StatusCode)
+		suite.Equal("application/json", response.Header.Get("Content-Type"))
+		body, _ := ioutil.ReadAll(response.Body)
+		suite.JSONEq(`[
+			{
+				"username": "username1",
+				"profile": {
+					"id": "0fcc38620f1845f3a54e1b523c1bd1c7",
+					"name": "username1"
+				}
+			},
+			{
+				"username": "username2",
+				"profile": null
+			},
+			{
+				"username": "username3",
+				"profile": null,
+				"error": {
+					"status": 429,
+					"message": "429: Too Many Requests"
+				}
+			},
+			{
+				"username": "username4",
+				"profile": null,
+				"error": {
+					"status": 500,
+					"message": "this is an error"
+				}
+			}
+		]`, string(body))
+	})
+
+	suite.RunSubTest("Receive invalid body", func() {
+		req := httptest.NewRequest("POST", "http://chrly/mojang-uuids", strings.NewReader(`{"username": "username1"}`))
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		response := w.Result()
+		suite.Equal(400, response.StatusCode)
+		body, _ := ioutil.ReadAll(response.Body)
+		suite.JSONEq(`{
+			"errors": {
+				"usernames": [
+					"The body must be a JSON array of usernames"
+				]
+			}
+		}`, string(body))
+	})
+
+	suite.RunSubTest("Receive empty usernames list", func() {
+		req := httptest.NewRequest("POST", "http://chrly/mojang-uuids", strings.NewReader(`[]`))
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		response := w.Result()
+		suite.Equal(400, response.StatusCode)
+		body, _ := ioutil.ReadAll(response.Body)
+		suite.JSONEq(`{
+			"errors": {
+				"usernames": [
+					"The number of usernames must be between 1 and 100"
+				]
+			}
+		}`, string(body))
+	})
+}
+
+/****************************
+ * Get textures tests cases *
+ ****************************/
+
+var getTexturesTestsCases = []*uuidsWorkerTestCase{
+	{
+		Name: "Success provider response",
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			suite.TexturesProvider.On("GetTextures", "0fcc38620f1845f3a54e1b523c1bd1c7").Return(&mojang.SignedTexturesResponse{
+				Id:   "0fcc38620f1845f3a54e1b523c1bd1c7",
+				Name: "mock_username",
+				Props: []*mojang.Property{
+					{
+						Name:      "textures",
+						Signature: "mock_signature",
+						Value:     "mock_value",
+					},
+				},
+			}, nil)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(200, response.StatusCode)
+			suite.Equal("application/json", response.Header.Get("Content-Type"))
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`{
+				"id": "0fcc38620f1845f3a54e1b523c1bd1c7",
+				"name": "mock_username",
+				"properties": [
+					{
+						"name": "textures",
+						"signature": "mock_signature",
+						"value": "mock_value"
+					}
+				]
+			}`, string(body))
+		},
+	},
+	{
+		Name: "Receive empty response from textures provider",
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			suite.TexturesProvider.On("GetTextures", "0fcc38620f1845f3a54e1b523c1bd1c7").Return(nil, nil)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(204, response.StatusCode)
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.Assert().Empty(body)
+		},
+	},
+	{
+		Name: "Receive error from textures provider",
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			err := errors.New("this is an error")
+			suite.TexturesProvider.On("GetTextures", "0fcc38620f1845f3a54e1b523c1bd1c7").Return(nil, err)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(500, response.StatusCode)
+			suite.Equal("application/json", response.Header.Get("Content-Type"))
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.JSONEq(`{
+				"provider": "this is an error"
+			}`, string(body))
+		},
+	},
+	{
+		Name: "Receive Too Many Requests from textures provider",
+		BeforeTest: func(suite *uuidsWorkerTestSuite) {
+			err := &mojang.TooManyRequestsError{}
+			suite.TexturesProvider.On("GetTextures", "0fcc38620f1845f3a54e1b523c1bd1c7").Return(nil, err)
+		},
+		AfterTest: func(suite *uuidsWorkerTestSuite, response *http.Response) {
+			suite.Equal(429, response.StatusCode)
+			body, _ := ioutil.ReadAll(response.Body)
+			suite.Empty(body)
+		},
+	},
+}
+
+func (suite *uuidsWorkerTestSuite) TestGetTextures() {
+	for _, testCase := range getTexturesTestsCases {
+		suite.RunSubTest(testCase.Name, func() {
+			testCase.BeforeTest(suite)
+
+			req := httptest.NewRequest("GET", "http://chrly/mojang-textures/0fcc38620f1845f3a54e1b523c1bd1c7", nil)
+			w := httptest.NewRecorder()
+
+			suite.App.Handler().ServeHTTP(w, req)
+
+			testCase.AfterTest(suite, w.Result())
+		})
+	}
+
+	suite.RunSubTest("Accept dashed uuid", func() {
+		suite.TexturesProvider.On("GetTextures", "0fcc3862-0f18-45f3-a54e-1b523c1bd1c7").Return(nil, nil)
+
+		req := httptest.NewRequest("GET", "http://chrly/mojang-textures/0fcc3862-0f18-45f3-a54e-1b523c1bd1c7", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		suite.Equal(204, w.Result().StatusCode)
+	})
+
+	suite.RunSubTest("Receive invalid uuid", func() {
+		req := httptest.NewRequest("GET", "http://chrly/mojang-textures/0fcc38620f1845f3a54e1b523c1bd1c7%3Funsigned=true", nil)
+		w := httptest.NewRecorder()
+
+		suite.App.Handler().ServeHTTP(w, req)
+
+		response := w.Result()
+		suite.Equal(400, response.StatusCode)
+		body, _ := ioutil.ReadAll(response.Body)
+		suite.JSONEq(`{
+			"errors": {
+				"uuid": [
+					"The uuid must be a valid UUID"
+				]
+			}
+		}`, string(body))
+	})
+}
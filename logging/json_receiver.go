@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/mono83/slf"
+)
+
+var placeholderRegex = regexp.MustCompile(`:\w+`)
+
+var levels = map[byte]string{
+	slf.TypeTrace:     "trace",
+	slf.TypeDebug:     "debug",
+	slf.TypeInfo:      "info",
+	slf.TypeWarning:   "warning",
+	slf.TypeError:     "error",
+	slf.TypeAlert:     "alert",
+	slf.TypeEmergency: "emergency",
+}
+
+// JsonReceiver writes each log event as a single JSON object per line. Besides the message
+// with the substituted placeholders, the object contains all the event's params as separate fields
+type JsonReceiver struct {
+	Target io.Writer
+
+	lock sync.Mutex
+}
+
+func (r *JsonReceiver) Receive(event slf.Event) {
+	level, ok := levels[event.Type]
+	if !ok {
+		return
+	}
+
+	fields := make(map[string]interface{}, len(event.Params)+3)
+	for _, param := range event.Params {
+		if param == nil {
+			continue
+		}
+
+		value := param.GetRaw()
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+
+		fields[param.GetKey()] = value
+	}
+
+	eventTime := event.Time
+	if eventTime.IsZero() {
+		eventTime = time.Now()
+	}
+
+	// The reserved fields are set last, so the params can't override them
+	fields["time"] = eventTime.Format(time.RFC3339Nano)
+	fields["level"] = level
+	fields["message"] = placeholderRegex.ReplaceAllStringFunc(event.Content, func(placeholder string) string {
+		for _, param := range event.Params {
+			if param != nil && param.GetKey() == placeholder[1:] {
+				return fmt.Sprint(param.GetRaw())
+			}
+		}
+
+		return placeholder
+	})
+
+	line, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, _ = r.Target.Write(append(line, '\n'))
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mono83/slf"
+	"github.com/mono83/slf/params"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJsonReceiver_Receive(t *testing.T) {
+	t.Run("should write the event with params as a single line", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		receiver := &JsonReceiver{Target: buffer}
+
+		receiver.Receive(slf.Event{
+			Type:    slf.TypeWarning,
+			Time:    time.Date(2020, 5, 1, 12, 30, 15, 123000000, time.UTC),
+			Content: ":name: unexpected response for :username: :err",
+			Params: []slf.Param{
+				params.String{Key: "name", Value: "textures"},
+				params.String{Key: "username", Value: "mock_username"},
+				params.Int{Key: "attempt", Value: 2},
+				params.Error{Key: "err", Value: errors.New("mock error")},
+			},
+		})
+
+		assert.JSONEq(t, `{
+			"time": "2020-05-01T12:30:15.123Z",
+			"level": "warning",
+			"message": "textures: unexpected response for mock_username: mock error",
+			"name": "textures",
+			"username": "mock_username",
+			"attempt": 2,
+			"err": "mock error"
+		}`, buffer.String())
+		assert.Equal(t, byte('\n'), buffer.Bytes()[buffer.Len()-1])
+	})
+
+	t.Run("should keep unknown placeholders and reserved fields", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		receiver := &JsonReceiver{Target: buffer}
+
+		receiver.Receive(slf.Event{
+			Type:    slf.TypeInfo,
+			Time:    time.Date(2020, 5, 1, 12, 30, 15, 0, time.UTC),
+			Content: "the :unknown placeholder",
+			Params: []slf.Param{
+				params.String{Key: "level", Value: "overridden"},
+			},
+		})
+
+		assert.JSONEq(t, `{
+			"time": "2020-05-01T12:30:15Z",
+			"level": "info",
+			"message": "the :unknown placeholder"
+		}`, buffer.String())
+	})
+
+	t.Run("should skip metrics events", func(t *testing.T) {
+		buffer := &bytes.Buffer{}
+		receiver := &JsonReceiver{Target: buffer}
+
+		receiver.Receive(slf.Event{
+			Type:    slf.TypeInc,
+			Content: "mojang_textures.request",
+			I64:     1,
+		})
+
+		assert.Empty(t, buffer.String())
+	})
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"context"
+	"time"
+)
+
+// Textures sources, that can be recorded for the request
+const (
+	LocalTexturesSource       = "local"
+	MojangCacheTexturesSource = "mojang_cache"
+	MojangCallTexturesSource  = "mojang_call"
+)
+
+type requestInfoKey struct{}
+
+// RequestInfo collects the details of the request, that are written into the access log.
+// It's filled by the goroutine, that handles the request, so it isn't safe for the concurrent use
+type RequestInfo struct {
+	Id             string
+	StartedAt      time.Time
+	Route          string
+	TexturesSource string
+	ResponseSize   int64
+}
+
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns nil when the context doesn't belong to the request
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+
+	return info
+}
+
+// SetTexturesSource does nothing when the context doesn't belong to the request
+func SetTexturesSource(ctx context.Context, source string) {
+	if info := RequestInfoFromContext(ctx); info != nil {
+		info.TexturesSource = source
+	}
+}
@@ -0,0 +1,28 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTexturesSource(t *testing.T) {
+	t.Run("should record the source into the request info", func(t *testing.T) {
+		info := &RequestInfo{Id: "mock-request-id"}
+		ctx := WithRequestInfo(context.Background(), info)
+
+		SetTexturesSource(ctx, MojangCallTexturesSource)
+
+		assert.Same(t, info, RequestInfoFromContext(ctx))
+		assert.Equal(t, MojangCallTexturesSource, info.TexturesSource)
+	})
+
+	t.Run("should do nothing for the context without the request info", func(t *testing.T) {
+		ctx := context.Background()
+
+		SetTexturesSource(ctx, MojangCallTexturesSource)
+
+		assert.Nil(t, RequestInfoFromContext(ctx))
+	})
+}
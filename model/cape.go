@@ -5,5 +5,6 @@ import (
 )
 
 type Cape struct {
-	File io.Reader
+	Username string
+	File     io.Reader
 }
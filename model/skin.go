@@ -1,5 +1,10 @@
 package model
 
+import (
+	"io"
+	"time"
+)
+
 type Skin struct {
 	UserId          int    `json:"userId"`
 	Uuid            string `json:"uuid"`
@@ -10,5 +15,13 @@ type Skin struct {
 	IsSlim          bool   `json:"isSlim"`
 	MojangTextures  string `json:"mojangTextures"`
 	MojangSignature string `json:"mojangSignature"`
-	OldUsername     string
+	// SkinHash is set only when the skin file was uploaded to Chrly and is stored in the skins files repository
+	SkinHash    string `json:"skinHash,omitempty"`
+	OldUsername string
+}
+
+type SkinFile struct {
+	Hash       string
+	File       io.ReadSeeker
+	ModifiedAt time.Time
 }
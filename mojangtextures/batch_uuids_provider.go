@@ -6,7 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/tracing"
 )
 
 type jobResult struct {
@@ -15,8 +19,16 @@ type jobResult struct {
 }
 
 type job struct {
+	// Context is used to determine whether the job was abandoned by its caller
+	Context     context.Context
 	Username    string
 	RespondChan chan *jobResult
+	// Requeues is the number of times the job has been put back to the queue by the strategy
+	Requeues int
+}
+
+func (j *job) IsAbandoned() bool {
+	return j.Context != nil && j.Context.Err() != nil
 }
 
 type jobsQueue struct {
@@ -39,19 +51,47 @@ func (s *jobsQueue) Enqueue(job *job) int {
 	return len(s.items)
 }
 
+// Abandoned jobs are removed from the queue without being returned
 func (s *jobsQueue) Dequeue(n int) ([]*job, int) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	l := len(s.items)
-	if n > l {
-		n = l
+	items := make([]*job, 0, n)
+	i := 0
+	for ; i < len(s.items) && len(items) < n; i++ {
+		if !s.items[i].IsAbandoned() {
+			items = append(items, s.items[i])
+		}
+	}
+
+	s.items = s.items[i:]
+
+	return items, len(s.items)
+}
+
+// Requeue puts the jobs back to the beginning of the queue, so they will be processed first.
+// Abandoned jobs are dropped
+func (s *jobsQueue) Requeue(jobs []*job) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	items := make([]*job, 0, len(jobs)+len(s.items))
+	for _, job := range jobs {
+		if !job.IsAbandoned() {
+			items = append(items, job)
+		}
 	}
 
-	items := s.items[0:n]
-	s.items = s.items[n:l]
+	s.items = append(items, s.items...)
 
-	return items, l - n
+	return len(s.items)
+}
+
+func (s *jobsQueue) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.items)
 }
 
 var usernamesToUuids = mojang.UsernamesToUuids
@@ -73,6 +113,15 @@ type BatchUuidsProviderStrategy interface {
 	GetJobs(abort context.Context) <-chan *JobsIteration
 }
 
+// RateLimitAwareStrategy is implemented by the strategies, that adjust their behaviour
+// depending on the results of the performed requests
+type RateLimitAwareStrategy interface {
+	// Report receives the result of the request for the iteration's jobs and returns the jobs,
+	// that must be responded with this result. The rest of the jobs have been re-queued by the strategy,
+	// so they must not be responded yet
+	Report(jobs []*job, err error) []*job
+}
+
 type PeriodicStrategy struct {
 	Delay time.Duration
 	Batch int
@@ -162,6 +211,168 @@ func (ctx *FullBusStrategy) sendJobs(ch chan *JobsIteration) {
 	ch <- &JobsIteration{jobs, queueLen, nil}
 }
 
+// TokenBucketStrategy models Mojang's requests budget as a token bucket: each request takes one token
+// and tokens are refilled with the constant rate up to the bucket's capacity. When Mojang responds
+// with the 429 error, the bucket is emptied, the jobs are re-queued and the requests are paused
+// for the exponentially increasing backoff duration. The job is re-queued at most MaxRequeues times,
+// after that the 429 error is returned to its caller
+type TokenBucketStrategy struct {
+	Batch          int
+	Capacity       int
+	RefillInterval time.Duration
+	Backoff        time.Duration
+	MaxBackoff     time.Duration
+	MaxRequeues    int
+
+	queue  *jobsQueue
+	notify chan struct{}
+
+	lock           sync.Mutex
+	tokens         int
+	lastRefill     time.Time
+	currentBackoff time.Duration
+	pausedUntil    time.Time
+}
+
+func NewTokenBucketStrategy(
+	batch int,
+	capacity int,
+	refillInterval time.Duration,
+	backoff time.Duration,
+	maxBackoff time.Duration,
+	maxRequeues int,
+) *TokenBucketStrategy {
+	return &TokenBucketStrategy{
+		Batch:          batch,
+		Capacity:       capacity,
+		RefillInterval: refillInterval,
+		Backoff:        backoff,
+		MaxBackoff:     maxBackoff,
+		MaxRequeues:    maxRequeues,
+		queue:          newJobsQueue(),
+		notify:         make(chan struct{}, 1),
+		tokens:         capacity,
+		lastRefill:     now(),
+	}
+}
+
+func (ctx *TokenBucketStrategy) Queue(job *job) {
+	ctx.queue.Enqueue(job)
+	ctx.wakeUp()
+}
+
+func (ctx *TokenBucketStrategy) GetJobs(abort context.Context) <-chan *JobsIteration {
+	ch := make(chan *JobsIteration)
+	go func() {
+		for {
+			var wait <-chan time.Time
+			// The jobs are taken before the token, so the queue of the abandoned jobs won't waste the token
+			if jobs, queueLen := ctx.queue.Dequeue(ctx.Batch); len(jobs) > 0 {
+				delay := ctx.takeToken()
+				if delay == 0 {
+					select {
+					case <-abort.Done():
+						close(ch)
+						return
+					case ch <- &JobsIteration{jobs, queueLen, nil}:
+					}
+
+					continue
+				}
+
+				ctx.queue.Requeue(jobs)
+				wait = time.After(delay)
+			}
+
+			select {
+			case <-abort.Done():
+				close(ch)
+				return
+			case <-wait:
+			case <-ctx.notify:
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (ctx *TokenBucketStrategy) Report(jobs []*job, err error) []*job {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	if _, ok := err.(*mojang.TooManyRequestsError); !ok {
+		ctx.currentBackoff = 0
+		return jobs
+	}
+
+	if ctx.currentBackoff == 0 {
+		ctx.currentBackoff = ctx.Backoff
+	} else {
+		ctx.currentBackoff *= 2
+	}
+
+	if ctx.currentBackoff > ctx.MaxBackoff {
+		ctx.currentBackoff = ctx.MaxBackoff
+	}
+
+	ctx.tokens = 0
+	ctx.lastRefill = now()
+	ctx.pausedUntil = ctx.lastRefill.Add(ctx.currentBackoff)
+
+	var requeued, exhausted []*job
+	for _, job := range jobs {
+		if job.Requeues >= ctx.MaxRequeues {
+			exhausted = append(exhausted, job)
+			continue
+		}
+
+		job.Requeues++
+		requeued = append(requeued, job)
+	}
+
+	ctx.queue.Requeue(requeued)
+	ctx.wakeUp()
+
+	return exhausted
+}
+
+// Returns zero duration if the token was taken or the duration to wait before the next attempt
+func (ctx *TokenBucketStrategy) takeToken() time.Duration {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	currentTime := now()
+	if currentTime.Before(ctx.pausedUntil) {
+		return ctx.pausedUntil.Sub(currentTime)
+	}
+
+	refilled := int(currentTime.Sub(ctx.lastRefill) / ctx.RefillInterval)
+	if refilled > 0 {
+		ctx.tokens += refilled
+		if ctx.tokens > ctx.Capacity {
+			ctx.tokens = ctx.Capacity
+		}
+
+		ctx.lastRefill = ctx.lastRefill.Add(time.Duration(refilled) * ctx.RefillInterval)
+	}
+
+	if ctx.tokens == 0 {
+		return ctx.lastRefill.Add(ctx.RefillInterval).Sub(currentTime)
+	}
+
+	ctx.tokens--
+
+	return 0
+}
+
+func (ctx *TokenBucketStrategy) wakeUp() {
+	select {
+	case ctx.notify <- struct{}{}:
+	default:
+	}
+}
+
 type BatchUuidsProvider struct {
 	context     context.Context
 	emitter     Emitter
@@ -181,32 +392,44 @@ func NewBatchUuidsProvider(
 	}
 }
 
-func (ctx *BatchUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo, error) {
-	ctx.onFirstCall.Do(ctx.startQueue)
+func (p *BatchUuidsProvider) GetUuid(ctx context.Context, username string) (profile *mojang.ProfileInfo, err error) {
+	p.onFirstCall.Do(p.startQueue)
 
-	resultChan := make(chan *jobResult)
-	ctx.strategy.Queue(&job{username, resultChan})
-	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:queued", username)
+	// The span covers the time spent in the queue, while the request itself is traced by the performRequest
+	ctx, span := tracer.Start(ctx, "mojangtextures.BatchUuidsProvider.GetUuid", trace.WithAttributes(
+		attribute.String("mojang.username", username),
+	))
+	defer func() {
+		tracing.EndSpan(span, err)
+	}()
 
-	result := <-resultChan
+	// The chan is buffered, so the queue will not be blocked when the job is abandoned
+	resultChan := make(chan *jobResult, 1)
+	p.strategy.Queue(&job{Context: ctx, Username: username, RespondChan: resultChan})
+	p.emitter.Emit("mojang_textures:batch_uuids_provider:queued", username)
 
-	return result.Profile, result.Error
+	select {
+	case result := <-resultChan:
+		return result.Profile, result.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-func (ctx *BatchUuidsProvider) startQueue() {
+func (p *BatchUuidsProvider) startQueue() {
 	// This synchronization chan is used to ensure that strategy's jobs provider
 	// will be initialized before any job will be scheduled
 	d := make(chan struct{})
 	go func() {
-		jobsChan := ctx.strategy.GetJobs(ctx.context)
+		jobsChan := p.strategy.GetJobs(p.context)
 		close(d)
 		for {
 			select {
-			case <-ctx.context.Done():
+			case <-p.context.Done():
 				return
 			case iteration := <-jobsChan:
 				go func() {
-					ctx.performRequest(iteration)
+					p.performRequest(iteration)
 					iteration.Done()
 				}()
 			}
@@ -216,20 +439,49 @@ func (ctx *BatchUuidsProvider) startQueue() {
 	<-d
 }
 
-func (ctx *BatchUuidsProvider) performRequest(iteration *JobsIteration) {
+func (p *BatchUuidsProvider) performRequest(iteration *JobsIteration) {
 	usernames := make([]string, len(iteration.Jobs))
 	for i, job := range iteration.Jobs {
 		usernames[i] = job.Username
 	}
 
-	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:round", usernames, iteration.Queue)
+	p.emitter.Emit("mojang_textures:batch_uuids_provider:round", usernames, iteration.Queue)
 	if len(usernames) == 0 {
 		return
 	}
 
-	profiles, err := usernamesToUuids(usernames)
-	ctx.emitter.Emit("mojang_textures:batch_uuids_provider:result", usernames, profiles, err)
+	// The request is shared by several callers, so it's linked to each of their traces instead of being a child of one
+	links := make([]trace.Link, 0, len(iteration.Jobs))
 	for _, job := range iteration.Jobs {
+		if job.Context != nil {
+			links = append(links, trace.LinkFromContext(job.Context))
+		}
+	}
+
+	// Nobody needs the result, when all the callers are gone, so the request is cancelled with the last of them
+	requestContext, cancel := withJobsContexts(p.context, iteration.Jobs)
+	defer cancel()
+
+	requestContext, span := tracer.Start(requestContext, "mojangtextures.BatchUuidsProvider.performRequest",
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.StringSlice("mojang.usernames", usernames),
+			attribute.Int("mojang.queue_size", iteration.Queue),
+		),
+	)
+	profiles, err := usernamesToUuids(requestContext, usernames)
+	tracing.EndSpan(span, err)
+	p.emitter.Emit("mojang_textures:batch_uuids_provider:result", usernames, profiles, err)
+
+	jobs := iteration.Jobs
+	if strategy, ok := p.strategy.(RateLimitAwareStrategy); ok {
+		jobs = strategy.Report(iteration.Jobs, err)
+		if requeued := getRequeuedUsernames(iteration.Jobs, jobs); len(requeued) > 0 {
+			p.emitter.Emit("mojang_textures:batch_uuids_provider:requeued", requeued)
+		}
+	}
+
+	for _, job := range jobs {
 		response := &jobResult{}
 		if err == nil {
 			// The profiles in the response aren't ordered, so we must search each username over full array
@@ -247,3 +499,44 @@ func (ctx *BatchUuidsProvider) performRequest(iteration *JobsIteration) {
 		close(job.RespondChan)
 	}
 }
+
+// Returns the context, that is cancelled when the parent is done or all the jobs are abandoned.
+// The jobs without the context are never abandoned, so the context is bound only to the parent in this case
+func withJobsContexts(parent context.Context, jobs []*job) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	for _, job := range jobs {
+		if job.Context == nil {
+			return ctx, cancel
+		}
+	}
+
+	go func() {
+		for _, job := range jobs {
+			select {
+			case <-job.Context.Done():
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		cancel()
+	}()
+
+	return ctx, cancel
+}
+
+func getRequeuedUsernames(jobs []*job, respondedJobs []*job) []string {
+	responded := make(map[*job]bool, len(respondedJobs))
+	for _, job := range respondedJobs {
+		responded[job] = true
+	}
+
+	var usernames []string
+	for _, job := range jobs {
+		if !responded[job] {
+			usernames = append(usernames, job.Username)
+		}
+	}
+
+	return usernames
+}
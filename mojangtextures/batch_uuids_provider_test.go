@@ -44,13 +44,49 @@ func TestJobsQueue(t *testing.T) {
 		require.Equal(t, "username4", items[1].Username)
 		require.Equal(t, "username5", items[2].Username)
 	})
+
+	t.Run("Requeue", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := newJobsQueue()
+		s.Enqueue(&job{Username: "username3"})
+
+		require.Equal(t, 3, s.Requeue([]*job{
+			{Username: "username1"},
+			{Username: "abandoned", Context: ctx},
+			{Username: "username2"},
+		}))
+
+		items, _ := s.Dequeue(3)
+		require.Equal(t, "username1", items[0].Username)
+		require.Equal(t, "username2", items[1].Username)
+		require.Equal(t, "username3", items[2].Username)
+	})
+
+	t.Run("Dequeue should skip abandoned jobs", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := newJobsQueue()
+		s.Enqueue(&job{Username: "username1"})
+		s.Enqueue(&job{Username: "username2", Context: ctx})
+		s.Enqueue(&job{Username: "username3"})
+		s.Enqueue(&job{Username: "username4", Context: context.Background()})
+
+		items, queueLen := s.Dequeue(2)
+		require.Len(t, items, 2)
+		require.Equal(t, 1, queueLen)
+		require.Equal(t, "username1", items[0].Username)
+		require.Equal(t, "username3", items[1].Username)
+	})
 }
 
 type mojangUsernamesToUuidsRequestMock struct {
 	mock.Mock
 }
 
-func (o *mojangUsernamesToUuidsRequestMock) UsernamesToUuids(usernames []string) ([]*mojang.ProfileInfo, error) {
+func (o *mojangUsernamesToUuidsRequestMock) UsernamesToUuids(ctx context.Context, usernames []string) ([]*mojang.ProfileInfo, error) {
 	args := o.Called(usernames)
 	var result []*mojang.ProfileInfo
 	if casted, ok := args.Get(0).([]*mojang.ProfileInfo); ok {
@@ -122,7 +158,7 @@ func (suite *batchUuidsProviderTestSuite) GetUuidAsync(username string) <-chan *
 
 	c := make(chan *batchUuidsProviderGetUuidResult)
 	go func() {
-		profile, err := suite.Provider.GetUuid(username)
+		profile, err := suite.Provider.GetUuid(context.Background(), username)
 		c <- &batchUuidsProviderGetUuidResult{
 			Result: profile,
 			Error:  err,
@@ -228,6 +264,129 @@ func (suite *batchUuidsProviderTestSuite) TestGetUuidForFewUsernamesWithAnError(
 	suite.Assert().Equal(expectedError, result2.Error)
 }
 
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWithCancelledContext() {
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:queued", "username").Once().Run(func(args mock.Arguments) {
+		cancel()
+	})
+
+	result, err := suite.Provider.GetUuid(ctx, "username")
+	suite.Assert().Nil(result)
+	suite.Assert().Equal(context.Canceled, err)
+	suite.Assert().True(suite.Strategy.jobs[0].IsAbandoned())
+}
+
+func (suite *batchUuidsProviderTestSuite) TestCancelRequestWhenAllCallersAreGone() {
+	expectedUsernames := []string{"username1", "username2"}
+	var nilProfilesResponse []*mojang.ProfileInfo
+
+	requestStarted := make(chan struct{})
+	usernamesToUuids = func(ctx context.Context, usernames []string) ([]*mojang.ProfileInfo, error) {
+		close(requestStarted)
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	resultEmitted := make(chan struct{})
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0).Once()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, context.Canceled).Once().Run(func(args mock.Arguments) {
+		close(resultEmitted)
+	})
+
+	var cancels []context.CancelFunc
+	for _, username := range expectedUsernames {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancels = append(cancels, cancel)
+
+		queued := make(chan struct{})
+		suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:queued", username).Once().Run(func(args mock.Arguments) {
+			close(queued)
+		})
+		go func(username string) {
+			_, _ = suite.Provider.GetUuid(ctx, username)
+		}(username)
+		<-queued
+	}
+
+	suite.Strategy.Iterate(2, 0)
+	<-requestStarted
+
+	cancels[0]()
+	select {
+	case <-resultEmitted:
+		suite.Fail("the request must not be cancelled while one of the callers still waits for it")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancels[1]()
+	select {
+	case <-resultEmitted:
+	case <-time.After(time.Second):
+		suite.Fail("the request hasn't been cancelled")
+	}
+}
+
+type rateLimitAwareManualStrategy struct {
+	manualStrategy
+	mock.Mock
+}
+
+func (m *rateLimitAwareManualStrategy) Report(jobs []*job, err error) []*job {
+	switch result := m.Called(jobs, err).Get(0).(type) {
+	case func([]*job, error) []*job:
+		return result(jobs, err)
+	case []*job:
+		return result
+	default:
+		return nil
+	}
+}
+
+func (suite *batchUuidsProviderTestSuite) TestGetUuidWithRequeuedJobs() {
+	strategy := &rateLimitAwareManualStrategy{}
+	suite.Provider = NewBatchUuidsProvider(context.Background(), strategy, suite.Emitter)
+	defer strategy.AssertExpectations(suite.T())
+
+	expectedUsernames := []string{"username1"}
+	expectedError := &mojang.TooManyRequestsError{}
+	expectedResult := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username1"}
+	var nilProfilesResponse []*mojang.ProfileInfo
+
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:round", expectedUsernames, 0).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, nilProfilesResponse, expectedError).Once()
+	requeued := make(chan struct{})
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:requeued", expectedUsernames).Once().Run(func(args mock.Arguments) {
+		close(requeued)
+	})
+	suite.Emitter.On("Emit", "mojang_textures:batch_uuids_provider:result", expectedUsernames, []*mojang.ProfileInfo{expectedResult}, nil).Once()
+
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return(nil, expectedError)
+	suite.MojangApi.On("UsernamesToUuids", expectedUsernames).Once().Return([]*mojang.ProfileInfo{expectedResult}, nil)
+
+	strategy.On("Report", mock.Anything, expectedError).Once().Return(nil)
+	strategy.On("Report", mock.Anything, nil).Once().Return(func(jobs []*job, err error) []*job {
+		return jobs
+	})
+
+	resultChan := suite.GetUuidAsync("username1")
+
+	strategy.Iterate(1, 0)
+	<-requeued
+
+	select {
+	case <-resultChan:
+		suite.Fail("the requeued job must not be responded")
+	default:
+	}
+
+	strategy.Iterate(1, 0)
+
+	result := <-resultChan
+	suite.Assert().Equal(expectedResult, result.Result)
+	suite.Assert().Nil(result.Error)
+}
+
 func TestPeriodicStrategy(t *testing.T) {
 	t.Run("should return first job only after duration", func(t *testing.T) {
 		d := 20 * time.Millisecond
@@ -438,3 +597,137 @@ func TestFullBusStrategy(t *testing.T) {
 		cancel()
 	})
 }
+
+func TestTokenBucketStrategy(t *testing.T) {
+	t.Run("should spend the tokens and wait for the refill", func(t *testing.T) {
+		d := 20 * time.Millisecond
+		strategy := NewTokenBucketStrategy(1, 2, d, time.Minute, time.Minute, 5)
+		for i := 0; i < 3; i++ {
+			strategy.Queue(&job{Username: strconv.Itoa(i)})
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		startedAt := time.Now()
+		ch := strategy.GetJobs(ctx)
+
+		iteration := <-ch
+		require.Equal(t, "0", iteration.Jobs[0].Username)
+		require.Equal(t, 2, iteration.Queue)
+
+		iteration = <-ch
+		require.Equal(t, "1", iteration.Jobs[0].Username)
+		require.Equal(t, 1, iteration.Queue)
+		require.True(t, time.Since(startedAt) < d, "the tokens from the initial capacity should be spent immediately")
+
+		iteration = <-ch
+		require.Equal(t, "2", iteration.Jobs[0].Username)
+		require.Equal(t, 0, iteration.Queue)
+		require.True(t, time.Since(startedAt) >= d, "the last iteration should be performed only after the refill")
+	})
+
+	t.Run("should not return iterations when the queue is empty", func(t *testing.T) {
+		strategy := NewTokenBucketStrategy(10, 1, time.Millisecond, time.Minute, time.Minute, 5)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := strategy.GetJobs(ctx)
+
+		select {
+		case <-ch:
+			require.Fail(t, "iteration should not be provided for the empty queue")
+		case <-time.After(5 * time.Millisecond):
+		}
+
+		strategy.Queue(&job{Username: "username"})
+
+		select {
+		case iteration := <-ch:
+			require.Len(t, iteration.Jobs, 1)
+		case <-time.After(5 * time.Millisecond):
+			require.Fail(t, "iteration should be provided as soon as the job is queued")
+		}
+	})
+
+	t.Run("should not spend the tokens on the abandoned jobs", func(t *testing.T) {
+		strategy := NewTokenBucketStrategy(10, 1, time.Minute, time.Minute, time.Minute, 5)
+		abandonedCtx, abandon := context.WithCancel(context.Background())
+		abandon()
+		strategy.Queue(&job{Username: "abandoned", Context: abandonedCtx})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := strategy.GetJobs(ctx)
+
+		select {
+		case <-ch:
+			require.Fail(t, "iteration should not be provided for the abandoned jobs")
+		case <-time.After(5 * time.Millisecond):
+		}
+
+		strategy.Queue(&job{Username: "username"})
+
+		select {
+		case iteration := <-ch:
+			require.Len(t, iteration.Jobs, 1)
+			require.Equal(t, "username", iteration.Jobs[0].Username)
+		case <-time.After(5 * time.Millisecond):
+			require.Fail(t, "the token should be spent on the job, that is still waited for")
+		}
+	})
+
+	t.Run("should requeue the jobs and pause after the 429 error", func(t *testing.T) {
+		d := 20 * time.Millisecond
+		strategy := NewTokenBucketStrategy(10, 10, time.Millisecond, d, time.Minute, 5)
+		j := &job{Username: "username"}
+		strategy.Queue(j)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch := strategy.GetJobs(ctx)
+		iteration := <-ch
+
+		requeuedAt := time.Now()
+		require.Empty(t, strategy.Report(iteration.Jobs, &mojang.TooManyRequestsError{}))
+
+		iteration = <-ch
+		require.Equal(t, []*job{j}, iteration.Jobs)
+		require.True(t, time.Since(requeuedAt) >= d, "the iteration should be provided only after the backoff")
+	})
+
+	t.Run("should increase the backoff exponentially up to the limit", func(t *testing.T) {
+		strategy := NewTokenBucketStrategy(10, 10, time.Millisecond, time.Second, 3*time.Second, 5)
+
+		strategy.Report(nil, &mojang.TooManyRequestsError{})
+		require.Equal(t, time.Second, strategy.currentBackoff)
+
+		strategy.Report(nil, &mojang.TooManyRequestsError{})
+		require.Equal(t, 2*time.Second, strategy.currentBackoff)
+
+		strategy.Report(nil, &mojang.TooManyRequestsError{})
+		require.Equal(t, 3*time.Second, strategy.currentBackoff)
+
+		require.Empty(t, strategy.Report(nil, nil))
+		require.Equal(t, time.Duration(0), strategy.currentBackoff)
+	})
+
+	t.Run("should not requeue the jobs for other errors", func(t *testing.T) {
+		strategy := NewTokenBucketStrategy(10, 10, time.Millisecond, time.Second, time.Minute, 5)
+		jobs := []*job{{Username: "username"}}
+		require.Equal(t, jobs, strategy.Report(jobs, &mojang.ServerError{Status: 500}))
+		require.Equal(t, 0, strategy.queue.Len())
+	})
+
+	t.Run("should return the jobs, that have exhausted the requeues limit", func(t *testing.T) {
+		strategy := NewTokenBucketStrategy(10, 10, time.Millisecond, time.Second, time.Minute, 1)
+		exhaustedJob := &job{Username: "username1", Requeues: 1}
+		requeuedJob := &job{Username: "username2"}
+
+		require.Equal(t, []*job{exhaustedJob}, strategy.Report([]*job{exhaustedJob, requeuedJob}, &mojang.TooManyRequestsError{}))
+		require.Equal(t, 1, strategy.queue.Len())
+		require.Equal(t, 1, requeuedJob.Requeues)
+	})
+}
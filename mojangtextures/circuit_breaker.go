@@ -0,0 +1,174 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops sending requests to Mojang's API after the Threshold of the consecutive
+// failures (5xx responses, timeouts and 429 errors) and fails fast with the ErrCircuitOpen error.
+// After the OpenDuration one trial request is allowed: its success closes the circuit,
+// while its failure opens it again
+type CircuitBreaker struct {
+	Emitter
+	// Name is used to identify the breaker in the emitted events
+	Name         string
+	Threshold    int
+	OpenDuration time.Duration
+
+	lock     sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func (b *CircuitBreaker) Do(fn func() error) error {
+	allowed, newState := b.allow()
+	b.emitStateChange(newState)
+	if !allowed {
+		return ErrCircuitOpen
+	}
+
+	err := fn()
+	b.emitStateChange(b.report(err))
+
+	return err
+}
+
+// Returns whether the request is allowed and the new state if it has been changed.
+// Events are emitted by the caller after the lock is released
+func (b *CircuitBreaker) allow() (bool, string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.getState() {
+	case CircuitOpen:
+		if now().Sub(b.openedAt) < b.OpenDuration {
+			return false, ""
+		}
+
+		b.state = CircuitHalfOpen
+		b.trial = true
+
+		return true, CircuitHalfOpen
+	case CircuitHalfOpen:
+		// Only one trial request is allowed at a time
+		if b.trial {
+			return false, ""
+		}
+
+		b.trial = true
+
+		return true, ""
+	}
+
+	return true, ""
+}
+
+func (b *CircuitBreaker) report(err error) string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.trial = false
+
+	// The cancelled request tells nothing about the Mojang's state
+	if errors.Is(err, context.Canceled) {
+		return ""
+	}
+
+	if !isCircuitBreakingError(err) {
+		b.failures = 0
+
+		return b.setState(CircuitClosed)
+	}
+
+	b.failures++
+	if b.getState() == CircuitHalfOpen || b.failures >= b.Threshold {
+		b.openedAt = now()
+
+		return b.setState(CircuitOpen)
+	}
+
+	return ""
+}
+
+func (b *CircuitBreaker) getState() string {
+	if b.state == "" {
+		return CircuitClosed
+	}
+
+	return b.state
+}
+
+// Returns the passed state if it differs from the current one
+func (b *CircuitBreaker) setState(state string) string {
+	if b.getState() == state {
+		return ""
+	}
+
+	b.state = state
+
+	return state
+}
+
+func (b *CircuitBreaker) emitStateChange(state string) {
+	if state != "" {
+		b.Emit("mojang_textures:circuit_breaker:state_changed", b.Name, state)
+	}
+}
+
+func isCircuitBreakingError(err error) bool {
+	switch getErrorKind(err) {
+	case ServerErrorKind, TimeoutErrorKind, TooManyRequestsErrorKind:
+		return true
+	}
+
+	return false
+}
+
+type CircuitBreakingUuidsProvider struct {
+	UUIDsProvider
+	*CircuitBreaker
+}
+
+func (p *CircuitBreakingUuidsProvider) GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
+	var result *mojang.ProfileInfo
+	err := p.Do(func() error {
+		var err error
+		result, err = p.UUIDsProvider.GetUuid(ctx, username)
+
+		return err
+	})
+
+	return result, err
+}
+
+type CircuitBreakingTexturesProvider struct {
+	TexturesProvider
+	*CircuitBreaker
+}
+
+func (p *CircuitBreakingTexturesProvider) GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	var result *mojang.SignedTexturesResponse
+	err := p.Do(func() error {
+		var err error
+		result, err = p.TexturesProvider.GetTextures(ctx, uuid)
+
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,175 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+func createCircuitBreaker(emitter Emitter) *CircuitBreaker {
+	return &CircuitBreaker{
+		Emitter:      emitter,
+		Name:         "mock",
+		Threshold:    2,
+		OpenDuration: time.Minute,
+	}
+}
+
+func failingCall() error {
+	return &mojang.ServerError{Status: 503}
+}
+
+func successfulCall() error {
+	return nil
+}
+
+func TestCircuitBreaker_Do(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+
+	t.Run("should open the circuit after the threshold of the consecutive failures", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", CircuitOpen).Once()
+
+		breaker := createCircuitBreaker(emitter)
+		require.Error(t, breaker.Do(failingCall))
+		require.Error(t, breaker.Do(failingCall))
+
+		calls := 0
+		err := breaker.Do(func() error {
+			calls++
+			return nil
+		})
+		require.Equal(t, ErrCircuitOpen, err)
+		require.Equal(t, 0, calls, "the call should not be performed while the circuit is open")
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should reset the failures counter after the success", func(t *testing.T) {
+		breaker := createCircuitBreaker(&mockEmitter{})
+		require.Error(t, breaker.Do(failingCall))
+		require.Nil(t, breaker.Do(successfulCall))
+		require.Error(t, breaker.Do(failingCall))
+		require.Nil(t, breaker.Do(successfulCall))
+	})
+
+	t.Run("should not count errors, that aren't related to the Mojang's availability", func(t *testing.T) {
+		breaker := createCircuitBreaker(&mockEmitter{})
+		for i := 0; i < 3; i++ {
+			require.Error(t, breaker.Do(func() error {
+				return &mojang.BadRequestError{}
+			}))
+		}
+
+		for i := 0; i < 3; i++ {
+			require.Equal(t, context.Canceled, breaker.Do(func() error {
+				return context.Canceled
+			}))
+		}
+
+		require.Nil(t, breaker.Do(successfulCall))
+	})
+
+	t.Run("should close the circuit after the successful trial request", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", CircuitOpen).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", CircuitHalfOpen).Once()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", CircuitClosed).Once()
+
+		breaker := createCircuitBreaker(emitter)
+		_ = breaker.Do(failingCall)
+		_ = breaker.Do(failingCall)
+
+		now = func() time.Time {
+			return time.Now().Add(breaker.OpenDuration)
+		}
+
+		require.Nil(t, breaker.Do(successfulCall))
+		require.Nil(t, breaker.Do(successfulCall))
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should open the circuit again after the failed trial request", func(t *testing.T) {
+		now = time.Now
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", CircuitOpen).Twice()
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", CircuitHalfOpen).Once()
+
+		breaker := createCircuitBreaker(emitter)
+		_ = breaker.Do(failingCall)
+		_ = breaker.Do(failingCall)
+
+		now = func() time.Time {
+			return time.Now().Add(breaker.OpenDuration)
+		}
+
+		require.Error(t, breaker.Do(failingCall))
+		require.Equal(t, ErrCircuitOpen, breaker.Do(successfulCall))
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should allow only one trial request at a time", func(t *testing.T) {
+		now = time.Now
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:circuit_breaker:state_changed", "mock", mock.Anything)
+
+		breaker := createCircuitBreaker(emitter)
+		_ = breaker.Do(failingCall)
+		_ = breaker.Do(failingCall)
+
+		now = func() time.Time {
+			return time.Now().Add(breaker.OpenDuration)
+		}
+
+		var concurrentErr error
+		require.Nil(t, breaker.Do(func() error {
+			concurrentErr = breaker.Do(successfulCall)
+			return nil
+		}))
+		require.Equal(t, ErrCircuitOpen, concurrentErr)
+	})
+}
+
+func TestCircuitBreakingProviders(t *testing.T) {
+	t.Run("uuids provider", func(t *testing.T) {
+		expectedResult := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+		uuidsProvider := &mockUuidsProvider{}
+		uuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(expectedResult, nil)
+
+		provider := &CircuitBreakingUuidsProvider{
+			UUIDsProvider:  uuidsProvider,
+			CircuitBreaker: createCircuitBreaker(&mockEmitter{}),
+		}
+
+		result, err := provider.GetUuid(context.Background(), "username")
+		require.Nil(t, err)
+		require.Equal(t, expectedResult, result)
+		uuidsProvider.AssertExpectations(t)
+	})
+
+	t.Run("textures provider", func(t *testing.T) {
+		expectedErr := errors.New("mock error")
+		texturesProvider := &mockTexturesProvider{}
+		texturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, expectedErr)
+
+		provider := &CircuitBreakingTexturesProvider{
+			TexturesProvider: texturesProvider,
+			CircuitBreaker:   createCircuitBreaker(&mockEmitter{}),
+		}
+
+		result, err := provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+		require.Equal(t, expectedErr, err)
+		require.Nil(t, result)
+		texturesProvider.AssertExpectations(t)
+	})
+}
@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"container/list"
 	"sync"
 	"time"
 
@@ -8,53 +9,118 @@ import (
 )
 
 type inMemoryItem struct {
+	uuid      string
 	textures  *mojang.SignedTexturesResponse
 	timestamp int64
 }
 
 type InMemoryTexturesStorage struct {
+	Emitter
 	GCPeriod time.Duration
 	Duration time.Duration
+	// NilDuration is used instead of the Duration for the information about the absence of textures
+	NilDuration time.Duration
+	// StaleDuration specifies how long the expired textures are kept after the expiration,
+	// so they can be served by the GetStaleTextures method. Zero value disables it
+	StaleDuration time.Duration
+	// MaxEntries limits the number of stored textures. When the limit is reached,
+	// the least recently used entry is evicted. Zero value means no limit
+	MaxEntries int
 
 	once sync.Once
 	lock sync.RWMutex
-	data map[string]*inMemoryItem
+	data map[string]*list.Element
+	lru  *list.List
 	done chan struct{}
 }
 
-func NewInMemoryTexturesStorage() *InMemoryTexturesStorage {
+func NewInMemoryTexturesStorage(emitter Emitter) *InMemoryTexturesStorage {
 	storage := &InMemoryTexturesStorage{
-		GCPeriod: 10 * time.Second,
-		Duration: time.Minute + 10*time.Second,
-		data:     make(map[string]*inMemoryItem),
+		Emitter:     emitter,
+		GCPeriod:    10 * time.Second,
+		Duration:    time.Minute + 10*time.Second,
+		NilDuration: time.Minute + 10*time.Second,
+		data:        make(map[string]*list.Element),
+		lru:         list.New(),
 	}
 
 	return storage
 }
 
-func (s *InMemoryTexturesStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
+func (s *InMemoryTexturesStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	// Write lock is required, since the read access changes the order of the entries
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	element, exists := s.data[uuid]
+	if !exists {
+		return nil, false, nil
+	}
+
+	item := element.Value.(*inMemoryItem)
+	if s.isExpired(item) {
+		return nil, false, nil
+	}
+
+	s.lru.MoveToFront(element)
+
+	return item.textures, true, nil
+}
 
-	item, exists := s.data[uuid]
-	validRange := s.getMinimalNotExpiredTimestamp()
-	if !exists || validRange > item.timestamp {
-		return nil, nil
+func (s *InMemoryTexturesStorage) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	element, exists := s.data[uuid]
+	if !exists {
+		return nil, false, nil
+	}
+
+	item := element.Value.(*inMemoryItem)
+	if s.isStale(item) {
+		return nil, false, nil
 	}
 
-	return item.textures, nil
+	s.lru.MoveToFront(element)
+
+	return item.textures, true, nil
 }
 
 func (s *InMemoryTexturesStorage) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
 	s.once.Do(s.start)
 
+	evicted := s.store(uuid, textures)
+	for _, evictedUuid := range evicted {
+		s.Emit("mojang_textures:textures:evicted", evictedUuid)
+	}
+}
+
+// Returns the uuids of the evicted entries, so the events can be emitted after the lock is released
+func (s *InMemoryTexturesStorage) store(uuid string, textures *mojang.SignedTexturesResponse) []string {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	s.data[uuid] = &inMemoryItem{
+	item := &inMemoryItem{
+		uuid:      uuid,
 		textures:  textures,
 		timestamp: unixNanoToUnixMicro(time.Now().UnixNano()),
 	}
+
+	if element, exists := s.data[uuid]; exists {
+		element.Value = item
+		s.lru.MoveToFront(element)
+
+		return nil
+	}
+
+	s.data[uuid] = s.lru.PushFront(item)
+
+	var evicted []string
+	for s.MaxEntries > 0 && s.lru.Len() > s.MaxEntries {
+		evicted = append(evicted, s.remove(s.lru.Back()))
+	}
+
+	return evicted
 }
 
 func (s *InMemoryTexturesStorage) start() {
@@ -80,16 +146,39 @@ func (s *InMemoryTexturesStorage) gc() {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	maxTime := s.getMinimalNotExpiredTimestamp()
-	for uuid, value := range s.data {
-		if maxTime > value.timestamp {
-			delete(s.data, uuid)
+	for _, element := range s.data {
+		if s.isStale(element.Value.(*inMemoryItem)) {
+			s.remove(element)
 		}
 	}
 }
 
-func (s *InMemoryTexturesStorage) getMinimalNotExpiredTimestamp() int64 {
-	return unixNanoToUnixMicro(time.Now().Add(s.Duration * time.Duration(-1)).UnixNano())
+func (s *InMemoryTexturesStorage) remove(element *list.Element) string {
+	item := s.lru.Remove(element).(*inMemoryItem)
+	delete(s.data, item.uuid)
+
+	return item.uuid
+}
+
+func (s *InMemoryTexturesStorage) isExpired(item *inMemoryItem) bool {
+	return getMinimalNotExpiredTimestamp(s.getDuration(item)) > item.timestamp
+}
+
+// Stale item is expired and can't be served even by the GetStaleTextures method
+func (s *InMemoryTexturesStorage) isStale(item *inMemoryItem) bool {
+	return getMinimalNotExpiredTimestamp(s.getDuration(item)+s.StaleDuration) > item.timestamp
+}
+
+func (s *InMemoryTexturesStorage) getDuration(item *inMemoryItem) time.Duration {
+	if item.textures == nil {
+		return s.NilDuration
+	}
+
+	return s.Duration
+}
+
+func getMinimalNotExpiredTimestamp(duration time.Duration) int64 {
+	return unixNanoToUnixMicro(time.Now().Add(duration * time.Duration(-1)).UnixNano())
 }
 
 func unixNanoToUnixMicro(unixNano int64) int64 {
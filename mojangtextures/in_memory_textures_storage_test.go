@@ -45,56 +45,61 @@ var texturesWithoutSkin = &mojang.SignedTexturesResponse{
 }
 
 func TestInMemoryTexturesStorage_GetTextures(t *testing.T) {
-	t.Run("should return nil, nil when textures are unavailable", func(t *testing.T) {
-		storage := NewInMemoryTexturesStorage()
-		result, err := storage.GetTextures("b5d58475007d4f9e9ddd1403e2497579")
+	t.Run("should return not found when textures are unavailable", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
+		result, found, err := storage.GetTextures("b5d58475007d4f9e9ddd1403e2497579")
 
 		assert.Nil(t, result)
+		assert.False(t, found)
 		assert.Nil(t, err)
 	})
 
 	t.Run("get textures object, when uuid is stored in the storage", func(t *testing.T) {
-		storage := NewInMemoryTexturesStorage()
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
-		result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		result, found, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
 
 		assert.Equal(t, texturesWithSkin, result)
+		assert.True(t, found)
 		assert.Nil(t, err)
 	})
 
-	t.Run("should return nil, nil when textures are exists, but cache duration is expired", func(t *testing.T) {
-		storage := NewInMemoryTexturesStorage()
+	t.Run("should return not found when textures are exists, but cache duration is expired", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
 		storage.Duration = 10 * time.Millisecond
 		storage.GCPeriod = time.Minute
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
 
 		time.Sleep(storage.Duration * 2)
 
-		result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		result, found, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
 
 		assert.Nil(t, result)
+		assert.False(t, found)
 		assert.Nil(t, err)
 	})
 }
 
 func TestInMemoryTexturesStorage_StoreTextures(t *testing.T) {
 	t.Run("store textures for previously not existed uuid", func(t *testing.T) {
-		storage := NewInMemoryTexturesStorage()
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
-		result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		result, found, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
 
 		assert.Equal(t, texturesWithSkin, result)
+		assert.True(t, found)
 		assert.Nil(t, err)
 	})
 
 	t.Run("override already existed textures for uuid", func(t *testing.T) {
-		storage := NewInMemoryTexturesStorage()
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithoutSkin)
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
-		result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		result, found, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
 
 		assert.NotEqual(t, texturesWithoutSkin, result)
 		assert.Equal(t, texturesWithSkin, result)
+		assert.True(t, found)
 		assert.Nil(t, err)
 	})
 
@@ -105,26 +110,129 @@ func TestInMemoryTexturesStorage_StoreTextures(t *testing.T) {
 			Props: []*mojang.Property{},
 		}
 
-		storage := NewInMemoryTexturesStorage()
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithEmptyProps)
-		result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		result, found, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
 
 		assert.Exactly(t, texturesWithEmptyProps, result)
+		assert.True(t, found)
 		assert.Nil(t, err)
 	})
 
 	t.Run("store nil textures", func(t *testing.T) {
-		storage := NewInMemoryTexturesStorage()
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
 		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", nil)
-		result, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		result, found, err := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
 
 		assert.Nil(t, result)
+		assert.True(t, found)
 		assert.Nil(t, err)
 	})
 }
 
+func TestInMemoryTexturesStorage_NilDuration(t *testing.T) {
+	storage := NewInMemoryTexturesStorage(&mockEmitter{})
+	storage.Duration = time.Minute
+	storage.NilDuration = 10 * time.Millisecond
+	storage.GCPeriod = time.Minute
+	storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+	storage.StoreTextures("b5d58475007d4f9e9ddd1403e2497579", nil)
+
+	time.Sleep(storage.NilDuration * 2)
+
+	result, found, _ := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+	assert.Equal(t, texturesWithSkin, result)
+	assert.True(t, found)
+
+	result, found, _ = storage.GetTextures("b5d58475007d4f9e9ddd1403e2497579")
+	assert.Nil(t, result)
+	assert.False(t, found)
+}
+
+func TestInMemoryTexturesStorage_GetStaleTextures(t *testing.T) {
+	t.Run("should return expired textures within the stale duration", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
+		storage.Duration = 10 * time.Millisecond
+		storage.StaleDuration = time.Minute
+		storage.GCPeriod = time.Minute
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+
+		time.Sleep(storage.Duration * 2)
+
+		_, found, _ := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		assert.False(t, found)
+
+		result, found, err := storage.GetStaleTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		assert.Equal(t, texturesWithSkin, result)
+		assert.True(t, found)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should return not found when the stale duration is expired", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
+		storage.Duration = 10 * time.Millisecond
+		storage.StaleDuration = 10 * time.Millisecond
+		storage.GCPeriod = time.Minute
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+
+		time.Sleep((storage.Duration + storage.StaleDuration) * 2)
+
+		result, found, err := storage.GetStaleTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		assert.Nil(t, result)
+		assert.False(t, found)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should return not found when textures are unavailable", func(t *testing.T) {
+		storage := NewInMemoryTexturesStorage(&mockEmitter{})
+		result, found, err := storage.GetStaleTextures("b5d58475007d4f9e9ddd1403e2497579")
+
+		assert.Nil(t, result)
+		assert.False(t, found)
+		assert.Nil(t, err)
+	})
+}
+
+func TestInMemoryTexturesStorage_MaxEntries(t *testing.T) {
+	t.Run("evict the least recently used entry", func(t *testing.T) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:textures:evicted", "b5d58475007d4f9e9ddd1403e2497579").Once()
+
+		storage := NewInMemoryTexturesStorage(emitter)
+		storage.MaxEntries = 2
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+		storage.StoreTextures("b5d58475007d4f9e9ddd1403e2497579", nil)
+		// Access the first entry to make the second one the least recently used
+		_, _, _ = storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		storage.StoreTextures("a9a2e9d6d0c44d6a8c1e3b4d5f6a7b8c", texturesWithoutSkin)
+
+		storage.lock.RLock()
+		assert.Len(t, storage.data, 2)
+		assert.Contains(t, storage.data, "dead24f9a4fa4877b7b04c8c6c72bb46")
+		assert.Contains(t, storage.data, "a9a2e9d6d0c44d6a8c1e3b4d5f6a7b8c")
+		storage.lock.RUnlock()
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("override of the exists entry doesn't evict anything", func(t *testing.T) {
+		emitter := &mockEmitter{}
+
+		storage := NewInMemoryTexturesStorage(emitter)
+		storage.MaxEntries = 1
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithoutSkin)
+		storage.StoreTextures("dead24f9a4fa4877b7b04c8c6c72bb46", texturesWithSkin)
+
+		result, found, _ := storage.GetTextures("dead24f9a4fa4877b7b04c8c6c72bb46")
+		assert.Equal(t, texturesWithSkin, result)
+		assert.True(t, found)
+
+		emitter.AssertExpectations(t)
+	})
+}
+
 func TestInMemoryTexturesStorage_GarbageCollection(t *testing.T) {
-	storage := NewInMemoryTexturesStorage()
+	storage := NewInMemoryTexturesStorage(&mockEmitter{})
 	defer storage.Stop()
 	storage.GCPeriod = 10 * time.Millisecond
 	storage.Duration = 9 * time.Millisecond
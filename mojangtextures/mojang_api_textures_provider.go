@@ -1,6 +1,8 @@
 package mojangtextures
 
 import (
+	"context"
+
 	"github.com/elyby/chrly/api/mojang"
 )
 
@@ -10,10 +12,10 @@ type MojangApiTexturesProvider struct {
 	Emitter
 }
 
-func (ctx *MojangApiTexturesProvider) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
-	ctx.Emit("mojang_textures:mojang_api_textures_provider:before_request", uuid)
-	result, err := uuidToTextures(uuid, true)
-	ctx.Emit("mojang_textures:mojang_api_textures_provider:after_request", uuid, result, err)
+func (p *MojangApiTexturesProvider) GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	p.Emit("mojang_textures:mojang_api_textures_provider:before_request", uuid)
+	result, err := uuidToTextures(ctx, uuid, true)
+	p.Emit("mojang_textures:mojang_api_textures_provider:after_request", uuid, result, err)
 
 	return result, err
 }
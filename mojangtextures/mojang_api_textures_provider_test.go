@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -13,7 +14,7 @@ type mojangUuidToTexturesRequestMock struct {
 	mock.Mock
 }
 
-func (o *mojangUuidToTexturesRequestMock) UuidToTextures(uuid string, signed bool) (*mojang.SignedTexturesResponse, error) {
+func (o *mojangUuidToTexturesRequestMock) UuidToTextures(ctx context.Context, uuid string, signed bool) (*mojang.SignedTexturesResponse, error) {
 	args := o.Called(uuid, signed)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
@@ -69,7 +70,7 @@ func (suite *mojangApiTexturesProviderTestSuite) TestGetTextures() {
 		nil,
 	).Once()
 
-	result, err := suite.Provider.GetTextures("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
 
 	suite.Assert().Equal(expectedResult, result)
 	suite.Assert().Nil(err)
@@ -91,7 +92,7 @@ func (suite *mojangApiTexturesProviderTestSuite) TestGetTexturesWithError() {
 		expectedError,
 	).Once()
 
-	result, err := suite.Provider.GetTextures("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	result, err := suite.Provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
 
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(expectedError, err)
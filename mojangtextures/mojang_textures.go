@@ -1,15 +1,27 @@
 package mojangtextures
 
 import (
+	"context"
 	"errors"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/elyby/chrly/api/mojang"
 	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/logging"
+	"github.com/elyby/chrly/tracing"
 )
 
+var now = time.Now
+
+var tracer = otel.Tracer("github.com/elyby/chrly/mojangtextures")
+
 type broadcastResult struct {
 	textures *mojang.SignedTexturesResponse
 	error    error
@@ -18,34 +30,78 @@ type broadcastResult struct {
 type broadcaster struct {
 	lock      sync.Mutex
 	listeners map[string][]chan *broadcastResult
+	cancels   map[string]context.CancelFunc
 }
 
 func createBroadcaster() *broadcaster {
 	return &broadcaster{
 		listeners: make(map[string][]chan *broadcastResult),
+		cancels:   make(map[string]context.CancelFunc),
 	}
 }
 
-// Returns a boolean value, which will be true if the passed username didn't exist before
-func (c *broadcaster) AddListener(username string, resultChan chan *broadcastResult) bool {
+// Returns a boolean value, which will be true if the passed username didn't exist before.
+// In this case the returned context should be used to perform the job, since it will be
+// cancelled when all listeners are removed before the result is broadcasted
+func (c *broadcaster) AddListener(username string, resultChan chan *broadcastResult) (context.Context, bool) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	val, alreadyHasSource := c.listeners[username]
 	if alreadyHasSource {
 		c.listeners[username] = append(val, resultChan)
-		return false
+		return nil, false
 	}
 
-	c.listeners[username] = []chan *broadcastResult{resultChan}
+	return c.start(username, resultChan), true
+}
 
-	return true
+// AddFirstListener adds the listener only when there is no job for the passed username yet
+// and the passed function allows to start it. The function is called under the lock, so it isn't
+// called at all while the job is in flight. The returned context is nil when the job isn't started
+func (c *broadcaster) AddFirstListener(username string, resultChan chan *broadcastResult, canStart func() bool) context.Context {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, alreadyHasSource := c.listeners[username]; alreadyHasSource || !canStart() {
+		return nil
+	}
+
+	return c.start(username, resultChan)
 }
 
-func (c *broadcaster) BroadcastAndRemove(username string, result *broadcastResult) {
+// RemoveListener should be called when the listener isn't interested in the result anymore.
+// When the last listener is removed, the job's context is cancelled
+func (c *broadcaster) RemoveListener(username string, resultChan chan *broadcastResult) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
+	val := c.listeners[username]
+	for i, channel := range val {
+		if channel == resultChan {
+			val = append(val[:i], val[i+1:]...)
+			break
+		}
+	}
+
+	if len(val) > 0 {
+		c.listeners[username] = val
+		return
+	}
+
+	c.remove(username)
+}
+
+// The result will not be broadcasted if the passed job's context has been cancelled,
+// since its listeners have been removed and there may be listeners of the new job
+func (c *broadcaster) BroadcastAndRemove(ctx context.Context, username string, result *broadcastResult) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+
 	val, ok := c.listeners[username]
 	if !ok {
 		return
@@ -58,18 +114,62 @@ func (c *broadcaster) BroadcastAndRemove(username string, result *broadcastResul
 		}(channel)
 	}
 
+	c.remove(username)
+}
+
+func (c *broadcaster) start(username string, resultChan chan *broadcastResult) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.listeners[username] = []chan *broadcastResult{resultChan}
+	c.cancels[username] = cancel
+
+	return ctx
+}
+
+func (c *broadcaster) remove(username string) {
+	if cancel, ok := c.cancels[username]; ok {
+		cancel()
+	}
+
 	delete(c.listeners, username)
+	delete(c.cancels, username)
+}
+
+// refreshBudget limits the number of the background refreshes within a one minute window
+type refreshBudget struct {
+	lock        sync.Mutex
+	limit       int
+	used        int
+	windowStart time.Time
+}
+
+func (b *refreshBudget) Take() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	currentTime := now()
+	if currentTime.Sub(b.windowStart) >= time.Minute {
+		b.windowStart = currentTime
+		b.used = 0
+	}
+
+	if b.used >= b.limit {
+		return false
+	}
+
+	b.used++
+
+	return true
 }
 
 // https://help.mojang.com/customer/portal/articles/928638
 var allowedUsernamesRegex = regexp.MustCompile(`^[\w_]{3,16}$`)
 
 type UUIDsProvider interface {
-	GetUuid(username string) (*mojang.ProfileInfo, error)
+	GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error)
 }
 
 type TexturesProvider interface {
-	GetTextures(uuid string) (*mojang.SignedTexturesResponse, error)
+	GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error)
 }
 
 type Emitter interface {
@@ -81,14 +181,21 @@ type Provider struct {
 	UUIDsProvider
 	TexturesProvider
 	Storage
+	// RefreshesPerMinute enables the stale-while-revalidate mode: when the storage
+	// has only expired textures, they are served immediately, while the refresh is
+	// performed in the background. The value limits the number of such refreshes per
+	// minute to respect Mojang's rate limits. Zero value disables the mode
+	RefreshesPerMinute int
 
 	onFirstCall sync.Once
 	*broadcaster
+	*refreshBudget
 }
 
-func (ctx *Provider) GetForUsername(username string) (*mojang.SignedTexturesResponse, error) {
-	ctx.onFirstCall.Do(func() {
-		ctx.broadcaster = createBroadcaster()
+func (p *Provider) GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	p.onFirstCall.Do(func() {
+		p.broadcaster = createBroadcaster()
+		p.refreshBudget = &refreshBudget{limit: p.RefreshesPerMinute}
 	})
 
 	if !allowedUsernamesRegex.MatchString(username) {
@@ -96,9 +203,9 @@ func (ctx *Provider) GetForUsername(username string) (*mojang.SignedTexturesResp
 	}
 
 	username = strings.ToLower(username)
-	ctx.Emit("mojang_textures:call", username)
+	p.Emit("mojang_textures:call", username)
 
-	uuid, found, err := ctx.getUuidFromCache(username)
+	uuid, found, err := p.getUuidFromCache(ctx, username)
 	if err != nil {
 		return nil, err
 	}
@@ -108,36 +215,120 @@ func (ctx *Provider) GetForUsername(username string) (*mojang.SignedTexturesResp
 	}
 
 	if uuid != "" {
-		textures, err := ctx.getTexturesFromCache(uuid)
-		if err == nil && textures != nil {
+		textures, found, err := p.getTexturesFromCache(ctx, uuid)
+		if err == nil && found {
+			setTexturesSource(ctx, textures, logging.MojangCacheTexturesSource)
 			return textures, nil
 		}
 	}
 
-	resultChan := make(chan *broadcastResult)
-	isFirstListener := ctx.broadcaster.AddListener(username, resultChan)
+	if uuid != "" && p.RefreshesPerMinute > 0 {
+		textures, found := p.getStaleTexturesAndRefresh(username, uuid)
+		if found {
+			setTexturesSource(ctx, textures, logging.MojangCacheTexturesSource)
+			return textures, nil
+		}
+	}
+
+	// The chan is buffered, so the broadcaster will not be blocked when the listener is gone
+	resultChan := make(chan *broadcastResult, 1)
+	jobContext, isFirstListener := p.broadcaster.AddListener(username, resultChan)
 	if isFirstListener {
-		go ctx.getResultAndBroadcast(username, uuid)
+		// The job isn't cancelled with the request, that started it, but it's still a part of the request's trace
+		jobContext = trace.ContextWithSpanContext(jobContext, trace.SpanContextFromContext(ctx))
+		go p.getResultAndBroadcast(jobContext, username, uuid)
 	} else {
-		ctx.Emit("mojang_textures:already_processing", username)
+		p.Emit("mojang_textures:already_processing", username)
+	}
+
+	select {
+	case result := <-resultChan:
+		// While Mojang's API is unavailable, the expired textures are better than nothing
+		if errors.Is(result.error, ErrCircuitOpen) && uuid != "" {
+			if textures, found := p.getStaleTextures(uuid); found {
+				setTexturesSource(ctx, textures, logging.MojangCacheTexturesSource)
+				return textures, nil
+			}
+		}
+
+		setTexturesSource(ctx, result.textures, logging.MojangCallTexturesSource)
+
+		return result.textures, result.error
+	case <-ctx.Done():
+		p.broadcaster.RemoveListener(username, resultChan)
+		return nil, ctx.Err()
+	}
+}
+
+// The source is recorded only when the textures are found. Otherwise, it's left
+// to the next provider, that will handle the request
+func setTexturesSource(ctx context.Context, textures *mojang.SignedTexturesResponse, source string) {
+	if textures != nil {
+		logging.SetTexturesSource(ctx, source)
+	}
+}
+
+// Returns the expired textures, if the storage still has them, and schedules their refresh
+// through the broadcaster, so the concurrent requests for the same username will be merged
+func (p *Provider) getStaleTexturesAndRefresh(username string, uuid string) (*mojang.SignedTexturesResponse, bool) {
+	textures, found := p.getStaleTextures(uuid)
+	if !found {
+		return nil, false
 	}
 
-	result := <-resultChan
+	// The channel is buffered, since nobody will wait for the result of the background refresh
+	resultChan := make(chan *broadcastResult, 1)
+	// The budget is spent only when the refresh is actually started, not when it's already in flight
+	budgetExceeded := false
+	jobContext := p.broadcaster.AddFirstListener(username, resultChan, func() bool {
+		budgetExceeded = !p.refreshBudget.Take()
+		return !budgetExceeded
+	})
+	if jobContext != nil {
+		p.Emit("mojang_textures:refresh:scheduled", username, uuid)
+		go p.getResultAndBroadcast(jobContext, username, uuid)
+	} else if budgetExceeded {
+		p.Emit("mojang_textures:refresh:budget_exceeded", username)
+	}
 
-	return result.textures, result.error
+	return textures, true
 }
 
-func (ctx *Provider) getResultAndBroadcast(username string, uuid string) {
-	ctx.Emit("mojang_textures:before_result", username, uuid)
-	result := ctx.getResult(username, uuid)
-	ctx.Emit("mojang_textures:after_result", username, result.textures, result.error)
+func (p *Provider) getStaleTextures(uuid string) (*mojang.SignedTexturesResponse, bool) {
+	storage, ok := p.Storage.(StaleTexturesStorage)
+	if !ok {
+		return nil, false
+	}
+
+	textures, found, err := storage.GetStaleTextures(uuid)
+	if err != nil || !found {
+		return nil, false
+	}
+
+	p.Emit("mojang_textures:textures:stale", uuid, textures)
 
-	ctx.broadcaster.BroadcastAndRemove(username, result)
+	return textures, true
 }
 
-func (ctx *Provider) getResult(username string, uuid string) *broadcastResult {
+func (p *Provider) getResultAndBroadcast(ctx context.Context, username string, uuid string) {
+	p.Emit("mojang_textures:before_result", username, uuid)
+	result := p.getResult(ctx, username, uuid)
+	p.Emit("mojang_textures:after_result", username, result.textures, result.error)
+
+	p.broadcaster.BroadcastAndRemove(ctx, username, result)
+}
+
+func (p *Provider) getResult(ctx context.Context, username string, uuid string) (result *broadcastResult) {
+	ctx, span := tracer.Start(ctx, "mojangtextures.Provider.getResult", trace.WithAttributes(
+		attribute.String("mojang.username", username),
+		attribute.String("mojang.uuid", uuid),
+	))
+	defer func() {
+		tracing.EndSpan(span, result.error)
+	}()
+
 	if uuid == "" {
-		profile, err := ctx.getUuid(username)
+		profile, err := p.getUuid(ctx, username)
 		if err != nil {
 			return &broadcastResult{nil, err}
 		}
@@ -147,53 +338,72 @@ func (ctx *Provider) getResult(username string, uuid string) *broadcastResult {
 			uuid = profile.Id
 		}
 
-		_ = ctx.Storage.StoreUuid(username, uuid)
+		p.storeUuid(ctx, username, uuid)
 
 		if uuid == "" {
 			return &broadcastResult{nil, nil}
 		}
 	}
 
-	textures, err := ctx.getTextures(uuid)
+	textures, err := p.getTextures(ctx, uuid)
 	if err != nil {
 		return &broadcastResult{nil, err}
 	}
 
 	// Mojang can respond with an error, but it will still count as a hit,
 	// therefore store the result even if textures is nil to prevent 429 error
-	ctx.Storage.StoreTextures(uuid, textures)
+	p.storeTextures(ctx, uuid, textures)
 
 	return &broadcastResult{textures, nil}
 }
 
-func (ctx *Provider) getUuidFromCache(username string) (string, bool, error) {
-	ctx.Emit("mojang_textures:usernames:before_cache", username)
-	uuid, found, err := ctx.Storage.GetUuid(username)
-	ctx.Emit("mojang_textures:usernames:after_cache", username, uuid, found, err)
+// Storage methods don't accept the context, so their spans are started here
+func (p *Provider) getUuidFromCache(ctx context.Context, username string) (string, bool, error) {
+	_, span := tracer.Start(ctx, "mojangtextures.UUIDsStorage.GetUuid")
+	p.Emit("mojang_textures:usernames:before_cache", username)
+	uuid, found, err := p.Storage.GetUuid(username)
+	p.Emit("mojang_textures:usernames:after_cache", username, uuid, found, err)
+	span.SetAttributes(attribute.Bool("cache.found", found))
+	tracing.EndSpan(span, err)
 
 	return uuid, found, err
 }
 
-func (ctx *Provider) getTexturesFromCache(uuid string) (*mojang.SignedTexturesResponse, error) {
-	ctx.Emit("mojang_textures:textures:before_cache", uuid)
-	textures, err := ctx.Storage.GetTextures(uuid)
-	ctx.Emit("mojang_textures:textures:after_cache", uuid, textures, err)
+func (p *Provider) getTexturesFromCache(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	_, span := tracer.Start(ctx, "mojangtextures.TexturesStorage.GetTextures")
+	p.Emit("mojang_textures:textures:before_cache", uuid)
+	textures, found, err := p.Storage.GetTextures(uuid)
+	p.Emit("mojang_textures:textures:after_cache", uuid, textures, found, err)
+	span.SetAttributes(attribute.Bool("cache.found", found))
+	tracing.EndSpan(span, err)
 
-	return textures, err
+	return textures, found, err
+}
+
+func (p *Provider) storeUuid(ctx context.Context, username string, uuid string) {
+	_, span := tracer.Start(ctx, "mojangtextures.UUIDsStorage.StoreUuid")
+	err := p.Storage.StoreUuid(username, uuid)
+	tracing.EndSpan(span, err)
+}
+
+func (p *Provider) storeTextures(ctx context.Context, uuid string, textures *mojang.SignedTexturesResponse) {
+	_, span := tracer.Start(ctx, "mojangtextures.TexturesStorage.StoreTextures")
+	p.Storage.StoreTextures(uuid, textures)
+	span.End()
 }
 
-func (ctx *Provider) getUuid(username string) (*mojang.ProfileInfo, error) {
-	ctx.Emit("mojang_textures:usernames:before_call", username)
-	profile, err := ctx.UUIDsProvider.GetUuid(username)
-	ctx.Emit("mojang_textures:usernames:after_call", username, profile, err)
+func (p *Provider) getUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
+	p.Emit("mojang_textures:usernames:before_call", username)
+	profile, err := p.UUIDsProvider.GetUuid(ctx, username)
+	p.Emit("mojang_textures:usernames:after_call", username, profile, err)
 
 	return profile, err
 }
 
-func (ctx *Provider) getTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
-	ctx.Emit("mojang_textures:textures:before_call", uuid)
-	textures, err := ctx.TexturesProvider.GetTextures(uuid)
-	ctx.Emit("mojang_textures:textures:after_call", uuid, textures, err)
+func (p *Provider) getTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	p.Emit("mojang_textures:textures:before_call", uuid)
+	textures, err := p.TexturesProvider.GetTextures(ctx, uuid)
+	p.Emit("mojang_textures:textures:after_call", uuid, textures, err)
 
 	return textures, err
 }
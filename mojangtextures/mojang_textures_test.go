@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"context"
 	"errors"
 	"sync"
 	"testing"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/logging"
 )
 
 func TestBroadcaster(t *testing.T) {
@@ -20,7 +22,7 @@ func TestBroadcaster(t *testing.T) {
 
 			broadcaster := createBroadcaster()
 			channel := make(chan *broadcastResult)
-			isFirstListener := broadcaster.AddListener("mock", channel)
+			_, isFirstListener := broadcaster.AddListener("mock", channel)
 
 			assert.True(isFirstListener)
 			listeners, ok := broadcaster.listeners["mock"]
@@ -34,22 +36,64 @@ func TestBroadcaster(t *testing.T) {
 
 			broadcaster := createBroadcaster()
 			channel1 := make(chan *broadcastResult)
-			isFirstListener := broadcaster.AddListener("mock", channel1)
+			_, isFirstListener := broadcaster.AddListener("mock", channel1)
 
 			assert.True(isFirstListener)
 
 			channel2 := make(chan *broadcastResult)
-			isFirstListener = broadcaster.AddListener("mock", channel2)
+			_, isFirstListener = broadcaster.AddListener("mock", channel2)
 
 			assert.False(isFirstListener)
 
 			channel3 := make(chan *broadcastResult)
-			isFirstListener = broadcaster.AddListener("mock", channel3)
+			_, isFirstListener = broadcaster.AddListener("mock", channel3)
 
 			assert.False(isFirstListener)
 		})
 	})
 
+	t.Run("AddFirstListener", func(t *testing.T) {
+		t.Run("should start the job when it's allowed", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel := make(chan *broadcastResult)
+			ctx := broadcaster.AddFirstListener("mock", channel, func() bool {
+				return true
+			})
+
+			assert.NotNil(ctx)
+			assert.Equal([]chan *broadcastResult{channel}, broadcaster.listeners["mock"])
+		})
+
+		t.Run("should not start the job when it isn't allowed", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			ctx := broadcaster.AddFirstListener("mock", make(chan *broadcastResult), func() bool {
+				return false
+			})
+
+			assert.Nil(ctx)
+			assert.NotContains(broadcaster.listeners, "mock")
+		})
+
+		t.Run("should not ask for the permission when the job is in flight", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel := make(chan *broadcastResult)
+			broadcaster.AddListener("mock", channel)
+			ctx := broadcaster.AddFirstListener("mock", make(chan *broadcastResult), func() bool {
+				assert.Fail("the permission shouldn't be requested")
+				return true
+			})
+
+			assert.Nil(ctx)
+			assert.Equal([]chan *broadcastResult{channel}, broadcaster.listeners["mock"])
+		})
+	})
+
 	t.Run("BroadcastAndRemove", func(t *testing.T) {
 		t.Run("should broadcast to all listeners and remove the key", func(t *testing.T) {
 			assert := testify.New(t)
@@ -57,26 +101,73 @@ func TestBroadcaster(t *testing.T) {
 			broadcaster := createBroadcaster()
 			channel1 := make(chan *broadcastResult)
 			channel2 := make(chan *broadcastResult)
-			broadcaster.AddListener("mock", channel1)
+			ctx, _ := broadcaster.AddListener("mock", channel1)
 			broadcaster.AddListener("mock", channel2)
 
 			result := &broadcastResult{}
-			broadcaster.BroadcastAndRemove("mock", result)
+			broadcaster.BroadcastAndRemove(ctx, "mock", result)
 
 			assert.Equal(result, <-channel1)
 			assert.Equal(result, <-channel2)
 
 			channel3 := make(chan *broadcastResult)
-			isFirstListener := broadcaster.AddListener("mock", channel3)
+			_, isFirstListener := broadcaster.AddListener("mock", channel3)
 			assert.True(isFirstListener)
 		})
 
+		t.Run("should not broadcast the result of the abandoned job", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel1 := make(chan *broadcastResult, 1)
+			ctx, _ := broadcaster.AddListener("mock", channel1)
+			broadcaster.RemoveListener("mock", channel1)
+
+			channel2 := make(chan *broadcastResult, 1)
+			_, isFirstListener := broadcaster.AddListener("mock", channel2)
+			assert.True(isFirstListener)
+
+			broadcaster.BroadcastAndRemove(ctx, "mock", &broadcastResult{})
+
+			assert.Len(broadcaster.listeners["mock"], 1)
+			assert.Len(channel2, 0)
+		})
+
 		t.Run("call on not exists username", func(t *testing.T) {
 			assert := testify.New(t)
 
 			assert.NotPanics(func() {
 				broadcaster := createBroadcaster()
-				broadcaster.BroadcastAndRemove("mock", &broadcastResult{})
+				broadcaster.BroadcastAndRemove(context.Background(), "mock", &broadcastResult{})
+			})
+		})
+	})
+
+	t.Run("RemoveListener", func(t *testing.T) {
+		t.Run("should cancel the job's context when the last listener is removed", func(t *testing.T) {
+			assert := testify.New(t)
+
+			broadcaster := createBroadcaster()
+			channel1 := make(chan *broadcastResult)
+			channel2 := make(chan *broadcastResult)
+			ctx, _ := broadcaster.AddListener("mock", channel1)
+			broadcaster.AddListener("mock", channel2)
+
+			broadcaster.RemoveListener("mock", channel1)
+			assert.Nil(ctx.Err())
+			assert.Len(broadcaster.listeners["mock"], 1)
+
+			broadcaster.RemoveListener("mock", channel2)
+			assert.Equal(context.Canceled, ctx.Err())
+			assert.NotContains(broadcaster.listeners, "mock")
+		})
+
+		t.Run("call on not exists username", func(t *testing.T) {
+			assert := testify.New(t)
+
+			assert.NotPanics(func() {
+				broadcaster := createBroadcaster()
+				broadcaster.RemoveListener("mock", make(chan *broadcastResult))
 			})
 		})
 	})
@@ -94,8 +185,8 @@ type mockUuidsProvider struct {
 	mock.Mock
 }
 
-func (m *mockUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo, error) {
-	args := m.Called(username)
+func (m *mockUuidsProvider) GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
+	args := m.Called(ctx, username)
 	var result *mojang.ProfileInfo
 	if casted, ok := args.Get(0).(*mojang.ProfileInfo); ok {
 		result = casted
@@ -108,7 +199,7 @@ type mockTexturesProvider struct {
 	mock.Mock
 }
 
-func (m *mockTexturesProvider) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (m *mockTexturesProvider) GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
 	args := m.Called(uuid)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
@@ -132,20 +223,34 @@ func (m *mockStorage) StoreUuid(username string, uuid string) error {
 	return args.Error(0)
 }
 
-func (m *mockStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (m *mockStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
 	args := m.Called(uuid)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
 		result = casted
 	}
 
-	return result, args.Error(1)
+	return result, args.Bool(1), args.Error(2)
 }
 
 func (m *mockStorage) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
 	m.Called(uuid, textures)
 }
 
+type mockStaleStorage struct {
+	mockStorage
+}
+
+func (m *mockStaleStorage) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	args := m.Called(uuid)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Bool(1), args.Error(2)
+}
+
 type providerTestSuite struct {
 	suite.Suite
 	Provider         *Provider
@@ -198,13 +303,17 @@ func (suite *providerTestSuite) TestGetForUsernameWithoutAnyCache() {
 	suite.Storage.On("StoreUuid", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil)
 	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
 
-	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	requestInfo := &logging.RequestInfo{}
+	ctx := logging.WithRequestInfo(context.Background(), requestInfo)
+
+	result, err := suite.Provider.GetForUsername(ctx, "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
+	suite.Assert().Equal(logging.MojangCallTexturesSource, requestInfo.TexturesSource)
 }
 
 func (suite *providerTestSuite) TestGetForUsernameWithCachedUuid() {
@@ -215,19 +324,19 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUuid() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, false, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
 	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
 	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
-	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, nil)
+	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, false, nil)
 	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
 
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
@@ -240,15 +349,163 @@ func (suite *providerTestSuite) TestGetForUsernameWithFullyCachedResult() {
 	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
 	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Once()
 	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
-	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, true, nil).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
-	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
+	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, true, nil)
+
+	requestInfo := &logging.RequestInfo{}
+	ctx := logging.WithRequestInfo(context.Background(), requestInfo)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(ctx, "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Equal(expectedResult, result)
+	suite.Assert().Equal(logging.MojangCacheTexturesSource, requestInfo.TexturesSource)
+}
+
+func (suite *providerTestSuite) TestGetForUsernameWithStaleTextures() {
+	staleStorage := &mockStaleStorage{}
+	suite.Provider.Storage = staleStorage
+	suite.Provider.RefreshesPerMinute = 1
+	defer staleStorage.AssertExpectations(suite.T())
+
+	var expectedCachedTextures *mojang.SignedTexturesResponse
+	staleTextures := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	freshTextures := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "new_username"}
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, false, nil).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:textures:stale", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", staleTextures).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:refresh:scheduled", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:refresh:budget_exceeded", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", freshTextures, nil).Once()
+	refreshed := make(chan struct{})
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", freshTextures, nil).Once().Run(func(args mock.Arguments) {
+		close(refreshed)
+	})
+
+	staleStorage.On("GetUuid", "username").Twice().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	staleStorage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice().Return(nil, false, nil)
+	staleStorage.On("GetStaleTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice().Return(staleTextures, true, nil)
+	staleStorage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", freshTextures).Once()
+
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(freshTextures, nil)
+
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(staleTextures, result)
+
+	<-refreshed
+
+	// The budget is exhausted, so the stale textures should be served without refresh
+	result, err = suite.Provider.GetForUsername(context.Background(), "username")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(staleTextures, result)
+}
+
+func (suite *providerTestSuite) TestGetForUsernameWithStaleTexturesWhileRefreshIsInFlight() {
+	staleStorage := &mockStaleStorage{}
+	suite.Provider.Storage = staleStorage
+	suite.Provider.RefreshesPerMinute = 1
+	defer staleStorage.AssertExpectations(suite.T())
+
+	var expectedCachedTextures *mojang.SignedTexturesResponse
+	staleTextures := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+	freshTextures := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "new_username"}
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, false, nil).Twice()
+	suite.Emitter.On("Emit", "mojang_textures:textures:stale", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", staleTextures).Twice()
+	// The second request joins the refresh in flight, so the budget isn't exceeded
+	suite.Emitter.On("Emit", "mojang_textures:refresh:scheduled", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", freshTextures, nil).Once()
+	refreshed := make(chan struct{})
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", freshTextures, nil).Once().Run(func(args mock.Arguments) {
+		close(refreshed)
+	})
+
+	staleStorage.On("GetUuid", "username").Twice().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	staleStorage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice().Return(nil, false, nil)
+	staleStorage.On("GetStaleTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Twice().Return(staleTextures, true, nil)
+	staleStorage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", freshTextures).Once()
+
+	release := make(chan time.Time)
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().WaitUntil(release).Return(freshTextures, nil)
+
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(staleTextures, result)
+
+	result, err = suite.Provider.GetForUsername(context.Background(), "username")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(staleTextures, result)
+
+	close(release)
+	<-refreshed
+}
+
+func (suite *providerTestSuite) TestGetForUsernameWithStaleTexturesWhenCircuitIsOpen() {
+	staleStorage := &mockStaleStorage{}
+	suite.Provider.Storage = staleStorage
+	defer staleStorage.AssertExpectations(suite.T())
+
+	var expectedCachedTextures *mojang.SignedTexturesResponse
+	var expectedResult *mojang.SignedTexturesResponse
+	staleTextures := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, false, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_call", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult, ErrCircuitOpen).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, ErrCircuitOpen).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:stale", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", staleTextures).Once()
+
+	staleStorage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	staleStorage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, false, nil)
+	staleStorage.On("GetStaleTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(staleTextures, true, nil)
+
+	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, ErrCircuitOpen)
+
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
+	suite.Assert().Nil(err)
+	suite.Assert().Equal(staleTextures, result)
+}
+
+func (suite *providerTestSuite) TestGetForUsernameWithCachedEmptyTextures() {
+	var expectedCachedTextures *mojang.SignedTexturesResponse
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:before_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once()
+	suite.Emitter.On("Emit", "mojang_textures:textures:after_cache", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedCachedTextures, true, nil).Once()
+
+	suite.Storage.On("GetUuid", "username").Once().Return("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", true, nil)
+	suite.Storage.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, true, nil)
+
+	requestInfo := &logging.RequestInfo{}
+	ctx := logging.WithRequestInfo(context.Background(), requestInfo)
+
+	result, err := suite.Provider.GetForUsername(ctx, "username")
+
+	suite.Assert().Nil(err)
+	suite.Assert().Nil(result)
+	suite.Assert().Empty(requestInfo.TexturesSource)
 }
 
 func (suite *providerTestSuite) TestGetForUsernameWithCachedUnknownUuid() {
@@ -258,7 +515,7 @@ func (suite *providerTestSuite) TestGetForUsernameWithCachedUnknownUuid() {
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", true, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(result)
 	suite.Assert().Nil(err)
@@ -279,12 +536,16 @@ func (suite *providerTestSuite) TestGetForUsernameWhichHasNoMojangAccount() {
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
 	suite.Storage.On("StoreUuid", "username", "").Once().Return(nil)
 
-	suite.UuidsProvider.On("GetUuid", "username").Once().Return(nil, nil)
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(nil, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	requestInfo := &logging.RequestInfo{}
+	ctx := logging.WithRequestInfo(context.Background(), requestInfo)
+
+	result, err := suite.Provider.GetForUsername(ctx, "username")
 
 	suite.Assert().Nil(err)
 	suite.Assert().Nil(result)
+	suite.Assert().Empty(requestInfo.TexturesSource)
 }
 
 func (suite *providerTestSuite) TestGetForUsernameWhichHasMojangAccountButHasNoMojangSkin() {
@@ -305,10 +566,10 @@ func (suite *providerTestSuite) TestGetForUsernameWhichHasMojangAccountButHasNoM
 	suite.Storage.On("StoreUuid", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil)
 	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
 
-	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Equal(expectedResult, result)
 	suite.Assert().Nil(err)
@@ -334,7 +595,7 @@ func (suite *providerTestSuite) TestGetForTheSameUsernames() {
 	suite.Storage.On("StoreTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", expectedResult).Once()
 
 	// If possible, than remove this .After call
-	suite.UuidsProvider.On("GetUuid", "username").Once().After(time.Millisecond).Return(expectedProfile, nil)
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().After(time.Millisecond).Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
 
 	results := make([]*mojang.SignedTexturesResponse, 2)
@@ -342,7 +603,7 @@ func (suite *providerTestSuite) TestGetForTheSameUsernames() {
 	for i := 0; i < 2; i++ {
 		wg.Add(1)
 		go func(i int) {
-			textures, _ := suite.Provider.GetForUsername("username")
+			textures, _ := suite.Provider.GetForUsername(context.Background(), "username")
 			results[i] = textures
 			wg.Done()
 		}(i)
@@ -353,8 +614,39 @@ func (suite *providerTestSuite) TestGetForTheSameUsernames() {
 	suite.Assert().Equal(expectedResult, results[1])
 }
 
+func (suite *providerTestSuite) TestGetForUsernameWithCancelledContext() {
+	var expectedProfile *mojang.ProfileInfo
+	var expectedResult *mojang.SignedTexturesResponse
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	suite.Emitter.On("Emit", "mojang_textures:call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_cache", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_cache", "username", "", false, nil).Once()
+	suite.Emitter.On("Emit", "mojang_textures:before_result", "username", "").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:before_call", "username").Once()
+	suite.Emitter.On("Emit", "mojang_textures:usernames:after_call", "username", expectedProfile, context.Canceled).Once()
+	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, context.Canceled).Once().Run(func(args mock.Arguments) {
+		close(done)
+	})
+
+	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
+
+	// The provider must receive the cancellation of the job's context once the only waiter has gone
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().Run(func(args mock.Arguments) {
+		cancel()
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled)
+
+	result, err := suite.Provider.GetForUsername(ctx, "username")
+	suite.Assert().Nil(result)
+	suite.Assert().Equal(context.Canceled, err)
+
+	<-done
+}
+
 func (suite *providerTestSuite) TestGetForNotAllowedMojangUsername() {
-	result, err := suite.Provider.GetForUsername("Not allowed")
+	result, err := suite.Provider.GetForUsername(context.Background(), "Not allowed")
 	suite.Assert().Error(err, "invalid username")
 	suite.Assert().Nil(result)
 }
@@ -368,7 +660,7 @@ func (suite *providerTestSuite) TestGetErrorFromUUIDsStorage() {
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, expectedErr)
 
-	result, err := suite.Provider.GetForUsername("username")
+	result, err := suite.Provider.GetForUsername(context.Background(), "username")
 
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(expectedErr, err)
@@ -388,9 +680,9 @@ func (suite *providerTestSuite) TestGetErrorFromUuidsProvider() {
 	suite.Emitter.On("Emit", "mojang_textures:after_result", "username", expectedResult, err).Once()
 
 	suite.Storage.On("GetUuid", "username").Once().Return("", false, nil)
-	suite.UuidsProvider.On("GetUuid", "username").Once().Return(nil, err)
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(nil, err)
 
-	result, resErr := suite.Provider.GetForUsername("username")
+	result, resErr := suite.Provider.GetForUsername(context.Background(), "username")
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(err, resErr)
 }
@@ -412,10 +704,10 @@ func (suite *providerTestSuite) TestGetErrorFromTexturesProvider() {
 
 	suite.Storage.On("GetUuid", "username").Return("", false, nil)
 	suite.Storage.On("StoreUuid", "username", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Return(nil)
-	suite.UuidsProvider.On("GetUuid", "username").Once().Return(expectedProfile, nil)
+	suite.UuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(expectedProfile, nil)
 	suite.TexturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, err)
 
-	result, resErr := suite.Provider.GetForUsername("username")
+	result, resErr := suite.Provider.GetForUsername(context.Background(), "username")
 	suite.Assert().Nil(result)
 	suite.Assert().Equal(err, resErr)
 }
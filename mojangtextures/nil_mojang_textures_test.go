@@ -1,6 +1,7 @@
 package mojangtextures
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -8,7 +9,7 @@ import (
 
 func TestNilProvider_GetForUsername(t *testing.T) {
 	provider := &NilProvider{}
-	result, err := provider.GetForUsername("username")
+	result, err := provider.GetForUsername(context.Background(), "username")
 	assert.Nil(t, result)
 	assert.Nil(t, err)
 }
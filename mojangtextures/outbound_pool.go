@@ -0,0 +1,156 @@
+package mojangtextures
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutboundEndpoint is a single route to Mojang's session server: a local address or a proxy
+type OutboundEndpoint struct {
+	// Name is used to identify the endpoint in the emitted events
+	Name      string
+	Transport http.RoundTripper
+
+	lock           sync.Mutex
+	unhealthyUntil time.Time
+}
+
+// NewLocalAddrEndpoint creates an endpoint, that binds outgoing connections to the passed local IP address
+func NewLocalAddrEndpoint(addr string) (*OutboundEndpoint, error) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid local address \"%s\"", addr)
+	}
+
+	dialer := &net.Dialer{
+		LocalAddr: &net.TCPAddr{IP: ip},
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &OutboundEndpoint{
+		Name: sanitizeEndpointName(ip.String()),
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			MaxIdleConnsPerHost: 1024,
+		},
+	}, nil
+}
+
+// NewProxyEndpoint creates an endpoint, that sends requests through the HTTP(S) or SOCKS5 proxy
+func NewProxyEndpoint(proxyUrl string) (*OutboundEndpoint, error) {
+	u, err := url.Parse(proxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse proxy url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme \"%s\"", u.Scheme)
+	}
+
+	return &OutboundEndpoint{
+		// Credentials must not leak into the metrics
+		Name: sanitizeEndpointName(u.Scheme + "_" + u.Host),
+		Transport: &http.Transport{
+			Proxy:               http.ProxyURL(u),
+			MaxIdleConnsPerHost: 1024,
+		},
+	}, nil
+}
+
+func (e *OutboundEndpoint) isHealthy() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return !now().Before(e.unhealthyUntil)
+}
+
+func (e *OutboundEndpoint) getUnhealthyUntil() time.Time {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return e.unhealthyUntil
+}
+
+func (e *OutboundEndpoint) markUnhealthy(duration time.Duration) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.unhealthyUntil = now().Add(duration)
+}
+
+// OutboundPool rotates the requests across the endpoints. An endpoint, that has been rate limited
+// or failed to connect, is excluded from the rotation for the CoolDown duration.
+// When all endpoints are unhealthy, the one that will recover first is used
+type OutboundPool struct {
+	Emitter
+	Endpoints []*OutboundEndpoint
+	CoolDown  time.Duration
+
+	lock sync.Mutex
+	next int
+}
+
+func (p *OutboundPool) RoundTrip(request *http.Request) (*http.Response, error) {
+	endpoint := p.pick()
+	p.Emit("mojang_textures:outbound_pool:request", endpoint.Name)
+
+	response, err := endpoint.Transport.RoundTrip(request)
+	if p.isEndpointFailure(request, response, err) {
+		endpoint.markUnhealthy(p.CoolDown)
+		p.Emit("mojang_textures:outbound_pool:unhealthy", endpoint.Name, p.countHealthy())
+	}
+
+	return response, err
+}
+
+func (p *OutboundPool) pick() *OutboundEndpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var fallback *OutboundEndpoint
+	for i := 0; i < len(p.Endpoints); i++ {
+		endpoint := p.Endpoints[(p.next+i)%len(p.Endpoints)]
+		if endpoint.isHealthy() {
+			p.next = (p.next + i + 1) % len(p.Endpoints)
+			return endpoint
+		}
+
+		if fallback == nil || endpoint.getUnhealthyUntil().Before(fallback.getUnhealthyUntil()) {
+			fallback = endpoint
+		}
+	}
+
+	return fallback
+}
+
+func (p *OutboundPool) countHealthy() int {
+	healthy := 0
+	for _, endpoint := range p.Endpoints {
+		if endpoint.isHealthy() {
+			healthy++
+		}
+	}
+
+	return healthy
+}
+
+func (p *OutboundPool) isEndpointFailure(request *http.Request, response *http.Response, err error) bool {
+	if err != nil {
+		// The cancelled request tells nothing about the endpoint's state
+		return request.Context().Err() == nil
+	}
+
+	return response.StatusCode == http.StatusTooManyRequests
+}
+
+func sanitizeEndpointName(name string) string {
+	return strings.NewReplacer(".", "_", ":", "_", "[", "", "]", "").Replace(name)
+}
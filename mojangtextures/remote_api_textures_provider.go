@@ -0,0 +1,67 @@
+package mojangtextures
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	. "net/url"
+	"path"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/tracing"
+	"github.com/elyby/chrly/version"
+)
+
+type RemoteApiTexturesProvider struct {
+	Emitter
+	Url URL
+	// Token is sent as the bearer token to authenticate on the worker
+	Token string
+}
+
+func (p *RemoteApiTexturesProvider) GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	url := p.Url
+	url.Path = path.Join(url.Path, uuid)
+	urlStr := url.String()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Accept", "application/json")
+	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+	addAuthorizationHeader(request, p.Token)
+	tracing.InjectHeaders(request)
+
+	p.Emit("mojang_textures:remote_api_textures_provider:before_request", urlStr)
+	response, err := HttpClient.Do(request)
+	p.Emit("mojang_textures:remote_api_textures_provider:after_request", response, err)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == 204 {
+		return nil, nil
+	}
+
+	// The worker responds with 429 when Mojang's rate limit has been exceeded
+	if response.StatusCode == 429 {
+		return nil, &mojang.TooManyRequestsError{}
+	}
+
+	if response.StatusCode != 200 {
+		return nil, &UnexpectedRemoteApiResponse{response}
+	}
+
+	var result *mojang.SignedTexturesResponse
+	body, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
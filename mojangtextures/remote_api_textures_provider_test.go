@@ -0,0 +1,145 @@
+package mojangtextures
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+type remoteApiTexturesProviderTestSuite struct {
+	suite.Suite
+
+	Provider *RemoteApiTexturesProvider
+	Emitter  *mockEmitter
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) SetupSuite() {
+	client := &http.Client{}
+	gock.InterceptClient(client)
+
+	HttpClient = client
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) SetupTest() {
+	suite.Emitter = &mockEmitter{}
+	suite.Provider = &RemoteApiTexturesProvider{
+		Emitter: suite.Emitter,
+		Url:     shouldParseUrl("http://example.com/subpath"),
+	}
+
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_textures_provider:before_request",
+		"http://example.com/subpath/dead24f9a4fa4877b7b04c8c6c72bb46",
+	).Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_textures_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TearDownTest() {
+	suite.Emitter.AssertExpectations(suite.T())
+	gock.Off()
+}
+
+func TestRemoteApiTexturesProvider(t *testing.T) {
+	suite.Run(t, new(remoteApiTexturesProviderTestSuite))
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TestGetTexturesForValidUuid() {
+	gock.New("http://example.com").
+		Get("/subpath/dead24f9a4fa4877b7b04c8c6c72bb46").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"id":   "dead24f9a4fa4877b7b04c8c6c72bb46",
+			"name": "username",
+			"properties": []interface{}{
+				map[string]interface{}{
+					"name":      "textures",
+					"signature": "signature",
+					"value":     "value",
+				},
+			},
+		})
+
+	result, err := suite.Provider.GetTextures(context.Background(), "dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert := suite.Assert()
+	if assert.NoError(err) {
+		assert.Equal("dead24f9a4fa4877b7b04c8c6c72bb46", result.Id)
+		assert.Equal("username", result.Name)
+		assert.Len(result.Props, 1)
+		assert.Equal("signature", result.Props[0].Signature)
+	}
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TestGetTexturesWithToken() {
+	gock.New("http://example.com").
+		Get("/subpath/dead24f9a4fa4877b7b04c8c6c72bb46").
+		MatchHeader("Authorization", "^Bearer mock-token$").
+		Reply(204)
+
+	suite.Provider.Token = "mock-token"
+	result, err := suite.Provider.GetTextures(context.Background(), "dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.Nil(err)
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TestGetTexturesForNotExistsUuid() {
+	gock.New("http://example.com").
+		Get("/subpath/dead24f9a4fa4877b7b04c8c6c72bb46").
+		Reply(204)
+
+	result, err := suite.Provider.GetTextures(context.Background(), "dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.Nil(err)
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TestGetTexturesForTooManyRequestsResponse() {
+	gock.New("http://example.com").
+		Get("/subpath/dead24f9a4fa4877b7b04c8c6c72bb46").
+		Reply(429)
+
+	result, err := suite.Provider.GetTextures(context.Background(), "dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.IsType(&mojang.TooManyRequestsError{}, err)
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TestGetTexturesForNon20xResponse() {
+	gock.New("http://example.com").
+		Get("/subpath/dead24f9a4fa4877b7b04c8c6c72bb46").
+		Reply(504).
+		BodyString("504 Gateway Timeout")
+
+	result, err := suite.Provider.GetTextures(context.Background(), "dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.EqualError(err, "Unexpected remote api response")
+}
+
+func (suite *remoteApiTexturesProviderTestSuite) TestGetTexturesForInvalidSuccessResponse() {
+	gock.New("http://example.com").
+		Get("/subpath/dead24f9a4fa4877b7b04c8c6c72bb46").
+		Reply(200).
+		BodyString("completely not json")
+
+	result, err := suite.Provider.GetTextures(context.Background(), "dead24f9a4fa4877b7b04c8c6c72bb46")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.Error(err)
+}
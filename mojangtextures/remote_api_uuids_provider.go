@@ -1,13 +1,22 @@
 package mojangtextures
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	. "net/url"
 	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/tracing"
 	"github.com/elyby/chrly/version"
 )
 
@@ -19,22 +28,51 @@ var HttpClient = &http.Client{
 
 type RemoteApiUuidsProvider struct {
 	Emitter
-	Url    URL
+	Url URL
+	// Token is sent as the bearer token to authenticate on the worker
+	Token string
+	// BatchUrl enables the batching mode: the usernames requested within the BatchDelay
+	// are resolved by the single request to the worker's batch endpoint
+	BatchUrl   *URL
+	BatchDelay time.Duration
+	// BatchSize is the number of usernames, after which the batch is sent without waiting for the BatchDelay
+	BatchSize int
+
+	batchLock sync.Mutex
+	batch     *remoteUuidsBatch
 }
 
-func (ctx *RemoteApiUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo, error) {
-	url := ctx.Url
+type remoteUuidsBatch struct {
+	usernames []string
+	// Listeners are grouped by the lowercased username, since usernames are case insensitive
+	listeners map[string][]chan *jobResult
+	// Traces of the callers, that are waiting for the batch
+	links []trace.Link
+}
+
+func (p *RemoteApiUuidsProvider) GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
+	if p.BatchUrl != nil {
+		return p.getUuidFromBatch(ctx, username)
+	}
+
+	url := p.Url
 	url.Path = path.Join(url.Path, username)
 	urlStr := url.String()
 
-	request, _ := http.NewRequest("GET", urlStr, nil)
+	request, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	request.Header.Add("Accept", "application/json")
 	// Change default User-Agent to allow specify "Username -> UUID at time" Mojang's api endpoint
 	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+	addAuthorizationHeader(request, p.Token)
+	tracing.InjectHeaders(request)
 
-	ctx.Emit("mojang_textures:remote_api_uuids_provider:before_request", urlStr)
+	p.Emit("mojang_textures:remote_api_uuids_provider:before_request", urlStr)
 	response, err := HttpClient.Do(request)
-	ctx.Emit("mojang_textures:remote_api_uuids_provider:after_request", response, err)
+	p.Emit("mojang_textures:remote_api_uuids_provider:after_request", response, err)
 	if err != nil {
 		return nil, err
 	}
@@ -58,6 +96,154 @@ func (ctx *RemoteApiUuidsProvider) GetUuid(username string) (*mojang.ProfileInfo
 	return result, nil
 }
 
+func (p *RemoteApiUuidsProvider) getUuidFromBatch(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
+	// The chan is buffered, so the batch will not be blocked when the caller has gone
+	resultChan := make(chan *jobResult, 1)
+	p.addToBatch(ctx, username, resultChan)
+
+	select {
+	case result := <-resultChan:
+		return result.Profile, result.Error
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *RemoteApiUuidsProvider) addToBatch(ctx context.Context, username string, resultChan chan *jobResult) {
+	p.batchLock.Lock()
+	defer p.batchLock.Unlock()
+
+	batch := p.batch
+	if batch == nil {
+		batch = &remoteUuidsBatch{listeners: make(map[string][]chan *jobResult)}
+		p.batch = batch
+		time.AfterFunc(p.BatchDelay, func() {
+			p.sendBatch(batch)
+		})
+	}
+
+	key := strings.ToLower(username)
+	if _, ok := batch.listeners[key]; !ok {
+		batch.usernames = append(batch.usernames, username)
+	}
+
+	batch.listeners[key] = append(batch.listeners[key], resultChan)
+	batch.links = append(batch.links, trace.LinkFromContext(ctx))
+	if p.BatchSize > 0 && len(batch.usernames) >= p.BatchSize {
+		go p.sendBatch(batch)
+	}
+}
+
+func (p *RemoteApiUuidsProvider) sendBatch(batch *remoteUuidsBatch) {
+	p.batchLock.Lock()
+	// The batch may have already been sent by the size limit
+	if p.batch != batch {
+		p.batchLock.Unlock()
+		return
+	}
+
+	p.batch = nil
+	p.batchLock.Unlock()
+
+	// The batch is shared by multiple callers, so it can't be bound to any of their contexts
+	requestContext, span := tracer.Start(context.Background(), "mojangtextures.RemoteApiUuidsProvider.sendBatch",
+		trace.WithLinks(batch.links...),
+		trace.WithAttributes(attribute.StringSlice("mojang.usernames", batch.usernames)),
+	)
+	results, err := p.requestBatch(requestContext, batch.usernames)
+	tracing.EndSpan(span, err)
+	for key, listeners := range batch.listeners {
+		result := &jobResult{}
+		if err != nil {
+			result.Error = err
+		} else if found, ok := results[key]; ok {
+			result = found
+		} else {
+			// The missing username must not be treated as the absence of the account, since it'll be cached
+			result.Error = &RemoteApiProviderError{"the username is missing from the batch response"}
+		}
+
+		for _, listener := range listeners {
+			listener <- result
+		}
+	}
+}
+
+type remoteUuidsBatchResult struct {
+	Username string              `json:"username"`
+	Profile  *mojang.ProfileInfo `json:"profile"`
+	Error    *struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Returns the results indexed by the lowercased username
+func (p *RemoteApiUuidsProvider) requestBatch(ctx context.Context, usernames []string) (map[string]*jobResult, error) {
+	urlStr := p.BatchUrl.String()
+	requestBody, _ := json.Marshal(usernames)
+	request, err := http.NewRequestWithContext(ctx, "POST", urlStr, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Accept", "application/json")
+	request.Header.Add("Content-Type", "application/json")
+	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+	addAuthorizationHeader(request, p.Token)
+	tracing.InjectHeaders(request)
+
+	p.Emit("mojang_textures:remote_api_uuids_provider:before_request", urlStr)
+	response, err := HttpClient.Do(request)
+	p.Emit("mojang_textures:remote_api_uuids_provider:after_request", response, err)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		return nil, &UnexpectedRemoteApiResponse{response}
+	}
+
+	var batchResults []*remoteUuidsBatchResult
+	body, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(body, &batchResults)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]*jobResult, len(batchResults))
+	for _, batchResult := range batchResults {
+		result := &jobResult{Profile: batchResult.Profile}
+		if batchResult.Error != nil {
+			if batchResult.Error.Status == http.StatusTooManyRequests {
+				result.Error = &mojang.TooManyRequestsError{}
+			} else {
+				result.Error = &RemoteApiProviderError{batchResult.Error.Message}
+			}
+		}
+
+		results[strings.ToLower(batchResult.Username)] = result
+	}
+
+	return results, nil
+}
+
+// RemoteApiProviderError is returned when the remote worker has failed to resolve the username
+type RemoteApiProviderError struct {
+	Message string
+}
+
+func (e *RemoteApiProviderError) Error() string {
+	return "Remote api provider error: " + e.Message
+}
+
+func addAuthorizationHeader(request *http.Request, token string) {
+	if token != "" {
+		request.Header.Add("Authorization", "Bearer "+token)
+	}
+}
+
 type UnexpectedRemoteApiResponse struct {
 	Response *http.Response
 }
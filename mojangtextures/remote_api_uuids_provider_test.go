@@ -1,14 +1,19 @@
 package mojangtextures
 
 import (
+	"context"
 	"net"
 	"net/http"
 	. "net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/h2non/gock"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/elyby/chrly/api/mojang"
 )
 
 type remoteApiUuidsProviderTestSuite struct {
@@ -58,7 +63,7 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForValidUsername() {
 		})
 
 	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	if assert.NoError(err) {
@@ -82,7 +87,29 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNotExistsUsername()
 		Reply(204)
 
 	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.Nil(err)
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidWithToken() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/username").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Get("/subpath/username").
+		MatchHeader("Authorization", "^Bearer mock-token$").
+		Reply(204)
+
+	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
+	suite.Provider.Token = "mock-token"
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
@@ -103,7 +130,7 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNon20xResponse() {
 		BodyString("504 Gateway Timeout")
 
 	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
@@ -125,7 +152,7 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForNotSuccessRequest()
 		ReplyError(expectedError)
 
 	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
@@ -150,13 +177,177 @@ func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidForInvalidSuccessRespon
 		BodyString("completely not json")
 
 	suite.Provider.Url = shouldParseUrl("http://example.com/subpath")
-	result, err := suite.Provider.GetUuid("username")
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
 
 	assert := suite.Assert()
 	assert.Nil(result)
 	assert.Error(err)
 }
 
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidInBatchMode() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/batch").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/subpath/batch").
+		MatchType("json").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{
+				"username": "username1",
+				"profile": map[string]interface{}{
+					"id":   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+					"name": "username1",
+				},
+			},
+			map[string]interface{}{
+				"username": "username2",
+				"profile":  nil,
+			},
+			map[string]interface{}{
+				"username": "username3",
+				"profile":  nil,
+				"error": map[string]interface{}{
+					"status":  429,
+					"message": "429: Too Many Requests",
+				},
+			},
+			map[string]interface{}{
+				"username": "username4",
+				"profile":  nil,
+				"error": map[string]interface{}{
+					"status":  500,
+					"message": "this is an error",
+				},
+			},
+		})
+
+	batchUrl := shouldParseUrl("http://example.com/subpath/batch")
+	suite.Provider.BatchUrl = &batchUrl
+	suite.Provider.BatchDelay = 50 * time.Millisecond
+	suite.Provider.BatchSize = 100
+
+	type result struct {
+		Profile *mojang.ProfileInfo
+		Error   error
+	}
+
+	usernames := []string{"username1", "USERNAME1", "username2", "username3", "username4"}
+	results := make([]*result, len(usernames))
+	var wg sync.WaitGroup
+	for i, username := range usernames {
+		wg.Add(1)
+		go func(i int, username string) {
+			defer wg.Done()
+			profile, err := suite.Provider.GetUuid(context.Background(), username)
+			results[i] = &result{profile, err}
+		}(i, username)
+	}
+
+	wg.Wait()
+
+	assert := suite.Assert()
+	for i := 0; i < 2; i++ {
+		assert.Nil(results[i].Error)
+		if assert.NotNil(results[i].Profile) {
+			assert.Equal("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", results[i].Profile.Id)
+		}
+	}
+
+	assert.Nil(results[2].Profile)
+	assert.Nil(results[2].Error)
+	assert.IsType(&mojang.TooManyRequestsError{}, results[3].Error)
+	assert.EqualError(results[4].Error, "Remote api provider error: this is an error")
+	assert.True(gock.IsDone())
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidInBatchModeShouldSendFullBatchImmediately() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/batch").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/subpath/batch").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"username": "username1", "profile": nil},
+			map[string]interface{}{"username": "username2", "profile": nil},
+		})
+
+	batchUrl := shouldParseUrl("http://example.com/subpath/batch")
+	suite.Provider.BatchUrl = &batchUrl
+	suite.Provider.BatchDelay = time.Hour
+	suite.Provider.BatchSize = 2
+
+	var wg sync.WaitGroup
+	for _, username := range []string{"username1", "username2"} {
+		wg.Add(1)
+		go func(username string) {
+			defer wg.Done()
+			profile, err := suite.Provider.GetUuid(context.Background(), username)
+			suite.Assert().Nil(profile)
+			suite.Assert().Nil(err)
+		}(username)
+	}
+
+	wg.Wait()
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidInBatchModeForMissingUsername() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/batch").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/subpath/batch").
+		Reply(200).
+		JSON([]interface{}{})
+
+	batchUrl := shouldParseUrl("http://example.com/subpath/batch")
+	suite.Provider.BatchUrl = &batchUrl
+	suite.Provider.BatchDelay = time.Millisecond
+
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.EqualError(err, "Remote api provider error: the username is missing from the batch response")
+}
+
+func (suite *remoteApiUuidsProviderTestSuite) TestGetUuidInBatchModeForNon20xResponse() {
+	suite.Emitter.On("Emit", "mojang_textures:remote_api_uuids_provider:before_request", "http://example.com/subpath/batch").Once()
+	suite.Emitter.On("Emit",
+		"mojang_textures:remote_api_uuids_provider:after_request",
+		mock.AnythingOfType("*http.Response"),
+		nil,
+	).Once()
+
+	gock.New("http://example.com").
+		Post("/subpath/batch").
+		Reply(504).
+		BodyString("504 Gateway Timeout")
+
+	batchUrl := shouldParseUrl("http://example.com/subpath/batch")
+	suite.Provider.BatchUrl = &batchUrl
+	suite.Provider.BatchDelay = time.Millisecond
+
+	result, err := suite.Provider.GetUuid(context.Background(), "username")
+
+	assert := suite.Assert()
+	assert.Nil(result)
+	assert.EqualError(err, "Unexpected remote api response")
+}
+
 func shouldParseUrl(rawUrl string) URL {
 	url, err := Parse(rawUrl)
 	if err != nil {
@@ -0,0 +1,132 @@
+package mojangtextures
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+const (
+	ServerErrorKind          = "server"
+	TimeoutErrorKind         = "timeout"
+	ConnectionErrorKind      = "connection"
+	TooManyRequestsErrorKind = "too_many_requests"
+)
+
+// RetryPolicy describes how the failed requests to Mojang's API should be retried
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first one
+	Attempts int
+	// Delay is the base delay before the first retry. It's doubled for each subsequent retry
+	Delay time.Duration
+	// MaxDelay limits the delay between retries
+	MaxDelay time.Duration
+	// Errors lists the kinds of errors, which should be retried
+	Errors []string
+}
+
+// Do calls the passed function until it succeeds, returns an error that shouldn't be retried
+// or the attempts are exhausted. The onRetry callback is called before each retry
+func (p *RetryPolicy) Do(ctx context.Context, fn func() error, onRetry func(attempt int, err error)) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil || !p.isRetryable(err) {
+			return err
+		}
+
+		onRetry(attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.getDelay(attempt)):
+		}
+	}
+}
+
+func (p *RetryPolicy) isRetryable(err error) bool {
+	kind := getErrorKind(err)
+	for _, retryableKind := range p.Errors {
+		if kind == retryableKind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The delay is jittered in the range [delay/2, delay) to prevent simultaneous retries from the different callers
+func (p *RetryPolicy) getDelay(attempt int) time.Duration {
+	delay := p.Delay << uint(attempt-1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+
+	half := int64(delay / 2)
+	if half == 0 {
+		return delay
+	}
+
+	return time.Duration(half + rand.Int63n(half))
+}
+
+func getErrorKind(err error) string {
+	switch err := err.(type) {
+	case *mojang.ServerError:
+		return ServerErrorKind
+	case *mojang.TooManyRequestsError:
+		return TooManyRequestsErrorKind
+	case net.Error:
+		if err.Timeout() {
+			return TimeoutErrorKind
+		}
+
+		return ConnectionErrorKind
+	}
+
+	return ""
+}
+
+type RetryingUuidsProvider struct {
+	Emitter
+	UUIDsProvider
+	*RetryPolicy
+}
+
+func (p *RetryingUuidsProvider) GetUuid(ctx context.Context, username string) (*mojang.ProfileInfo, error) {
+	var result *mojang.ProfileInfo
+	err := p.Do(ctx, func() error {
+		var err error
+		result, err = p.UUIDsProvider.GetUuid(ctx, username)
+
+		return err
+	}, func(attempt int, err error) {
+		p.Emit("mojang_textures:usernames:retry", username, attempt, err)
+	})
+
+	return result, err
+}
+
+type RetryingTexturesProvider struct {
+	Emitter
+	TexturesProvider
+	*RetryPolicy
+}
+
+func (p *RetryingTexturesProvider) GetTextures(ctx context.Context, uuid string) (*mojang.SignedTexturesResponse, error) {
+	var result *mojang.SignedTexturesResponse
+	err := p.Do(ctx, func() error {
+		var err error
+		result, err = p.TexturesProvider.GetTextures(ctx, uuid)
+
+		return err
+	}, func(attempt int, err error) {
+		p.Emit("mojang_textures:textures:retry", uuid, attempt, err)
+	})
+
+	return result, err
+}
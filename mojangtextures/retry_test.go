@@ -0,0 +1,178 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+)
+
+type timeoutError struct{}
+
+func (*timeoutError) Error() string   { return "timeout error" }
+func (*timeoutError) Timeout() bool   { return true }
+func (*timeoutError) Temporary() bool { return false }
+
+func createRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		Attempts: 3,
+		Delay:    time.Millisecond,
+		MaxDelay: 2 * time.Millisecond,
+		Errors:   []string{ServerErrorKind, TimeoutErrorKind, ConnectionErrorKind},
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	t.Run("should not retry successful call", func(t *testing.T) {
+		calls := 0
+		err := createRetryPolicy().Do(context.Background(), func() error {
+			calls++
+			return nil
+		}, func(attempt int, err error) {
+			require.Fail(t, "onRetry should not be called")
+		})
+
+		require.Nil(t, err)
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("should retry until the attempts are exhausted", func(t *testing.T) {
+		expectedErr := &mojang.ServerError{Status: 503}
+		calls := 0
+		var retries []int
+		err := createRetryPolicy().Do(context.Background(), func() error {
+			calls++
+			return expectedErr
+		}, func(attempt int, err error) {
+			require.Equal(t, expectedErr, err)
+			retries = append(retries, attempt)
+		})
+
+		require.Equal(t, expectedErr, err)
+		require.Equal(t, 3, calls)
+		require.Equal(t, []int{1, 2}, retries)
+	})
+
+	t.Run("should stop retrying after success", func(t *testing.T) {
+		calls := 0
+		err := createRetryPolicy().Do(context.Background(), func() error {
+			calls++
+			if calls == 1 {
+				return &timeoutError{}
+			}
+
+			return nil
+		}, func(attempt int, err error) {})
+
+		require.Nil(t, err)
+		require.Equal(t, 2, calls)
+	})
+
+	t.Run("should not retry errors of not configured kinds", func(t *testing.T) {
+		for _, expectedErr := range []error{
+			&mojang.TooManyRequestsError{},
+			&mojang.BadRequestError{},
+			errors.New("unexpected error"),
+		} {
+			calls := 0
+			err := createRetryPolicy().Do(context.Background(), func() error {
+				calls++
+				return expectedErr
+			}, func(attempt int, err error) {})
+
+			require.Equal(t, expectedErr, err)
+			require.Equal(t, 1, calls)
+		}
+	})
+
+	t.Run("should stop retrying when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		policy := createRetryPolicy()
+		policy.Delay = time.Minute
+		policy.MaxDelay = time.Minute
+
+		calls := 0
+		err := policy.Do(ctx, func() error {
+			calls++
+			return &net.OpError{Op: "dial"}
+		}, func(attempt int, err error) {
+			cancel()
+		})
+
+		require.Equal(t, context.Canceled, err)
+		require.Equal(t, 1, calls)
+	})
+}
+
+func TestRetryPolicy_getDelay(t *testing.T) {
+	policy := &RetryPolicy{
+		Delay:    100 * time.Millisecond,
+		MaxDelay: 300 * time.Millisecond,
+	}
+
+	for attempt, expectedDelay := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 300 * time.Millisecond,
+		4: 300 * time.Millisecond,
+	} {
+		delay := policy.getDelay(attempt)
+		require.True(t, delay >= expectedDelay/2 && delay < expectedDelay, "attempt %d: %s", attempt, delay)
+	}
+}
+
+func TestRetryingUuidsProvider_GetUuid(t *testing.T) {
+	expectedErr := &mojang.ServerError{Status: 500}
+	expectedResult := &mojang.ProfileInfo{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+
+	emitter := &mockEmitter{}
+	emitter.On("Emit", "mojang_textures:usernames:retry", "username", 1, expectedErr).Once()
+
+	uuidsProvider := &mockUuidsProvider{}
+	uuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(nil, expectedErr)
+	uuidsProvider.On("GetUuid", mock.Anything, "username").Once().Return(expectedResult, nil)
+
+	provider := &RetryingUuidsProvider{
+		Emitter:       emitter,
+		UUIDsProvider: uuidsProvider,
+		RetryPolicy:   createRetryPolicy(),
+	}
+
+	result, err := provider.GetUuid(context.Background(), "username")
+	require.Nil(t, err)
+	require.Equal(t, expectedResult, result)
+
+	emitter.AssertExpectations(t)
+	uuidsProvider.AssertExpectations(t)
+}
+
+func TestRetryingTexturesProvider_GetTextures(t *testing.T) {
+	expectedErr := &timeoutError{}
+	expectedResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+
+	emitter := &mockEmitter{}
+	emitter.On("Emit", "mojang_textures:textures:retry", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", 1, expectedErr).Once()
+
+	texturesProvider := &mockTexturesProvider{}
+	texturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(nil, expectedErr)
+	texturesProvider.On("GetTextures", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa").Once().Return(expectedResult, nil)
+
+	provider := &RetryingTexturesProvider{
+		Emitter:          emitter,
+		TexturesProvider: texturesProvider,
+		RetryPolicy:      createRetryPolicy(),
+	}
+
+	result, err := provider.GetTextures(context.Background(), "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")
+	require.Nil(t, err)
+	require.Equal(t, expectedResult, result)
+
+	emitter.AssertExpectations(t)
+	texturesProvider.AssertExpectations(t)
+}
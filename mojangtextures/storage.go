@@ -18,12 +18,21 @@ type UUIDsStorage interface {
 // TexturesStorage is a Mojang's textures storage, used as a values cache to avoid 429 errors
 type TexturesStorage interface {
 	// Error should not have nil value only if the repository failed to determine if there are any textures
-	// for this uuid or not at all. If there is information about the absence of textures, nil nil should be returned
-	GetTextures(uuid string) (*mojang.SignedTexturesResponse, error)
+	// for this uuid or not at all. The second argument indicates whether a record was found in the storage,
+	// so if there is information about the absence of textures, nil textures with true value should be returned
+	GetTextures(uuid string) (textures *mojang.SignedTexturesResponse, found bool, err error)
 	// The nil value can be passed when there are no textures for the corresponding uuid and we know about it
 	StoreTextures(uuid string, textures *mojang.SignedTexturesResponse)
 }
 
+// StaleTexturesStorage is implemented by the textures storages, that keep the expired textures
+// for some time, so they can be served while the fresh ones are requested in the background
+type StaleTexturesStorage interface {
+	// Returns textures, that are already expired, but still not removed from the storage.
+	// The second argument has the same meaning as in the TexturesStorage.GetTextures
+	GetStaleTextures(uuid string) (textures *mojang.SignedTexturesResponse, found bool, err error)
+}
+
 type Storage interface {
 	UUIDsStorage
 	TexturesStorage
@@ -44,10 +53,19 @@ func (s *SeparatedStorage) StoreUuid(username string, uuid string) error {
 	return s.UUIDsStorage.StoreUuid(username, uuid)
 }
 
-func (s *SeparatedStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (s *SeparatedStorage) GetTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
 	return s.TexturesStorage.GetTextures(uuid)
 }
 
 func (s *SeparatedStorage) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
 	s.TexturesStorage.StoreTextures(uuid, textures)
 }
+
+func (s *SeparatedStorage) GetStaleTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
+	storage, ok := s.TexturesStorage.(StaleTexturesStorage)
+	if !ok {
+		return nil, false, nil
+	}
+
+	return storage.GetStaleTextures(uuid)
+}
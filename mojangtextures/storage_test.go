@@ -26,14 +26,14 @@ type texturesStorageMock struct {
 	mock.Mock
 }
 
-func (m *texturesStorageMock) GetTextures(uuid string) (*mojang.SignedTexturesResponse, error) {
+func (m *texturesStorageMock) GetTextures(uuid string) (*mojang.SignedTexturesResponse, bool, error) {
 	args := m.Called(uuid)
 	var result *mojang.SignedTexturesResponse
 	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
 		result = casted
 	}
 
-	return result, args.Error(1)
+	return result, args.Bool(1), args.Error(2)
 }
 
 func (m *texturesStorageMock) StoreTextures(uuid string, textures *mojang.SignedTexturesResponse) {
@@ -68,9 +68,10 @@ func TestSplittedStorage(t *testing.T) {
 	t.Run("GetTextures", func(t *testing.T) {
 		result := &mojang.SignedTexturesResponse{Id: "mock id"}
 		storage, _, texturesMock := createMockedStorage()
-		texturesMock.On("GetTextures", "uuid").Once().Return(result, nil)
-		returned, err := storage.GetTextures("uuid")
+		texturesMock.On("GetTextures", "uuid").Once().Return(result, true, nil)
+		returned, found, err := storage.GetTextures("uuid")
 		assert.Nil(t, err)
+		assert.True(t, found)
 		assert.Equal(t, result, returned)
 		texturesMock.AssertExpectations(t)
 	})
@@ -0,0 +1,153 @@
+package mojangtextures
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	. "net/url"
+	"path"
+	"time"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/logging"
+	"github.com/elyby/chrly/tracing"
+	"github.com/elyby/chrly/version"
+)
+
+type UsernameTexturesProvider interface {
+	GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error)
+}
+
+// TexturesSource is a named upstream of the signed textures
+type TexturesSource struct {
+	// Name is used to identify the source in the emitted events
+	Name     string
+	Provider UsernameTexturesProvider
+	// Timeout limits the time of the source call. Zero value disables the limit
+	Timeout time.Duration
+}
+
+// TexturesSourcesChain asks the sources in the order they're specified until one of them returns textures.
+// A source, that failed or doesn't know the username, passes the call to the next one
+type TexturesSourcesChain struct {
+	Emitter
+	Sources []*TexturesSource
+}
+
+func (c *TexturesSourcesChain) GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	// The username becomes a part of the remote sources urls, so it must be validated before any of them is called
+	if !allowedUsernamesRegex.MatchString(username) {
+		return nil, errors.New("invalid username")
+	}
+
+	var lastErr error
+	for _, source := range c.Sources {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		result, err := c.callSource(ctx, source, username)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if result != nil {
+			// Mojang's provider records a more precise source, so the name is used only when nothing has been recorded
+			if info := logging.RequestInfoFromContext(ctx); info != nil && info.TexturesSource == "" {
+				info.TexturesSource = source.Name
+			}
+
+			return result, nil
+		}
+	}
+
+	return nil, lastErr
+}
+
+func (c *TexturesSourcesChain) callSource(
+	ctx context.Context,
+	source *TexturesSource,
+	username string,
+) (*mojang.SignedTexturesResponse, error) {
+	if source.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, source.Timeout)
+		defer cancel()
+	}
+
+	c.Emit("mojang_textures:sources:before_call", source.Name, username)
+	result, err := source.Provider.GetForUsername(ctx, username)
+	c.Emit("mojang_textures:sources:after_call", source.Name, username, result, err)
+
+	return result, err
+}
+
+// RemoteSkinsystemTexturesProvider obtains textures from the signed textures endpoint of another skin system
+// with the Chrly compatible API (another Chrly instance, Ely.by, etc.)
+type RemoteSkinsystemTexturesProvider struct {
+	Emitter
+	Url URL
+	// Proxy allows the remote skin system to fetch textures from Mojang when it doesn't have own ones
+	Proxy bool
+}
+
+func (p *RemoteSkinsystemTexturesProvider) GetForUsername(
+	ctx context.Context,
+	username string,
+) (*mojang.SignedTexturesResponse, error) {
+	url := p.Url
+	// The username is escaped as a single segment, so it can't change the path of the request
+	url.RawPath = path.Join(url.EscapedPath(), "textures/signed") + "/" + PathEscape(username)
+	url.Path = path.Join(url.Path, "textures/signed") + "/" + username
+	if p.Proxy {
+		url.RawQuery = "proxy=true"
+	}
+
+	urlStr := url.String()
+	request, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Accept", "application/json")
+	request.Header.Add("User-Agent", "Chrly/"+version.Version())
+	tracing.InjectHeaders(request)
+
+	p.Emit("mojang_textures:remote_skinsystem_textures_provider:before_request", urlStr)
+	response, err := HttpClient.Do(request)
+	p.Emit("mojang_textures:remote_skinsystem_textures_provider:after_request", response, err)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == 204 || response.StatusCode == 404 {
+		return nil, nil
+	}
+
+	if response.StatusCode != 200 {
+		return nil, &UnexpectedRemoteApiResponse{response}
+	}
+
+	var result *mojang.SignedTexturesResponse
+	body, _ := ioutil.ReadAll(response.Body)
+	err = json.Unmarshal(body, &result)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	// The remote skin system appends its own extra params, which must not be passed to our clients
+	var props []*mojang.Property
+	for _, prop := range result.Props {
+		if prop.Name == "textures" {
+			props = append(props, prop)
+		}
+	}
+
+	result.Props = props
+
+	return result, nil
+}
@@ -0,0 +1,275 @@
+package mojangtextures
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/api/mojang"
+	"github.com/elyby/chrly/logging"
+)
+
+type mockUsernameTexturesProvider struct {
+	mock.Mock
+}
+
+func (m *mockUsernameTexturesProvider) GetForUsername(ctx context.Context, username string) (*mojang.SignedTexturesResponse, error) {
+	args := m.Called(ctx, username)
+	var result *mojang.SignedTexturesResponse
+	if casted, ok := args.Get(0).(*mojang.SignedTexturesResponse); ok {
+		result = casted
+	}
+
+	return result, args.Error(1)
+}
+
+func TestTexturesSourcesChain_GetForUsername(t *testing.T) {
+	t.Run("should fall through the sources until textures are found", func(t *testing.T) {
+		expectedErr := errors.New("mock error")
+		expectedResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+		var nilResult *mojang.SignedTexturesResponse
+
+		first := &mockUsernameTexturesProvider{}
+		first.On("GetForUsername", mock.Anything, "username").Once().Return(nil, expectedErr)
+		second := &mockUsernameTexturesProvider{}
+		second.On("GetForUsername", mock.Anything, "username").Once().Return(nil, nil)
+		third := &mockUsernameTexturesProvider{}
+		third.On("GetForUsername", mock.Anything, "username").Once().Return(expectedResult, nil)
+		fourth := &mockUsernameTexturesProvider{}
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:sources:before_call", "first", "username").Once()
+		emitter.On("Emit", "mojang_textures:sources:after_call", "first", "username", nilResult, expectedErr).Once()
+		emitter.On("Emit", "mojang_textures:sources:before_call", "second", "username").Once()
+		emitter.On("Emit", "mojang_textures:sources:after_call", "second", "username", nilResult, nil).Once()
+		emitter.On("Emit", "mojang_textures:sources:before_call", "third", "username").Once()
+		emitter.On("Emit", "mojang_textures:sources:after_call", "third", "username", expectedResult, nil).Once()
+
+		chain := &TexturesSourcesChain{
+			Emitter: emitter,
+			Sources: []*TexturesSource{
+				{Name: "first", Provider: first},
+				{Name: "second", Provider: second},
+				{Name: "third", Provider: third},
+				{Name: "fourth", Provider: fourth},
+			},
+		}
+
+		requestInfo := &logging.RequestInfo{}
+		ctx := logging.WithRequestInfo(context.Background(), requestInfo)
+
+		result, err := chain.GetForUsername(ctx, "username")
+		require.Nil(t, err)
+		require.Equal(t, expectedResult, result)
+		require.Equal(t, "third", requestInfo.TexturesSource)
+
+		emitter.AssertExpectations(t)
+		first.AssertExpectations(t)
+		second.AssertExpectations(t)
+		third.AssertExpectations(t)
+		fourth.AssertExpectations(t)
+	})
+
+	t.Run("should keep the textures source recorded by the provider", func(t *testing.T) {
+		expectedResult := &mojang.SignedTexturesResponse{Id: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", Name: "username"}
+
+		provider := &mockUsernameTexturesProvider{}
+		provider.On("GetForUsername", mock.Anything, "username").Once().Run(func(args mock.Arguments) {
+			logging.SetTexturesSource(args.Get(0).(context.Context), logging.MojangCacheTexturesSource)
+		}).Return(expectedResult, nil)
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit", mock.Anything, mock.Anything, mock.Anything)
+		emitter.On("Emit", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		chain := &TexturesSourcesChain{
+			Emitter: emitter,
+			Sources: []*TexturesSource{
+				{Name: "mojang", Provider: provider},
+			},
+		}
+
+		requestInfo := &logging.RequestInfo{}
+		ctx := logging.WithRequestInfo(context.Background(), requestInfo)
+
+		_, _ = chain.GetForUsername(ctx, "username")
+		require.Equal(t, logging.MojangCacheTexturesSource, requestInfo.TexturesSource)
+	})
+
+	t.Run("should return the last error when no source has textures", func(t *testing.T) {
+		expectedErr := errors.New("mock error")
+
+		first := &mockUsernameTexturesProvider{}
+		first.On("GetForUsername", mock.Anything, "username").Once().Return(nil, expectedErr)
+		second := &mockUsernameTexturesProvider{}
+		second.On("GetForUsername", mock.Anything, "username").Once().Return(nil, nil)
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit", mock.Anything, mock.Anything, mock.Anything)
+		emitter.On("Emit", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		chain := &TexturesSourcesChain{
+			Emitter: emitter,
+			Sources: []*TexturesSource{
+				{Name: "first", Provider: first},
+				{Name: "second", Provider: second},
+			},
+		}
+
+		result, err := chain.GetForUsername(context.Background(), "username")
+		require.Equal(t, expectedErr, err)
+		require.Nil(t, result)
+	})
+
+	t.Run("should reject the invalid username before calling the sources", func(t *testing.T) {
+		provider := &mockUsernameTexturesProvider{}
+		chain := &TexturesSourcesChain{
+			Emitter: &mockEmitter{},
+			Sources: []*TexturesSource{
+				{Name: "source", Provider: provider},
+			},
+		}
+
+		result, err := chain.GetForUsername(context.Background(), "../../api/skins")
+		require.EqualError(t, err, "invalid username")
+		require.Nil(t, result)
+
+		provider.AssertNotCalled(t, "GetForUsername", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should limit the source call with its timeout", func(t *testing.T) {
+		provider := &mockUsernameTexturesProvider{}
+		provider.On("GetForUsername", mock.Anything, "username").Once().Run(func(args mock.Arguments) {
+			deadline, ok := args.Get(0).(context.Context).Deadline()
+			require.True(t, ok)
+			require.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+		}).Return(nil, nil)
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit", mock.Anything, mock.Anything, mock.Anything)
+		emitter.On("Emit", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+		chain := &TexturesSourcesChain{
+			Emitter: emitter,
+			Sources: []*TexturesSource{
+				{Name: "source", Provider: provider, Timeout: time.Second},
+			},
+		}
+
+		_, _ = chain.GetForUsername(context.Background(), "username")
+		provider.AssertExpectations(t)
+	})
+}
+
+func TestRemoteSkinsystemTexturesProvider_GetForUsername(t *testing.T) {
+	client := &http.Client{}
+	gock.InterceptClient(client)
+	HttpClient = client
+
+	createProvider := func() (*RemoteSkinsystemTexturesProvider, *mockEmitter) {
+		emitter := &mockEmitter{}
+		emitter.On("Emit", "mojang_textures:remote_skinsystem_textures_provider:before_request", mock.Anything).Once()
+		emitter.On("Emit",
+			"mojang_textures:remote_skinsystem_textures_provider:after_request",
+			mock.AnythingOfType("*http.Response"),
+			nil,
+		).Once()
+
+		return &RemoteSkinsystemTexturesProvider{
+			Emitter: emitter,
+			Url:     shouldParseUrl("http://example.com/subpath"),
+		}, emitter
+	}
+
+	t.Run("should return textures without extra params", func(t *testing.T) {
+		defer gock.Off()
+		gock.New("http://example.com").
+			Get("/subpath/textures/signed/username").
+			MatchParam("proxy", "true").
+			Reply(200).
+			JSON(map[string]interface{}{
+				"id":   "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa",
+				"name": "username",
+				"properties": []interface{}{
+					map[string]interface{}{
+						"name":      "textures",
+						"signature": "signature",
+						"value":     "value",
+					},
+					map[string]interface{}{
+						"name":  "chrly",
+						"value": "how do you tame a horse in Minecraft?",
+					},
+				},
+			})
+
+		provider, emitter := createProvider()
+		provider.Proxy = true
+
+		result, err := provider.GetForUsername(context.Background(), "username")
+		require.Nil(t, err)
+		require.Equal(t, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", result.Id)
+		require.Equal(t, "username", result.Name)
+		require.Len(t, result.Props, 1)
+		require.Equal(t, "textures", result.Props[0].Name)
+		require.Equal(t, "signature", result.Props[0].Signature)
+		require.Equal(t, "value", result.Props[0].Value)
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should return nil when the remote skin system has no textures", func(t *testing.T) {
+		defer gock.Off()
+		gock.New("http://example.com").
+			Get("/subpath/textures/signed/username").
+			Reply(204)
+
+		provider, emitter := createProvider()
+		result, err := provider.GetForUsername(context.Background(), "username")
+		require.Nil(t, err)
+		require.Nil(t, result)
+
+		emitter.AssertExpectations(t)
+	})
+
+	t.Run("should return error on unexpected response", func(t *testing.T) {
+		defer gock.Off()
+		gock.New("http://example.com").
+			Get("/subpath/textures/signed/username").
+			Reply(500)
+
+		provider, emitter := createProvider()
+		result, err := provider.GetForUsername(context.Background(), "username")
+		require.IsType(t, &UnexpectedRemoteApiResponse{}, err)
+		require.Nil(t, result)
+
+		emitter.AssertExpectations(t)
+	})
+	t.Run("should escape the username in the request path", func(t *testing.T) {
+		defer gock.Off()
+		gock.New("http://example.com").
+			Get("/subpath/textures/signed/").
+			Reply(204)
+
+		emitter := &mockEmitter{}
+		emitter.On("Emit",
+			"mojang_textures:remote_skinsystem_textures_provider:before_request",
+			"http://example.com/subpath/textures/signed/..%2Fapi",
+		).Once()
+		emitter.On("Emit", "mojang_textures:remote_skinsystem_textures_provider:after_request", mock.Anything, mock.Anything).Once()
+
+		provider := &RemoteSkinsystemTexturesProvider{
+			Emitter: emitter,
+			Url:     shouldParseUrl("http://example.com/subpath"),
+		}
+		_, _ = provider.GetForUsername(context.Background(), "../api")
+
+		emitter.AssertExpectations(t)
+	})
+}
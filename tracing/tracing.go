@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EndSpan marks the span as failed when the error is passed and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// InjectHeaders passes the trace of the request's context to the remote service,
+// so the spans of the remote Chrly instance will be a part of the same trace
+func InjectHeaders(request *http.Request) {
+	otel.GetTextMapPropagator().Inject(request.Context(), propagation.HeaderCarrier(request.Header))
+}
+
+// ExtractHeaders continues the trace, that was started by the remote service
+func ExtractHeaders(request *http.Request) *http.Request {
+	ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+
+	return request.WithContext(ctx)
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEndSpan(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)).Tracer("test")
+
+	_, span := tracer.Start(httptest.NewRequest("GET", "/", nil).Context(), "successful")
+	EndSpan(span, nil)
+	_, span = tracer.Start(httptest.NewRequest("GET", "/", nil).Context(), "failed")
+	EndSpan(span, errors.New("mock error"))
+
+	spans := spanRecorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "mock error", spans[1].Status().Description)
+	assert.Len(t, spans[1].Events(), 1)
+}
+
+func TestInjectExtractHeaders(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	outgoingRequest := httptest.NewRequest("GET", "http://example.com", nil)
+	ctx, span := tracer.Start(outgoingRequest.Context(), "outgoing")
+	defer span.End()
+
+	outgoingRequest = outgoingRequest.WithContext(ctx)
+	InjectHeaders(outgoingRequest)
+	assert.NotEmpty(t, outgoingRequest.Header.Get("traceparent"))
+
+	incomingRequest := httptest.NewRequest("GET", "http://example.com", nil)
+	incomingRequest.Header = outgoingRequest.Header
+	incomingRequest = ExtractHeaders(incomingRequest)
+
+	spanContext := trace.SpanContextFromContext(incomingRequest.Context())
+	assert.True(t, spanContext.IsRemote())
+	assert.Equal(t, span.SpanContext().TraceID(), spanContext.TraceID())
+	assert.Equal(t, span.SpanContext().SpanID(), spanContext.SpanID())
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// DeadLetter is the delivery, that has failed all its attempts
+type DeadLetter struct {
+	Id       string          `json:"id"`
+	Url      string          `json:"url"`
+	Event    string          `json:"event"`
+	Payload  json.RawMessage `json:"payload"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	FailedAt int64           `json:"failedAt"`
+}
+
+type DeadLetterQueue interface {
+	Push(letter *DeadLetter) error
+}
+
+// FileDeadLetterQueue appends the dead letters to the file as JSON lines, so they can be inspected
+// and replayed manually. The payload is stored as is, so its signature can be recalculated with the same secret
+type FileDeadLetterQueue struct {
+	Path string
+
+	lock sync.Mutex
+}
+
+func (q *FileDeadLetterQueue) Push(letter *DeadLetter) error {
+	line, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	file, err := os.OpenFile(q.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+
+	return err
+}
@@ -0,0 +1,34 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileDeadLetterQueue_Push(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrly_webhooks")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	queue := &FileDeadLetterQueue{Path: path.Join(dir, "dead_letters.jsonl")}
+	require.Nil(t, queue.Push(&DeadLetter{Id: "1", Url: "http://localhost", Payload: json.RawMessage(`{"event":"skin.saved"}`)}))
+	require.Nil(t, queue.Push(&DeadLetter{Id: "2", Url: "http://localhost", Payload: json.RawMessage(`{"event":"cape.saved"}`)}))
+
+	data, err := ioutil.ReadFile(queue.Path)
+	require.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	letter := &DeadLetter{}
+	require.Nil(t, json.Unmarshal([]byte(lines[1]), letter))
+	assert.Equal(t, "2", letter.Id)
+	assert.JSONEq(t, `{"event":"cape.saved"}`, string(letter.Payload))
+}
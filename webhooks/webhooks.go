@@ -0,0 +1,266 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/model"
+	"github.com/elyby/chrly/tracing"
+	"github.com/elyby/chrly/version"
+)
+
+const (
+	SignatureHeader = "X-Chrly-Signature"
+	EventHeader     = "X-Chrly-Event"
+	DeliveryHeader  = "X-Chrly-Delivery"
+)
+
+var ErrQueueIsFull = errors.New("the webhooks queue is full")
+
+type Emitter interface {
+	dispatcher.Emitter
+}
+
+type Subscriber interface {
+	dispatcher.Subscriber
+}
+
+// Payload is the body of the webhook request
+type Payload struct {
+	// Id is the same for all attempts of the delivery, so the receiver can drop the duplicates
+	Id        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// SkinData is the data of the skin.* events. The model isn't serialized directly,
+// so its internal fields won't leak into the public payload
+type SkinData struct {
+	UserId          int    `json:"userId"`
+	Uuid            string `json:"uuid"`
+	Username        string `json:"username"`
+	SkinId          int    `json:"skinId"`
+	Url             string `json:"url"`
+	Is1_8           bool   `json:"is1_8"`
+	IsSlim          bool   `json:"isSlim"`
+	MojangTextures  string `json:"mojangTextures"`
+	MojangSignature string `json:"mojangSignature"`
+	SkinHash        string `json:"skinHash,omitempty"`
+}
+
+// CapeData is the data of the cape.* events
+type CapeData struct {
+	Username string `json:"username"`
+}
+
+type delivery struct {
+	Id      string
+	Url     string
+	Event   string
+	Body    []byte
+	Attempt int
+}
+
+// Webhooks sends the records changes events to the configured targets. The deliveries are performed
+// by the workers from the queue. The failed deliveries are retried with the exponential backoff and,
+// when the attempts are exhausted, they're pushed into the dead letters queue
+type Webhooks struct {
+	Emitter
+	Targets []string
+	// Secret is the key of the HMAC-SHA256 signature of the request body
+	Secret      []byte
+	Client      *http.Client
+	DeadLetters DeadLetterQueue
+	// Attempts is the total number of attempts, including the first one
+	Attempts int
+	// Delay is the base delay before the first retry. It's doubled for each subsequent retry
+	Delay time.Duration
+	// MaxDelay limits the delay between retries
+	MaxDelay  time.Duration
+	QueueSize int
+	Workers   int
+
+	onFirstCall sync.Once
+	queue       chan *delivery
+}
+
+func (w *Webhooks) ConfigureWithDispatcher(d Subscriber) {
+	d.Subscribe("skinsystem:skin:saved", w.createSkinHandler("skin.saved"))
+	d.Subscribe("skinsystem:skin:removed", w.createSkinHandler("skin.removed"))
+	d.Subscribe("skinsystem:cape:saved", w.createCapeHandler("cape.saved"))
+	d.Subscribe("skinsystem:cape:removed", w.createCapeHandler("cape.removed"))
+}
+
+// The dispatcher holds its lock while calling the handlers, so the events, emitted by Send,
+// would deadlock if it was called synchronously from the handler
+func (w *Webhooks) createSkinHandler(event string) func(skin *model.Skin) {
+	return func(skin *model.Skin) {
+		go w.Send(event, &SkinData{
+			UserId:          skin.UserId,
+			Uuid:            skin.Uuid,
+			Username:        skin.Username,
+			SkinId:          skin.SkinId,
+			Url:             skin.Url,
+			Is1_8:           skin.Is1_8,
+			IsSlim:          skin.IsSlim,
+			MojangTextures:  skin.MojangTextures,
+			MojangSignature: skin.MojangSignature,
+			SkinHash:        skin.SkinHash,
+		})
+	}
+}
+
+func (w *Webhooks) createCapeHandler(event string) func(username string) {
+	return func(username string) {
+		go w.Send(event, &CapeData{Username: username})
+	}
+}
+
+// Send queues the delivery of the event to each target
+func (w *Webhooks) Send(event string, data interface{}) {
+	w.onFirstCall.Do(w.startWorkers)
+
+	for _, url := range w.Targets {
+		id := generateDeliveryId()
+		body, err := json.Marshal(&Payload{
+			Id:        id,
+			Event:     event,
+			Timestamp: time.Now().Unix(),
+			Data:      data,
+		})
+		if err != nil {
+			w.Emit("webhooks:error", fmt.Errorf("unable to encode the \"%s\" event: %w", event, err))
+			return
+		}
+
+		w.enqueue(&delivery{
+			Id:      id,
+			Url:     url,
+			Event:   event,
+			Body:    body,
+			Attempt: 1,
+		})
+	}
+}
+
+func (w *Webhooks) startWorkers() {
+	w.queue = make(chan *delivery, w.QueueSize)
+	for i := 0; i < w.Workers; i++ {
+		go func() {
+			for d := range w.queue {
+				w.deliver(d)
+			}
+		}()
+	}
+}
+
+// The queue isn't blocking, so the request, that has emitted the event, won't wait for the deliveries
+func (w *Webhooks) enqueue(d *delivery) {
+	select {
+	case w.queue <- d:
+	default:
+		w.deadLetter(d, ErrQueueIsFull)
+	}
+}
+
+func (w *Webhooks) deliver(d *delivery) {
+	err := w.send(d)
+	if err == nil {
+		w.Emit("webhooks:delivered", d.Url, d.Event, d.Attempt)
+		return
+	}
+
+	w.Emit("webhooks:failed", d.Url, d.Event, d.Attempt, err)
+	if d.Attempt >= w.Attempts {
+		w.deadLetter(d, err)
+		return
+	}
+
+	delay := w.getDelay(d.Attempt)
+	d.Attempt++
+	time.AfterFunc(delay, func() {
+		w.enqueue(d)
+	})
+}
+
+func (w *Webhooks) send(d *delivery) error {
+	request, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, d.Url, bytes.NewReader(d.Body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Chrly/"+version.Version())
+	request.Header.Set(EventHeader, d.Event)
+	request.Header.Set(DeliveryHeader, d.Id)
+	request.Header.Set(SignatureHeader, Sign(w.Secret, d.Body))
+	tracing.InjectHeaders(request)
+
+	response, err := w.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// Read the body to let the client reuse the connection
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+func (w *Webhooks) deadLetter(d *delivery, err error) {
+	w.Emit("webhooks:dead_lettered", d.Url, d.Event, err)
+	if w.DeadLetters == nil {
+		return
+	}
+
+	pushErr := w.DeadLetters.Push(&DeadLetter{
+		Id:       d.Id,
+		Url:      d.Url,
+		Event:    d.Event,
+		Payload:  d.Body,
+		Attempts: d.Attempt,
+		Error:    err.Error(),
+		FailedAt: time.Now().Unix(),
+	})
+	if pushErr != nil {
+		w.Emit("webhooks:error", fmt.Errorf("unable to push the dead letter: %w", pushErr))
+	}
+}
+
+func (w *Webhooks) getDelay(attempt int) time.Duration {
+	delay := w.Delay << uint(attempt-1)
+	if delay > w.MaxDelay || delay <= 0 {
+		delay = w.MaxDelay
+	}
+
+	return delay
+}
+
+// Sign returns the value of the signature header: the hex encoded HMAC-SHA256 of the body prefixed by the algorithm
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateDeliveryId() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
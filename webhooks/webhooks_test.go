@@ -0,0 +1,186 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elyby/chrly/dispatcher"
+	"github.com/elyby/chrly/model"
+)
+
+type deadLetterQueueMock struct {
+	letters chan *DeadLetter
+}
+
+func (q *deadLetterQueueMock) Push(letter *DeadLetter) error {
+	q.letters <- letter
+	return nil
+}
+
+func createWebhooks(d dispatcher.Dispatcher, url string) (*Webhooks, *deadLetterQueueMock) {
+	deadLetters := &deadLetterQueueMock{letters: make(chan *DeadLetter, 1)}
+	webhooks := &Webhooks{
+		Emitter:     d,
+		Targets:     []string{url},
+		Secret:      []byte("mock secret"),
+		Client:      &http.Client{Timeout: time.Second},
+		DeadLetters: deadLetters,
+		Attempts:    3,
+		Delay:       time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+		QueueSize:   10,
+		Workers:     1,
+	}
+	webhooks.ConfigureWithDispatcher(d)
+
+	return webhooks, deadLetters
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Run("should send the signed payload", func(t *testing.T) {
+		requests := make(chan *http.Request, 1)
+		bodies := make(chan []byte, 1)
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			body, _ := ioutil.ReadAll(req.Body)
+			requests <- req
+			bodies <- body
+		}))
+		defer server.Close()
+
+		d := dispatcher.New()
+		createWebhooks(d, server.URL)
+		delivered := make(chan int, 1)
+		d.Subscribe("webhooks:delivered", func(url string, event string, attempt int) {
+			delivered <- attempt
+		})
+
+		d.Emit("skinsystem:skin:saved", &model.Skin{UserId: 1, Username: "mock_username", SkinId: 5, OldUsername: "mock_old"})
+
+		select {
+		case attempt := <-delivered:
+			assert.Equal(t, 1, attempt)
+		case <-time.After(time.Second):
+			t.Fatal("the webhook hasn't been delivered")
+		}
+
+		req := <-requests
+		body := <-bodies
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.Equal(t, "skin.saved", req.Header.Get(EventHeader))
+		assert.Equal(t, Sign([]byte("mock secret"), body), req.Header.Get(SignatureHeader))
+
+		payload := &struct {
+			Id        string                 `json:"id"`
+			Event     string                 `json:"event"`
+			Timestamp int64                  `json:"timestamp"`
+			Data      map[string]interface{} `json:"data"`
+		}{}
+		require.Nil(t, json.Unmarshal(body, payload))
+		assert.Equal(t, req.Header.Get(DeliveryHeader), payload.Id)
+		assert.Equal(t, "skin.saved", payload.Event)
+		assert.InDelta(t, time.Now().Unix(), payload.Timestamp, 5)
+		assert.Equal(t, map[string]interface{}{
+			"userId":          float64(1),
+			"uuid":            "",
+			"username":        "mock_username",
+			"skinId":          float64(5),
+			"url":             "",
+			"is1_8":           false,
+			"isSlim":          false,
+			"mojangTextures":  "",
+			"mojangSignature": "",
+		}, payload.Data)
+	})
+
+	t.Run("should retry the failed delivery", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				resp.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		d := dispatcher.New()
+		createWebhooks(d, server.URL)
+		failed := make(chan error, 1)
+		d.Subscribe("webhooks:failed", func(url string, event string, attempt int, err error) {
+			failed <- err
+		})
+		delivered := make(chan int, 1)
+		d.Subscribe("webhooks:delivered", func(url string, event string, attempt int) {
+			delivered <- attempt
+		})
+
+		d.Emit("skinsystem:cape:removed", "mock_username")
+
+		select {
+		case attempt := <-delivered:
+			assert.Equal(t, 2, attempt)
+		case <-time.After(time.Second):
+			t.Fatal("the webhook hasn't been delivered")
+		}
+
+		assert.EqualError(t, <-failed, "unexpected response status 502")
+	})
+
+	t.Run("should dead-letter the delivery when the attempts are exhausted", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			resp.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+
+		d := dispatcher.New()
+		_, deadLetters := createWebhooks(d, server.URL)
+		d.Emit("skinsystem:skin:removed", &model.Skin{UserId: 1, Username: "mock_username"})
+
+		select {
+		case letter := <-deadLetters.letters:
+			assert.Equal(t, server.URL, letter.Url)
+			assert.Equal(t, "skin.removed", letter.Event)
+			assert.Equal(t, 3, letter.Attempts)
+			assert.Equal(t, "unexpected response status 500", letter.Error)
+			assert.Contains(t, string(letter.Payload), `"username":"mock_username"`)
+		case <-time.After(time.Second):
+			t.Fatal("the webhook hasn't been dead-lettered")
+		}
+
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("should dead-letter the delivery when the queue is full", func(t *testing.T) {
+		d := dispatcher.New()
+		webhooks, deadLetters := createWebhooks(d, "http://localhost")
+		webhooks.Workers = 0
+		webhooks.QueueSize = 0
+
+		d.Emit("skinsystem:cape:saved", "mock_username")
+
+		select {
+		case letter := <-deadLetters.letters:
+			assert.Equal(t, ErrQueueIsFull.Error(), letter.Error)
+			assert.Equal(t, 1, letter.Attempts)
+		case <-time.After(time.Second):
+			t.Fatal("the webhook hasn't been dead-lettered")
+		}
+	})
+}
+
+func TestSign(t *testing.T) {
+	assert.Equal(
+		t,
+		"sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		Sign([]byte("key"), []byte("The quick brown fox jumps over the lazy dog")),
+	)
+}